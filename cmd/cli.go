@@ -0,0 +1,96 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"txpool-viz/internal/config"
+)
+
+// configFlags collects config field overrides from the command line
+type configFlags struct {
+	path      string
+	overrides []string
+}
+
+// addConfigFlags registers --set and a flag per scalar config field on fs
+func addConfigFlags(fs *flag.FlagSet) *configFlags {
+	cf := &configFlags{}
+
+	fs.StringVar(&cf.path, "config", "", "config file (default $"+config.EnvConfigPath+" or "+config.DefaultPath+")")
+
+	fs.Func("set", "override a config field as `path=value`, repeatable (e.g. endpoints.0.rpc_url=http://127.0.0.1:8545)", func(v string) error {
+		if !strings.Contains(v, "=") {
+			return fmt.Errorf("expected path=value")
+		}
+		cf.overrides = append(cf.overrides, v)
+		return nil
+	})
+
+	for _, field := range config.Fields() {
+		path := field.Path
+		set := func(v string) error {
+			cf.overrides = append(cf.overrides, path+"="+v)
+			return nil
+		}
+		if field.Bool {
+			fs.BoolFunc(path, "override config field "+path, set)
+		} else {
+			fs.Func(path, "override config field "+path, set)
+		}
+	}
+
+	return cf
+}
+
+// load resolves the config file and environment, then applies the flag overrides, without connecting to the endpoints
+func (cf *configFlags) load() (*config.Config, error) {
+	cfg, err := config.Resolve(cf.path)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, override := range cf.overrides {
+		path, value, _ := strings.Cut(override, "=")
+		if err := cfg.Override(path, value); err != nil {
+			return nil, err
+		}
+	}
+
+	return cfg, nil
+}
+
+// parseArgs parses flags anywhere among the positional args, so `diff 0xabc --json` works
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		if fs.NArg() == 0 {
+			return positional, nil
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
+
+// newFlagSet builds a flag set whose usage names the command and its args
+func newFlagSet(name, args string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: txpool-viz %s [flags]\n\nFlags:\n", strings.TrimSpace(name+" "+args))
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// exactArgs exits with the command usage unless exactly n positional args were given
+func exactArgs(fs *flag.FlagSet, args []string, n int) {
+	if len(args) != n {
+		fs.Usage()
+		os.Exit(2)
+	}
+}
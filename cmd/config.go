@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+)
+
+// runConfig handles `config validate`, checking the config with overrides applied
+func runConfig(args []string) error {
+	fs := newFlagSet("config", "validate")
+	connect := fs.Bool("connect", false, "also check that every endpoint answers")
+	cf := addConfigFlags(fs)
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	exactArgs(fs, positional, 1)
+	if positional[0] != "validate" {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	cfg, err := cf.load()
+	if err != nil {
+		return err
+	}
+
+	errs := cfg.Validate()
+	for _, err := range errs {
+		fmt.Fprintf(os.Stderr, "invalid: %s\n", err)
+	}
+
+	if *connect && len(errs) == 0 {
+		if err := cfg.Connect(); err != nil {
+			return err
+		}
+
+		for _, endpoint := range cfg.Endpoints {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			chainID, err := endpoint.Client.ChainID(ctx)
+			cancel()
+
+			if err != nil {
+				errs = append(errs, err)
+				fmt.Fprintf(os.Stderr, "unreachable: %s (%s): %s\n", endpoint.Name, endpoint.RPCUrl, err)
+				continue
+			}
+			fmt.Printf("%s: chain id %s\n", endpoint.Name, chainID)
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%d problem(s) found", len(errs))
+	}
+
+	fmt.Println("config OK")
+	return nil
+}
@@ -0,0 +1,468 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"txpool-viz/internal/calldata"
+	"txpool-viz/internal/clock"
+	"txpool-viz/internal/model"
+	"txpool-viz/internal/service"
+)
+
+const inspectTimeout = 30 * time.Second
+
+// openServices loads the config and connects to the redis of a running instance without wiping it
+func openServices(cf *configFlags) (*service.TransactionServiceImpl, *service.InclusionListService, error) {
+	cfg, err := cf.load()
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(cfg.Endpoints) == 0 {
+		return nil, nil, fmt.Errorf("no endpoints configured")
+	}
+
+	srvc, err := service.OpenService(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	txService := service.NewTransactionService(context.Background(), srvc.Redis, srvc.Logger, clock.Wall, cfg.Endpoints)
+	calls, errs := calldata.NewRegistry(cfg.Decoding)
+	for _, err := range errs {
+		fmt.Fprintf(os.Stderr, "skipped: %s\n", err)
+	}
+	txService.SetCallRegistry(calls)
+	ilService := service.NewInclusionListService(srvc.Redis, srvc.Logger, true, cfg.Endpoints)
+	return txService, ilService, nil
+}
+
+func writeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// runDiff prints how each client saw a tx, the terminal version of GET /api/tx/:hash
+func runDiff(args []string) error {
+	fs := newFlagSet("diff", "<txhash>")
+	asJSON := fs.Bool("json", false, "print JSON")
+	cf := addConfigFlags(fs)
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	exactArgs(fs, positional, 1)
+
+	txService, _, err := openServices(cf)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), inspectTimeout)
+	defer cancel()
+
+	records, err := txService.GetClientRecords(ctx, positional[0])
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		return fmt.Errorf("tx %s was not seen by any client", positional[0])
+	}
+
+	details, err := txService.GetTxDetails(ctx, positional[0])
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		return writeJSON(os.Stdout, details)
+	}
+
+	var seenBy, notSeenBy []string
+	for _, client := range details.Clients {
+		if _, ok := records[client]; ok {
+			seenBy = append(seenBy, client)
+		} else {
+			notSeenBy = append(notSeenBy, client)
+		}
+	}
+
+	fmt.Printf("Transaction %s\n", details.Hash)
+	fmt.Printf("Seen by: %s\n", strings.Join(seenBy, ", "))
+	if len(notSeenBy) > 0 {
+		fmt.Printf("Not seen by: %s\n", strings.Join(notSeenBy, ", "))
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	for _, section := range []struct {
+		name   string
+		common map[string]interface{}
+		diff   map[string]map[string]interface{}
+	}{
+		{"tx", details.Common.Tx, details.Diff.Tx},
+		{"metadata", details.Common.Metadata, details.Diff.Metadata},
+	} {
+		fmt.Fprintf(w, "\n[%s]\n", section.name)
+		for _, field := range sortedKeys(section.common) {
+			fmt.Fprintf(w, "  %s\t%s\n", field, formatValue(section.common[field]))
+		}
+
+		if len(section.diff) == 0 {
+			continue
+		}
+
+		fmt.Fprintf(w, "\n  differs\t%s\n", strings.Join(seenBy, "\t"))
+		for _, field := range sortedKeys(section.diff) {
+			values := make([]string, 0, len(seenBy))
+			for _, client := range seenBy {
+				value, ok := section.diff[field][client]
+				if !ok {
+					values = append(values, "-")
+					continue
+				}
+				values = append(values, formatValue(value))
+			}
+			fmt.Fprintf(w, "  %s\t%s\n", field, strings.Join(values, "\t"))
+		}
+	}
+
+	if call := details.Call; call != nil {
+		fmt.Fprintf(w, "\n[call]\n")
+		fmt.Fprintf(w, "  selector\t%s\n", call.Selector)
+		if call.Signature == "" {
+			fmt.Fprintf(w, "  signature\tunknown\n")
+		} else {
+			fmt.Fprintf(w, "  signature\t%s (%s)\n", call.Signature, call.Source)
+		}
+		if call.Error != "" {
+			fmt.Fprintf(w, "  error\t%s\n", call.Error)
+		}
+		for i, arg := range call.Args {
+			name := arg.Name
+			if name == "" {
+				name = strconv.Itoa(i)
+			}
+			fmt.Fprintf(w, "  %s %s\t%s\n", arg.Type, name, formatValue(arg.Value))
+		}
+	}
+	return w.Flush()
+}
+
+// runPoolDiff prints the set differences between the clients' mempools
+func runPoolDiff(args []string) error {
+	fs := newFlagSet("pool-diff", "")
+	statuses := fs.String("status", "received,pending,queued", "comma separated statuses counted as in the pool, empty for all")
+	limit := fs.Int("limit", 10, "hashes listed per group, 0 for all")
+	asJSON := fs.Bool("json", false, "print JSON")
+	cf := addConfigFlags(fs)
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+
+	txService, _, err := openServices(cf)
+	if err != nil {
+		return err
+	}
+
+	var filter []model.TransactionStatus
+	for _, status := range strings.Split(*statuses, ",") {
+		if status = strings.TrimSpace(status); status != "" {
+			filter = append(filter, model.TransactionStatus(status))
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), inspectTimeout)
+	defer cancel()
+
+	diff, err := txService.GetPoolDiff(ctx, filter)
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		return writeJSON(os.Stdout, diff)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "CLIENT\tTXS")
+	for _, client := range diff.Clients {
+		fmt.Fprintf(w, "%s\t%d\n", client, diff.Sizes[client])
+	}
+	fmt.Fprintf(w, "(every client)\t%d\n", diff.Shared)
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	for _, group := range diff.Groups {
+		fmt.Printf("\n%d txs seen by %s, missing in %s\n", len(group.Hashes), strings.Join(group.SeenBy, ", "), strings.Join(group.MissingIn, ", "))
+		for i, hash := range group.Hashes {
+			if *limit > 0 && i == *limit {
+				fmt.Printf("  ... %d more\n", len(group.Hashes)-*limit)
+				break
+			}
+			fmt.Printf("  %s\n", hash)
+		}
+	}
+	return nil
+}
+
+// runPolicies prints the acceptance policies inferred for each client with the txs they rest on
+func runPolicies(args []string) error {
+	fs := newFlagSet("policies", "")
+	evidence := fs.Bool("evidence", false, "list the txs each inference rests on")
+	asJSON := fs.Bool("json", false, "print JSON")
+	cf := addConfigFlags(fs)
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+
+	txService, _, err := openServices(cf)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), inspectTimeout)
+	defer cancel()
+
+	policies, err := txService.GetClientPolicies(ctx)
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		return writeJSON(os.Stdout, policies)
+	}
+
+	for i, policy := range policies {
+		if i > 0 {
+			fmt.Println()
+		}
+		fmt.Printf("%s (%d txs)\n", policy.Client, policy.Txs)
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		for _, inference := range policy.Policies {
+			value := inference.Value
+			if value == "" {
+				value = "-"
+			}
+			fmt.Fprintf(w, "  %s\t%s\t%s\n", inference.Policy, value, inference.Summary)
+			if !*evidence {
+				continue
+			}
+			for _, e := range inference.Evidence {
+				mark := "-"
+				if e.Accepted {
+					mark = "+"
+				}
+				fmt.Fprintf(w, "  \t%s %s\t%s\n", mark, e.Hash, e.Note)
+			}
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runInspectIL prints a slot's inclusion list, the outcome of its txs, signatures and propagation
+func runInspectIL(args []string) error {
+	fs := newFlagSet("inspect-il", "<slot>")
+	asJSON := fs.Bool("json", false, "print JSON")
+	cf := addConfigFlags(fs)
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	exactArgs(fs, positional, 1)
+
+	slot, err := strconv.Atoi(positional[0])
+	if err != nil || slot < 0 {
+		return fmt.Errorf("invalid slot %q", positional[0])
+	}
+
+	_, ilService, err := openServices(cf)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), inspectTimeout)
+	defer cancel()
+
+	detail, err := ilService.GetInclusionListDetail(ctx, slot)
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		return writeJSON(os.Stdout, detail)
+	}
+
+	fmt.Printf("Slot %d, lists from validators %s\n", detail.Slot, strings.Join(detail.Validators, ", "))
+	if detail.Report != nil {
+		fmt.Printf("Block %d: %d/%d txs included, %d missing\n", detail.Slot+1, detail.Report.Summary.Included, detail.Report.Summary.Total, detail.Report.Summary.Missing)
+	} else {
+		fmt.Printf("Block %d: no report yet\n", detail.Slot+1)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "\nHASH\tTYPE\tFROM\tNONCE\tOUTCOME\tLISTED BY")
+	for _, tx := range detail.Transactions {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t%s\n", tx.Hash, tx.Type, tx.Tx.From, tx.Tx.Nonce, tx.Outcome, strings.Join(tx.ListedBy, ","))
+	}
+
+	if len(detail.Verifications) > 0 {
+		fmt.Fprintln(w, "\nVALIDATOR\tSTATUS\tCOMMITTEE\tSIGNATURE\tREASON")
+		for _, v := range detail.Verifications {
+			fmt.Fprintf(w, "%s\t%s\t%t\t%t\t%s\n", v.ValidatorIndex, v.Status, v.CommitteeMember, v.SignatureValid, v.Reason)
+		}
+	}
+
+	if len(detail.Propagation) > 0 {
+		fmt.Fprintln(w, "\nVALIDATOR\tFIRST NODE\tDELAYS (ms)")
+		for _, p := range detail.Propagation {
+			delays := make([]string, 0, len(p.Delays))
+			for _, node := range sortedKeys(p.Delays) {
+				delays = append(delays, fmt.Sprintf("%s=%d", node, p.Delays[node]))
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\n", p.ValidatorIndex, p.FirstNode, strings.Join(delays, " "))
+		}
+	}
+	return w.Flush()
+}
+
+// exportHeader names the CSV columns, one row is written per tx and client
+var exportHeader = []string{
+	"hash", "client", "status", "from", "to", "nonce", "type", "value", "gas", "gas_price", "max_fee_per_gas",
+	"max_priority_fee", "time_received", "time_pending", "time_queued", "time_mined", "time_dropped", "block_number", "mine_status",
+}
+
+// exportedTx is a tx with the record each client kept of it
+type exportedTx struct {
+	Hash    string                             `json:"hash"`
+	Clients map[string]model.StoredTransaction `json:"clients"`
+}
+
+// runExport writes the latest transactions collected by a running instance
+func runExport(args []string) error {
+	fs := newFlagSet("export", "")
+	count := fs.Int64("count", 1000, "number of latest transactions")
+	format := fs.String("format", "json", "json or csv")
+	out := fs.String("out", "", "output file, stdout when empty")
+	cf := addConfigFlags(fs)
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if *format != "json" && *format != "csv" {
+		return fmt.Errorf("unsupported format %q", *format)
+	}
+
+	txService, _, err := openServices(cf)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), inspectTimeout)
+	defer cancel()
+
+	hashes, err := txService.GetLatestTxHashes(ctx, *count)
+	if err != nil {
+		return err
+	}
+
+	txs := make([]exportedTx, 0, len(hashes))
+	for _, hash := range hashes {
+		records, err := txService.GetClientRecords(ctx, hash)
+		if err != nil {
+			return err
+		}
+		txs = append(txs, exportedTx{Hash: hash, Clients: records})
+	}
+
+	w := io.Writer(os.Stdout)
+	if *out != "" {
+		file, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+
+	if *format == "json" {
+		return writeJSON(w, txs)
+	}
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(exportHeader); err != nil {
+		return err
+	}
+
+	for _, tx := range txs {
+		for _, client := range sortedKeys(tx.Clients) {
+			record := tx.Clients[client]
+			meta := record.Metadata
+
+			gasPrice := ""
+			if record.Tx.GasPrice != nil {
+				gasPrice = record.Tx.GasPrice.String()
+			}
+
+			row := []string{
+				tx.Hash, client, string(meta.Status), record.Tx.From, record.Tx.To,
+				strconv.FormatUint(record.Tx.Nonce, 10), model.TransactionType(record.Tx.Type).String(),
+				record.Tx.Value, strconv.FormatUint(record.Tx.Gas, 10), gasPrice,
+				record.Tx.MaxFeePerGas, record.Tx.MaxPriorityFee,
+				strconv.FormatInt(meta.TimeReceived, 10), formatValue(meta.TimePending),
+				strconv.FormatInt(meta.TimeQueued, 10), formatValue(meta.TimeMined),
+				strconv.FormatInt(meta.TimeDropped, 10), strconv.FormatUint(meta.BlockNumber, 10), meta.MineStatus,
+			}
+			if err := writer.Write(row); err != nil {
+				return err
+			}
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+func formatValue(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case *int64:
+		if v == nil {
+			return ""
+		}
+		return strconv.FormatInt(*v, 10)
+	case fmt.Stringer:
+		return v.String()
+	}
+
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return strings.Trim(string(raw), `"`)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
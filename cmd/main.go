@@ -1,29 +1,75 @@
 package main
 
 import (
+	"fmt"
 	"log"
-
-	"txpool-viz/internal/config"
-	"txpool-viz/internal/controller"
-	"txpool-viz/internal/service"
+	"os"
+	"strings"
 )
 
+// command is a txpool-viz subcommand
+type command struct {
+	name    string
+	args    string
+	summary string
+	run     func(args []string) error
+}
+
+var commands []command
+
+func init() {
+	commands = []command{
+		{"serve", "", "Stream from the configured clients and serve the web UI (default)", runServe},
+		{"record", "", "Serve while recording a capture of the session", runRecord},
+		{"replay", "<capture>", "Serve a recorded capture instead of live clients", runReplay},
+		{"export", "", "Export collected transactions as JSON or CSV", runExport},
+		{"diff", "<txhash>", "Show how each client saw a transaction", runDiff},
+		{"pool-diff", "", "Show the txs only some clients hold", runPoolDiff},
+		{"policies", "", "Infer each client's tx acceptance policies", runPolicies},
+		{"config", "validate", "Check the config for mistakes", runConfig},
+		{"inspect-il", "<slot>", "Show a slot's inclusion list and what happened to its txs", runInspectIL},
+		{"mocknode", "", "Serve mock execution clients from a scenario", runMockNode},
+		{"mockbeacon", "", "Serve mock beacon nodes from a scenario", runMockBeacon},
+	}
+}
+
 func main() {
-	// Load configuration
-	cfg, err := config.Load()
-	if err != nil {
-		log.Fatalf("Failed to load config: %v", err)
+	args := os.Args[1:]
+
+	// Without a subcommand txpool-viz serves, as it always has
+	name := "serve"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+
+	if name == "help" {
+		usage()
+		return
 	}
 
-	// Initialize services
-	srvc, err := service.NewService(cfg)
-	if err != nil {
-		log.Fatalf("Failed to initialize services: %v", err)
+	for _, cmd := range commands {
+		if cmd.name == name {
+			if err := cmd.run(args); err != nil {
+				log.Fatalf("%s: %v", name, err)
+			}
+			return
+		}
 	}
 
-	// Create and start the controller
-	ctrl := controller.NewController(cfg, srvc)
-	if err := ctrl.Serve(); err != nil {
-		log.Fatalf("Server failed: %v", err)
+	fmt.Fprintf(os.Stderr, "unknown command %q\n\n", name)
+	usage()
+	os.Exit(2)
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: txpool-viz <command> [flags] [args]")
+	fmt.Fprintln(os.Stderr, "\nCommands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-24s %s\n", strings.TrimSpace(cmd.name+" "+cmd.args), cmd.summary)
 	}
+	fmt.Fprintln(os.Stderr, "\nCommands reading the config accept --set path=value and --<path> value to override config fields,")
+	fmt.Fprintln(os.Stderr, "e.g. --polling.interval 1s or --set endpoints.0.rpc_url=http://127.0.0.1:8545, and --config to pick the")
+	fmt.Fprintln(os.Stderr, "config file. TXPOOLVIZ_* environment variables override the file, e.g. TXPOOLVIZ_ENDPOINTS_0_RPC_URL;")
+	fmt.Fprintln(os.Stderr, "flags override both.")
+	fmt.Fprintln(os.Stderr, "Run txpool-viz <command> -h for the flags of a command.")
 }
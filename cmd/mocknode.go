@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"txpool-viz/internal/logger"
+	"txpool-viz/internal/mocknode"
+)
+
+// runMockNode serves mock execution clients driven by a scenario file
+func runMockNode(args []string) error {
+	flags := newFlagSet("mocknode", "")
+	scenarioPath := flags.String("scenario", "cfg/mocknode.example.yaml", "scenario file")
+	addr := flags.String("addr", "127.0.0.1:8545", "listen address")
+	speed := flags.Float64("speed", 1, "scenario speed, 1 is real time")
+	logLevel := flags.String("log-level", "info", "log level")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	scenario, err := mocknode.LoadScenario(*scenarioPath)
+	if err != nil {
+		return err
+	}
+
+	l := logger.NewLogger(&logger.LoggerConfig{Development: true, Level: logger.LogLevel(*logLevel)})
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	return mocknode.New(scenario, *speed, l).ListenAndServe(ctx, *addr)
+}
+
+// runMockBeacon serves mock beacon nodes emitting inclusion_list and block events from a scenario file
+func runMockBeacon(args []string) error {
+	flags := newFlagSet("mockbeacon", "")
+	scenarioPath := flags.String("scenario", "cfg/mockbeacon.example.yaml", "beacon scenario file")
+	addr := flags.String("addr", "127.0.0.1:5052", "listen address")
+	speed := flags.Float64("speed", 1, "scenario speed, 1 is real time")
+	logLevel := flags.String("log-level", "info", "log level")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	scenario, err := mocknode.LoadBeaconScenario(*scenarioPath)
+	if err != nil {
+		return err
+	}
+
+	l := logger.NewLogger(&logger.LoggerConfig{Development: true, Level: logger.LogLevel(*logLevel)})
+
+	beacon, err := mocknode.NewBeacon(scenario, *speed, l)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	return beacon.ListenAndServe(ctx, *addr)
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"txpool-viz/internal/config"
+	"txpool-viz/internal/controller"
+	"txpool-viz/internal/service"
+)
+
+func runServe(args []string) error {
+	fs := newFlagSet("serve", "")
+	cf := addConfigFlags(fs)
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+
+	cfg, err := cf.load()
+	if err != nil {
+		return err
+	}
+	return serve(cfg)
+}
+
+// runRecord serves while writing every message received from the clients to a capture
+func runRecord(args []string) error {
+	fs := newFlagSet("record", "")
+	out := fs.String("out", ".", "capture file, or directory for a timestamped capture")
+	cf := addConfigFlags(fs)
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+
+	cfg, err := cf.load()
+	if err != nil {
+		return err
+	}
+	cfg.Capture.RecordFile = *out
+	cfg.Capture.ReplayFile = ""
+
+	return serve(cfg)
+}
+
+// runReplay serves a capture through the processors instead of streaming from live clients
+func runReplay(args []string) error {
+	fs := newFlagSet("replay", "<capture>")
+	speed := fs.Float64("speed", 1, "replay speed, 1 is real time and 0 as fast as possible")
+	cf := addConfigFlags(fs)
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	exactArgs(fs, positional, 1)
+
+	cfg, err := cf.load()
+	if err != nil {
+		return err
+	}
+	cfg.Capture.ReplayFile = positional[0]
+	cfg.Capture.ReplaySpeed = *speed
+	cfg.Capture.RecordFile = ""
+
+	return serve(cfg)
+}
+
+func serve(cfg *config.Config) error {
+	// A replay answers RPC calls from the capture, so only live sessions connect
+	if cfg.Capture.ReplayFile == "" {
+		if err := cfg.Connect(); err != nil {
+			return err
+		}
+	}
+
+	// Initialize services
+	srvc, err := service.NewService(cfg)
+	if err != nil {
+		return err
+	}
+
+	// Create and start the controller
+	ctrl := controller.NewController(cfg, srvc)
+	return ctrl.Serve()
+}
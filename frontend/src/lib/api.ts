@@ -18,6 +18,16 @@ export interface ApiTxResponse {
     tx: Record<string, Record<string, any>>;
     metadata: Record<string, Record<string, any>>;
   };
+  call?: DecodedCall;
+}
+
+export interface DecodedCall {
+  selector: string;
+  signature?: string;
+  method?: string;
+  source?: string;
+  args?: { name?: string; type: string; value: any }[];
+  error?: string;
 }
 
 // Fetch list of transaction summaries (from backend)
@@ -36,3 +46,28 @@ export async function fetchTxDetails(txHash: string): Promise<ApiTxResponse> {
   if (!res.ok) throw new Error(`Failed to fetch tx details: ${res.status}`);
   return await res.json();
 }
+
+export interface BlockRef {
+  number: number;
+  hash: string;
+}
+
+export interface ReorgEvent {
+  endpoint: string;
+  detected_at: number;
+  depth: number;
+  common_ancestor: number;
+  old_head: BlockRef;
+  new_head: BlockRef;
+  removed: BlockRef[];
+  added: BlockRef[];
+  affected_txs: string[];
+}
+
+// Fetch the newest reorgs, of one endpoint when given
+export async function fetchReorgs(endpoint = ""): Promise<ReorgEvent[]> {
+  const query = endpoint ? `?endpoint=${encodeURIComponent(endpoint)}` : "";
+  const res = await fetch(`/api/reorgs${query}`);
+  if (!res.ok) throw new Error(`Failed to fetch reorgs: ${res.status}`);
+  return await res.json();
+}
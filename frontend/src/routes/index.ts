@@ -1,7 +1,9 @@
 import TransactionsView from "./TransactionsView.svelte";
 import InclusionListView from "./InclusionListView.svelte";
+import ReorgsView from "./ReorgsView.svelte";
 
 export default {
   "/": TransactionsView,
-  "/inclusion-lists": InclusionListView
+  "/inclusion-lists": InclusionListView,
+  "/reorgs": ReorgsView
 };
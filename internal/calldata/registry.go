@@ -0,0 +1,159 @@
+// Package calldata decodes tx calldata by its 4-byte selector, against bundled function signatures,
+// a user's signature file and user contract ABIs
+package calldata
+
+import (
+	"bufio"
+	_ "embed"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"txpool-viz/internal/config"
+	"txpool-viz/internal/model"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+)
+
+// Sources of a decoded call's signature
+const (
+	SourceABI       = "abi"
+	SourceSignature = "signature"
+)
+
+//go:embed signatures.txt
+var bundled string
+
+// entry is a function calldata may be decoded as
+type entry struct {
+	method abi.Method
+	source string
+}
+
+// Registry maps selectors to the functions they may call. A selector may be shared by several
+// signatures, those from ABIs are tried first.
+type Registry struct {
+	methods map[[4]byte][]entry
+}
+
+// NewRegistry loads the bundled signatures and those of the decoding settings. A signature line or
+// ABI file that can't be read is skipped, the rest are still loaded, and the problems are returned.
+func NewRegistry(cfg config.Decoding) (*Registry, []error) {
+	r := &Registry{methods: make(map[[4]byte][]entry)}
+
+	var errs []error
+	for _, file := range cfg.ABIFiles {
+		if err := r.loadABI(file); err != nil {
+			errs = append(errs, fmt.Errorf("abi file %s: %w", file, err))
+		}
+	}
+	if cfg.SignaturesFile != "" {
+		if f, err := os.Open(cfg.SignaturesFile); err != nil {
+			errs = append(errs, fmt.Errorf("signatures file: %w", err))
+		} else {
+			for _, err := range r.loadSignatures(f) {
+				errs = append(errs, fmt.Errorf("signatures file %s: %w", cfg.SignaturesFile, err))
+			}
+			f.Close()
+		}
+	}
+	for _, err := range r.loadSignatures(strings.NewReader(bundled)) {
+		errs = append(errs, fmt.Errorf("bundled signatures: %w", err))
+	}
+	return r, errs
+}
+
+// loadABI adds the functions of a contract ABI JSON file
+func (r *Registry) loadABI(file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	parsed, err := abi.JSON(f)
+	if err != nil {
+		return err
+	}
+	for _, method := range parsed.Methods {
+		r.add(method, SourceABI)
+	}
+	return nil
+}
+
+// loadSignatures adds a signature per line, skipping blank lines and # comments. Lines that don't parse
+// are skipped and reported.
+func (r *Registry) loadSignatures(src io.Reader) []error {
+	var errs []error
+	scanner := bufio.NewScanner(src)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		method, err := parseSignature(text)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("line %d: %w", line, err))
+			continue
+		}
+		r.add(method, SourceSignature)
+	}
+	if err := scanner.Err(); err != nil {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+// add registers a function unless the same signature already is
+func (r *Registry) add(method abi.Method, source string) {
+	var selector [4]byte
+	copy(selector[:], method.ID)
+	for _, known := range r.methods[selector] {
+		if known.method.Sig == method.Sig {
+			return
+		}
+	}
+	r.methods[selector] = append(r.methods[selector], entry{method: method, source: source})
+}
+
+// Decode matches hex calldata, with or without 0x, by its selector. It returns nil for data too short
+// to hold one and a call without a signature when the selector is unknown.
+func (r *Registry) Decode(data string) *model.DecodedCall {
+	raw, err := hex.DecodeString(strings.TrimPrefix(data, "0x"))
+	if err != nil || len(raw) < 4 {
+		return nil
+	}
+
+	var selector [4]byte
+	copy(selector[:], raw)
+	call := &model.DecodedCall{Selector: "0x" + hex.EncodeToString(selector[:])}
+
+	candidates := r.methods[selector]
+	for _, candidate := range candidates {
+		values, err := candidate.method.Inputs.Unpack(raw[4:])
+		if err != nil {
+			continue
+		}
+		call.Signature = candidate.method.Sig
+		call.Method = candidate.method.RawName
+		call.Source = candidate.source
+		call.Args = make([]model.DecodedArg, len(values))
+		for i, value := range values {
+			input := candidate.method.Inputs[i]
+			call.Args[i] = model.DecodedArg{Name: input.Name, Type: input.Type.String(), Value: formatValue(value)}
+		}
+		return call
+	}
+
+	if len(candidates) > 0 {
+		call.Signature = candidates[0].method.Sig
+		call.Method = candidates[0].method.RawName
+		call.Source = candidates[0].source
+		call.Error = "arguments don't match the signature"
+	}
+	return call
+}
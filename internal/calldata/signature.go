@@ -0,0 +1,174 @@
+package calldata
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// parseSignature builds the function of a signature like swap((address,uint256)[],bytes). Signatures
+// carry no names, so arguments stay unnamed and tuple fields are named by position: field0, field1...
+func parseSignature(sig string) (abi.Method, error) {
+	open := strings.Index(sig, "(")
+	if open <= 0 || !strings.HasSuffix(sig, ")") {
+		return abi.Method{}, fmt.Errorf("malformed signature %q", sig)
+	}
+	name := sig[:open]
+
+	types, err := splitTypes(sig[open+1 : len(sig)-1])
+	if err != nil {
+		return abi.Method{}, fmt.Errorf("signature %q: %w", sig, err)
+	}
+	inputs := make(abi.Arguments, len(types))
+	for i, t := range types {
+		marshaling, err := argumentOf("", t)
+		if err != nil {
+			return abi.Method{}, fmt.Errorf("signature %q: %w", sig, err)
+		}
+		typ, err := abi.NewType(marshaling.Type, "", marshaling.Components)
+		if err != nil {
+			return abi.Method{}, fmt.Errorf("signature %q: %w", sig, err)
+		}
+		inputs[i] = abi.Argument{Type: typ}
+	}
+
+	method := abi.NewMethod(name, name, abi.Function, "", false, false, inputs, nil)
+	if method.Sig != sig {
+		return abi.Method{}, fmt.Errorf("signature %q isn't canonical, expected %q", sig, method.Sig)
+	}
+	return method, nil
+}
+
+// argumentOf describes a type, a tuple's like (address,uint256)[] among them
+func argumentOf(name, t string) (abi.ArgumentMarshaling, error) {
+	if !strings.HasPrefix(t, "(") {
+		return abi.ArgumentMarshaling{Name: name, Type: t}, nil
+	}
+
+	end := closingParen(t)
+	if end < 0 {
+		return abi.ArgumentMarshaling{}, fmt.Errorf("unbalanced tuple %q", t)
+	}
+	fields, err := splitTypes(t[1:end])
+	if err != nil {
+		return abi.ArgumentMarshaling{}, err
+	}
+	arg := abi.ArgumentMarshaling{Name: name, Type: "tuple" + t[end+1:]}
+	for i, field := range fields {
+		component, err := argumentOf(fmt.Sprintf("field%d", i), field)
+		if err != nil {
+			return abi.ArgumentMarshaling{}, err
+		}
+		arg.Components = append(arg.Components, component)
+	}
+	return arg, nil
+}
+
+// closingParen returns the index of the parenthesis closing the one t starts with, -1 when unbalanced
+func closingParen(t string) int {
+	depth := 0
+	for i, c := range t {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// splitTypes splits a comma separated type list, leaving the commas inside tuples alone
+func splitTypes(list string) ([]string, error) {
+	if list == "" {
+		return nil, nil
+	}
+
+	var (
+		types []string
+		depth int
+		start int
+	)
+	for i, c := range list {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth < 0 {
+				return nil, fmt.Errorf("unbalanced parentheses in %q", list)
+			}
+		case ',':
+			if depth == 0 {
+				types = append(types, list[start:i])
+				start = i + 1
+			}
+		}
+	}
+	if depth != 0 {
+		return nil, fmt.Errorf("unbalanced parentheses in %q", list)
+	}
+	types = append(types, list[start:])
+
+	for _, t := range types {
+		if t == "" {
+			return nil, fmt.Errorf("empty type in %q", list)
+		}
+	}
+	return types, nil
+}
+
+// formatValue turns an unpacked argument into JSON friendly values: integers wider than 32 bits as
+// decimal strings, addresses checksummed, bytes as hex and tuples as objects keyed by field name
+func formatValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case *big.Int:
+		return v.String()
+	case common.Address:
+		return v.Hex()
+	case []byte:
+		return hexutil.Encode(v)
+	case uint64:
+		return fmt.Sprint(v)
+	case int64:
+		return fmt.Sprint(v)
+	}
+
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Array:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			b := make([]byte, rv.Len())
+			reflect.Copy(reflect.ValueOf(b), rv)
+			return hexutil.Encode(b)
+		}
+		fallthrough
+	case reflect.Slice:
+		out := make([]interface{}, rv.Len())
+		for i := range out {
+			out[i] = formatValue(rv.Index(i).Interface())
+		}
+		return out
+	case reflect.Struct:
+		out := make(map[string]interface{}, rv.NumField())
+		for i := 0; i < rv.NumField(); i++ {
+			field := rv.Type().Field(i)
+			name := field.Tag.Get("json")
+			if name == "" {
+				name = field.Name
+			}
+			out[name] = formatValue(rv.Field(i).Interface())
+		}
+		return out
+	default:
+		return value
+	}
+}
@@ -0,0 +1,192 @@
+package capture
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Source identifies where a captured record came from
+type Source string
+
+const (
+	SourceMeta   Source = "meta" // capture header
+	SourceStream Source = "ws"   // raw newPendingTransactions websocket message
+	SourceRPC    Source = "rpc"  // JSON-RPC call and response
+	SourceEvent  Source = "sse"  // beacon SSE event
+	SourceHead   Source = "head" // newHeads notification used by the FOCIL processor
+)
+
+const (
+	formatVersion = 1
+	flushInterval = time.Second
+)
+
+// Record is a single captured message. Captures are gzip compressed JSON lines.
+type Record struct {
+	Time     int64           `json:"t"` // unix nanoseconds
+	Source   Source          `json:"src"`
+	Endpoint string          `json:"ep,omitempty"` // client or beacon node name
+	Event    string          `json:"ev,omitempty"` // SSE event name
+	Method   string          `json:"m,omitempty"`  // JSON-RPC method
+	Params   json.RawMessage `json:"p,omitempty"`  // JSON-RPC params
+	Data     json.RawMessage `json:"d,omitempty"`  // message body or JSON-RPC response
+}
+
+// Recorder writes records to a capture file. A nil Recorder records nothing.
+type Recorder struct {
+	mu        sync.Mutex
+	path      string
+	file      *os.File
+	gz        *gzip.Writer
+	enc       *json.Encoder
+	lastFlush time.Time
+}
+
+// NewRecorder creates a capture file at path. When path is a directory a timestamped file is created inside it.
+func NewRecorder(path string) (*Recorder, error) {
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		path = filepath.Join(path, fmt.Sprintf("txpool-viz-%s.capture.gz", time.Now().Format("20060102-150405")))
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("error creating capture file: %w", err)
+	}
+
+	gz := gzip.NewWriter(file)
+	r := &Recorder{
+		path:      path,
+		file:      file,
+		gz:        gz,
+		enc:       json.NewEncoder(gz),
+		lastFlush: time.Now(),
+	}
+
+	header, _ := json.Marshal(map[string]int{"version": formatVersion})
+	r.write(Record{Time: time.Now().UnixNano(), Source: SourceMeta, Data: header})
+
+	return r, nil
+}
+
+// Path returns the file the recorder writes to.
+func (r *Recorder) Path() string {
+	if r == nil {
+		return ""
+	}
+	return r.path
+}
+
+// RecordStream captures a raw websocket message from an execution client.
+func (r *Recorder) RecordStream(endpoint string, msg []byte, t time.Time) {
+	r.write(Record{Time: t.UnixNano(), Source: SourceStream, Endpoint: endpoint, Data: asJSON(msg)})
+}
+
+// RecordEvent captures a beacon SSE event.
+func (r *Recorder) RecordEvent(node, event string, data []byte, t time.Time) {
+	r.write(Record{Time: t.UnixNano(), Source: SourceEvent, Endpoint: node, Event: event, Data: asJSON(data)})
+}
+
+// RecordHead captures a new block header notification.
+func (r *Recorder) RecordHead(endpoint string, header []byte, t time.Time) {
+	r.write(Record{Time: t.UnixNano(), Source: SourceHead, Endpoint: endpoint, Data: asJSON(header)})
+}
+
+func (r *Recorder) write(rec Record) {
+	if r == nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.enc == nil {
+		return
+	}
+
+	if err := r.enc.Encode(rec); err != nil {
+		return
+	}
+
+	// Flush regularly so a crash loses at most a second of capture
+	if time.Since(r.lastFlush) > flushInterval {
+		r.gz.Flush()
+		r.lastFlush = time.Now()
+	}
+}
+
+// Close flushes and closes the capture file.
+func (r *Recorder) Close() error {
+	if r == nil {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.enc == nil {
+		return nil
+	}
+	r.enc = nil
+
+	if err := r.gz.Close(); err != nil {
+		r.file.Close()
+		return err
+	}
+	return r.file.Close()
+}
+
+// ReadFile loads every record of a capture file.
+func ReadFile(path string) ([]Record, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, fmt.Errorf("not a capture file: %w", err)
+	}
+	defer gz.Close()
+
+	var records []Record
+	reader := bufio.NewReaderSize(gz, 1<<20)
+	dec := json.NewDecoder(reader)
+	for {
+		var rec Record
+		err := dec.Decode(&rec)
+		if err == io.EOF {
+			break
+		}
+		// A capture cut short by a crash ends in a partial record
+		if err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return records, fmt.Errorf("error reading capture: %w", err)
+		}
+		records = append(records, rec)
+	}
+
+	if len(records) == 0 || records[0].Source != SourceMeta {
+		return nil, fmt.Errorf("not a capture file: missing header")
+	}
+
+	return records, nil
+}
+
+// asJSON keeps valid JSON as is and stores anything else as a JSON string
+func asJSON(data []byte) json.RawMessage {
+	if json.Valid(data) {
+		return append(json.RawMessage(nil), data...)
+	}
+	quoted, _ := json.Marshal(string(data))
+	return quoted
+}
@@ -0,0 +1,163 @@
+package capture
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// Handlers receive replayed messages. Nil handlers are skipped.
+type Handlers struct {
+	Stream func(ctx context.Context, endpoint string, msg []byte, receivedAt time.Time)
+	Event  func(ctx context.Context, node, event string, data []byte, receivedAt time.Time)
+	Head   func(ctx context.Context, endpoint string, header []byte)
+}
+
+// Player feeds a capture back through the processors on a virtual clock. It is the clock.Clock of
+// the replay, so txs are stamped and scheduled in capture time.
+type Player struct {
+	speed    float64
+	timeline []Record            // stream, event and head records in capture order
+	rpc      map[string][]Record // recorded responses by call, in capture order
+
+	captureStart time.Time
+	replayStart  atomic.Int64 // unix nanoseconds, read by the replay transports
+	position     atomic.Int64 // capture time of the last dispatched message, unix nanoseconds
+}
+
+// NewPlayer loads a capture. speed 1 replays in real time, higher values accelerate
+// and 0 replays as fast as possible.
+func NewPlayer(path string, speed float64) (*Player, error) {
+	records, err := ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Player{
+		speed:        speed,
+		rpc:          make(map[string][]Record),
+		captureStart: time.Unix(0, records[0].Time),
+	}
+	p.replayStart.Store(time.Now().UnixNano())
+	p.position.Store(records[0].Time)
+
+	for _, rec := range records[1:] {
+		switch rec.Source {
+		case SourceRPC:
+			key := rpcKey(rec.Endpoint, rec.Method, rec.Params)
+			p.rpc[key] = append(p.rpc[key], rec)
+		case SourceStream, SourceEvent, SourceHead:
+			p.timeline = append(p.timeline, rec)
+		}
+	}
+
+	sort.SliceStable(p.timeline, func(i, j int) bool {
+		return p.timeline[i].Time < p.timeline[j].Time
+	})
+
+	return p, nil
+}
+
+// Len returns the number of replayable messages.
+func (p *Player) Len() int {
+	return len(p.timeline)
+}
+
+// Run dispatches every captured message to the handlers, waiting between them
+// according to the replay speed. It returns when the capture is exhausted.
+func (p *Player) Run(ctx context.Context, h Handlers) error {
+	p.replayStart.Store(time.Now().UnixNano())
+
+	for _, rec := range p.timeline {
+		if err := p.waitUntil(ctx, rec.Time); err != nil {
+			return err
+		}
+		p.position.Store(rec.Time)
+
+		switch rec.Source {
+		case SourceStream:
+			if h.Stream != nil {
+				h.Stream(ctx, rec.Endpoint, rec.Data, time.Unix(0, rec.Time))
+			}
+		case SourceEvent:
+			if h.Event != nil {
+				h.Event(ctx, rec.Endpoint, rec.Event, rec.Data, time.Unix(0, rec.Time))
+			}
+		case SourceHead:
+			if h.Head != nil {
+				h.Head(ctx, rec.Endpoint, rec.Data)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Now returns the capture time the replay has reached. Replays without a speed stay at the last
+// dispatched message, others follow the wall clock scaled by the speed.
+func (p *Player) Now() time.Time {
+	position := p.position.Load()
+	if p.speed > 0 {
+		position = max(position, p.now())
+	}
+	return time.Unix(0, position)
+}
+
+// now returns the capture time the replay has reached, in unix nanoseconds
+func (p *Player) now() int64 {
+	if p.speed <= 0 {
+		return math.MaxInt64
+	}
+	elapsed := time.Duration(float64(time.Since(time.Unix(0, p.replayStart.Load()))) * p.speed)
+	return p.captureStart.Add(elapsed).UnixNano()
+}
+
+func (p *Player) waitUntil(ctx context.Context, captureTime int64) error {
+	if p.speed <= 0 {
+		return ctx.Err()
+	}
+
+	wait := time.Duration(float64(captureTime-p.now()) / p.speed)
+	if wait <= 0 {
+		return ctx.Err()
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(wait):
+		return nil
+	}
+}
+
+// rpcResponse returns the latest recorded response for a call at the current replay time,
+// with the id rewritten to match the replayed request.
+func (p *Player) rpcResponse(endpoint string, call rpcCall) json.RawMessage {
+	responses := p.rpc[rpcKey(endpoint, call.Method, call.Params)]
+	if len(responses) == 0 {
+		resp, _ := json.Marshal(map[string]any{
+			"jsonrpc": "2.0",
+			"id":      call.ID,
+			"error":   map[string]any{"code": -32000, "message": "call not found in capture"},
+		})
+		return resp
+	}
+
+	now := p.now()
+	i := sort.Search(len(responses), func(i int) bool { return responses[i].Time > now })
+	if i > 0 {
+		i--
+	}
+
+	var envelope map[string]json.RawMessage
+	if err := json.Unmarshal(responses[i].Data, &envelope); err != nil {
+		return responses[i].Data
+	}
+	envelope["id"] = call.ID
+
+	resp, _ := json.Marshal(envelope)
+	return resp
+}
@@ -0,0 +1,227 @@
+package capture
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"txpool-viz/internal/config"
+
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// replayURL is never dialled, requests are answered by the replay transport
+const replayURL = "http://replay.invalid"
+
+type rpcCall struct {
+	ID     json.RawMessage `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+}
+
+// parseRPCBody decodes a single or batched JSON-RPC body
+func parseRPCBody[T any](body []byte) ([]T, bool, error) {
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		var batch []T
+		err := json.Unmarshal(trimmed, &batch)
+		return batch, true, err
+	}
+
+	var single T
+	err := json.Unmarshal(trimmed, &single)
+	return []T{single}, false, err
+}
+
+// rpcKey identifies a call so replayed requests find their recorded responses
+func rpcKey(endpoint, method string, params json.RawMessage) string {
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, params); err != nil {
+		compact.Write(params)
+	}
+	return endpoint + "|" + method + "|" + compact.String()
+}
+
+// recordingTransport captures every JSON-RPC call and response passing through it
+type recordingTransport struct {
+	endpoint string
+	recorder *Recorder
+	next     http.RoundTripper
+}
+
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil {
+		var err error
+		if reqBody, err = io.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(reqBody))
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	t.record(reqBody, respBody, time.Now())
+
+	return resp, nil
+}
+
+// record pairs calls with responses by id, so batches are stored call by call
+func (t *recordingTransport) record(reqBody, respBody []byte, now time.Time) {
+	calls, _, err := parseRPCBody[rpcCall](reqBody)
+	if err != nil {
+		return
+	}
+
+	responses, _, err := parseRPCBody[json.RawMessage](respBody)
+	if err != nil {
+		return
+	}
+
+	byID := make(map[string]json.RawMessage, len(responses))
+	for _, resp := range responses {
+		var envelope struct {
+			ID json.RawMessage `json:"id"`
+		}
+		if json.Unmarshal(resp, &envelope) == nil {
+			byID[string(envelope.ID)] = resp
+		}
+	}
+
+	for _, call := range calls {
+		resp, ok := byID[string(call.ID)]
+		if !ok {
+			continue
+		}
+		t.recorder.write(Record{
+			Time:     now.UnixNano(),
+			Source:   SourceRPC,
+			Endpoint: t.endpoint,
+			Method:   call.Method,
+			Params:   call.Params,
+			Data:     resp,
+		})
+	}
+}
+
+// replayTransport answers JSON-RPC calls from a capture
+type replayTransport struct {
+	endpoint string
+	player   *Player
+}
+
+func (t *replayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil {
+		var err error
+		if reqBody, err = io.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+	}
+
+	calls, batch, err := parseRPCBody[rpcCall](reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("invalid replayed request: %w", err)
+	}
+
+	responses := make([]json.RawMessage, 0, len(calls))
+	for _, call := range calls {
+		responses = append(responses, t.player.rpcResponse(t.endpoint, call))
+	}
+
+	var body []byte
+	if batch {
+		body, err = json.Marshal(responses)
+	} else {
+		body, err = json.Marshal(responses[0])
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{"application/json"}},
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+// RecordEndpoints redials every endpoint's RPC client through a recording transport.
+func RecordEndpoints(ctx context.Context, cfg *config.Config, recorder *Recorder) error {
+	for i := range cfg.Endpoints {
+		if err := RecordEndpoint(ctx, &cfg.Endpoints[i], recorder); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RecordEndpoint dials the endpoint's RPC and engine API clients through a recording transport.
+func RecordEndpoint(ctx context.Context, endpoint *config.Endpoint, recorder *Recorder) error {
+	creds, err := endpoint.Credentials()
+	if err != nil {
+		return err
+	}
+	record := func(next http.RoundTripper) http.RoundTripper {
+		return &recordingTransport{endpoint: endpoint.Name, recorder: recorder, next: next}
+	}
+
+	rpcClient, err := creds.DialRPC(ctx, endpoint.RPCUrl, record)
+	if err != nil {
+		return fmt.Errorf("error connecting to client %s. rpc url: %s", endpoint.Name, endpoint.RPCUrl)
+	}
+
+	var engine *rpc.Client
+	if endpoint.EngineUrl != "" {
+		if engine, err = creds.DialRPC(ctx, endpoint.EngineUrl, record); err != nil {
+			rpcClient.Close()
+			return fmt.Errorf("error connecting to client %s. engine url: %s", endpoint.Name, endpoint.EngineUrl)
+		}
+	}
+
+	endpoint.Client = ethclient.NewClient(rpcClient)
+	endpoint.Engine = engine
+	return nil
+}
+
+// ReplayEndpoints points every endpoint's RPC and engine API clients at the capture instead of a live node.
+func ReplayEndpoints(ctx context.Context, cfg *config.Config, player *Player) error {
+	for i := range cfg.Endpoints {
+		endpoint := &cfg.Endpoints[i]
+		httpClient := &http.Client{Transport: &replayTransport{endpoint: endpoint.Name, player: player}}
+
+		rpcClient, err := rpc.DialOptions(ctx, replayURL, rpc.WithHTTPClient(httpClient))
+		if err != nil {
+			return err
+		}
+
+		endpoint.Client = ethclient.NewClient(rpcClient)
+		if endpoint.EngineUrl != "" {
+			endpoint.Engine = rpcClient
+		}
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package chain
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+
+	"txpool-viz/internal/logger"
+	"txpool-viz/internal/model"
+	"txpool-viz/utils"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/misc/eip1559"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/redis/go-redis/v9"
+)
+
+// maxBlockFees is how many recent blocks' fee state is kept
+const maxBlockFees = 1024
+
+// recordFees stores the fee state of a new head in the endpoint's blocks, replacing any block recorded at
+// its height. The next block's base fee is computed with go-ethereum's dev chain config, which has London
+// active from genesis and the EIP-1559 elasticity and change denominator every chain uses, so it holds
+// for any chain whose head has a base fee. The next blob base fee is asked from the node.
+func (t *Tracker) recordFees(ctx context.Context, head *Head) {
+	header := head.Header
+	if header.BaseFee == nil {
+		return
+	}
+
+	fees := model.BlockFees{
+		Number:      head.Number(),
+		Hash:        head.Hash.Hex(),
+		Timestamp:   int64(header.Time),
+		Coinbase:    header.Coinbase.Hex(),
+		GasUsed:     header.GasUsed,
+		GasLimit:    header.GasLimit,
+		BaseFee:     header.BaseFee.String(),
+		NextBaseFee: eip1559.CalcBaseFee(params.AllDevChainProtocolChanges, header).String(),
+	}
+	if header.BlobGasUsed != nil {
+		fees.BlobGasUsed = *header.BlobGasUsed
+	}
+	if header.ExcessBlobGas != nil {
+		var blobBaseFee hexutil.Big
+		if err := t.rpc.CallContext(ctx, &blobBaseFee, "eth_blobBaseFee"); err != nil {
+			t.logger.Debug("Error fetching blob base fee", logger.Fields{"endpoint": t.endpoint, "error": err.Error()})
+		} else {
+			fees.NextBlobBaseFee = blobBaseFee.ToInt().String()
+		}
+	}
+
+	data, err := json.Marshal(fees)
+	if err != nil {
+		return
+	}
+
+	key := utils.RedisBlockFeesKey(t.endpoint)
+	number := strconv.FormatUint(fees.Number, 10)
+	pipe := t.redis.Pipeline()
+	pipe.ZRemRangeByScore(ctx, key, number, number)
+	pipe.ZAdd(ctx, key, redis.Z{Score: float64(fees.Number), Member: data})
+	pipe.ZRemRangeByRank(ctx, key, 0, -maxBlockFees-1)
+	if _, err := pipe.Exec(ctx); err != nil {
+		t.logger.Error("Error storing block fees", logger.Fields{"endpoint": t.endpoint, "block": fees.Number, "error": err.Error()})
+	}
+}
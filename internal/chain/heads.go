@@ -0,0 +1,183 @@
+package chain
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"txpool-viz/internal/capture"
+	"txpool-viz/internal/config"
+	"txpool-viz/internal/logger"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const (
+	// Heads of endpoints without a socket are polled, a slot being 12s
+	headPollInterval = 2 * time.Second
+	maxPolledHeadGap = 64
+)
+
+// Head is a block header along with the hash the node reported for it. The reported hash is used
+// rather than one computed from the header, which is only right if this build knows every header field.
+type Head struct {
+	Header *types.Header
+	Hash   common.Hash
+	Raw    json.RawMessage // the header as the node sent it
+}
+
+func (h *Head) UnmarshalJSON(data []byte) error {
+	var header types.Header
+	if err := json.Unmarshal(data, &header); err != nil {
+		return err
+	}
+
+	var reported struct {
+		Hash *common.Hash `json:"hash"`
+	}
+	if err := json.Unmarshal(data, &reported); err != nil {
+		return err
+	}
+
+	h.Header = &header
+	h.Hash = header.Hash()
+	if reported.Hash != nil {
+		h.Hash = *reported.Hash
+	}
+	h.Raw = append(json.RawMessage(nil), data...)
+	return nil
+}
+
+// Number returns the head's block number
+func (h *Head) Number() uint64 {
+	return h.Header.Number.Uint64()
+}
+
+// HeadHandler is called with each new head of an endpoint, in the order they arrive
+type HeadHandler func(ctx context.Context, head *Head)
+
+// FollowHeads calls handle with each new head of an endpoint until ctx is cancelled. Heads come from the
+// newHeads subscription, or are polled over HTTP for endpoints without a socket. They are recorded when a
+// capture is being recorded.
+func FollowHeads(ctx context.Context, endpoint config.Endpoint, l logger.Logger, rec *capture.Recorder, handle HeadHandler) {
+	record := func(ctx context.Context, head *Head) {
+		rec.RecordHead(endpoint.Name, head.Raw, time.Now())
+		handle(ctx, head)
+	}
+
+	if endpoint.Websocket == "" {
+		pollHeads(ctx, endpoint, l, record)
+		return
+	}
+
+	// Dial WebSocket endpoint directly for the newHeads subscription, blocks are fetched over RPC
+	creds, err := endpoint.Credentials()
+	if err != nil {
+		l.Error("Failed to load endpoint credentials", "err", err.Error())
+		return
+	}
+
+	client, err := creds.DialRPC(ctx, endpoint.Websocket, nil)
+	if err != nil {
+		l.Error("Failed to connect to WebSocket endpoint", "err", err.Error())
+		return
+	}
+	defer client.Close()
+
+	// Subscribe to new block headers
+	heads := make(chan *Head)
+	sub, err := client.EthSubscribe(ctx, heads, "newHeads")
+	if err != nil {
+		l.Error("Error subscribing to newHeads", "err", err.Error())
+		return
+	}
+
+	defer sub.Unsubscribe()
+
+	l.Info("Subscribed to new block headers", logger.Fields{"endpoint": endpoint.Name})
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case err := <-sub.Err():
+			l.Error("Subscription error", "err", err)
+			return
+		case head := <-heads:
+			record(ctx, head)
+		}
+	}
+}
+
+// pollHeads follows the head of an endpoint without a socket over HTTP. Blocks mined between
+// two polls are handled as well, up to maxPolledHeadGap of them.
+func pollHeads(ctx context.Context, endpoint config.Endpoint, l logger.Logger, handle HeadHandler) {
+	ticker := time.NewTicker(headPollInterval)
+	defer ticker.Stop()
+
+	l.Info("Polling for new block headers", logger.Fields{"endpoint": endpoint.Name})
+
+	client := endpoint.Client.Client()
+	var last *Head
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		head, err := HeadByNumber(ctx, client, "latest")
+		if err != nil {
+			if ctx.Err() == nil {
+				l.Warn("Error polling block header", logger.Fields{"endpoint": endpoint.Name, "error": err.Error()})
+			}
+			continue
+		}
+
+		// A different head at the same height is a reorg the handlers should see
+		number := head.Number()
+		if last != nil && (number < last.Number() || head.Hash == last.Hash) {
+			continue
+		}
+
+		from := number
+		if last != nil && number-last.Number() <= maxPolledHeadGap {
+			from = last.Number() + 1
+		}
+		for n := from; n < number; n++ {
+			header, err := HeadByNumber(ctx, client, hexutil.EncodeUint64(n))
+			if err != nil {
+				l.Warn("Error fetching block header", logger.Fields{"endpoint": endpoint.Name, "block": n, "error": err.Error()})
+				continue
+			}
+			handle(ctx, header)
+		}
+		handle(ctx, head)
+		last = head
+	}
+}
+
+// HeadByNumber fetches the header of a block by number or tag
+func HeadByNumber(ctx context.Context, client *rpc.Client, number string) (*Head, error) {
+	return fetchHead(ctx, client, "eth_getBlockByNumber", number)
+}
+
+// HeadByHash fetches the header of a block by hash
+func HeadByHash(ctx context.Context, client *rpc.Client, hash common.Hash) (*Head, error) {
+	return fetchHead(ctx, client, "eth_getBlockByHash", hash)
+}
+
+func fetchHead(ctx context.Context, client *rpc.Client, method string, block any) (*Head, error) {
+	var head *Head
+	if err := client.CallContext(ctx, &head, method, block, false); err != nil {
+		return nil, err
+	}
+	if head == nil {
+		return nil, fmt.Errorf("block %v not found", block)
+	}
+	return head, nil
+}
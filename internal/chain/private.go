@@ -0,0 +1,111 @@
+package chain
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"txpool-viz/internal/logger"
+	"txpool-viz/internal/model"
+	"txpool-viz/utils"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// maxPrivateFlowBlocks is how many recent blocks' private flow is kept
+	maxPrivateFlowBlocks = 1024
+	// privateFlowClaim is how long a block stays claimed by the endpoint analysing it
+	privateFlowClaim = time.Hour
+)
+
+// recordPrivateFlow compares a new head's txs with every tx an endpoint announced. Txs no endpoint ever
+// announced were sent to the block's builder out of band, those announced only after the block's
+// timestamp most likely were too. Every endpoint sees the same blocks, so only the first to claim one
+// analyses it.
+func (t *Tracker) recordPrivateFlow(ctx context.Context, head *Head) {
+	claimed, err := t.redis.SetNX(ctx, utils.RedisPrivateFlowClaimKey(head.Hash.Hex()), t.endpoint, privateFlowClaim).Result()
+	if err != nil || !claimed {
+		return
+	}
+
+	var body struct {
+		Transactions []common.Hash `json:"transactions"`
+	}
+	if err := t.rpc.CallContext(ctx, &body, "eth_getBlockByHash", head.Hash, false); err != nil {
+		t.logger.Debug("Error fetching block txs", logger.Fields{"endpoint": t.endpoint, "block": head.Number(), "error": err.Error()})
+		t.redis.Del(ctx, utils.RedisPrivateFlowClaimKey(head.Hash.Hex()))
+		return
+	}
+
+	header := head.Header
+	report := model.PrivateFlowReport{
+		Number:     head.Number(),
+		Hash:       head.Hash.Hex(),
+		Timestamp:  int64(header.Time),
+		Coinbase:   header.Coinbase.Hex(),
+		Builder:    builderName(header.Extra),
+		Txs:        len(body.Transactions),
+		PrivateTxs: []string{},
+		LateTxs:    []string{},
+	}
+
+	pipe := t.redis.Pipeline()
+	scores := make([]*redis.FloatCmd, len(body.Transactions))
+	for i, hash := range body.Transactions {
+		scores[i] = pipe.ZScore(ctx, utils.RedisUniversalKey(), hash.Hex())
+	}
+	// Unseen txs fail their command with redis.Nil, which isn't an error here
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		t.logger.Error("Error looking up block txs", logger.Fields{"endpoint": t.endpoint, "block": report.Number, "error": err.Error()})
+		t.redis.Del(ctx, utils.RedisPrivateFlowClaimKey(report.Hash))
+		return
+	}
+
+	for i, hash := range body.Transactions {
+		firstSeen, err := scores[i].Result()
+		switch {
+		case err != nil:
+			report.PrivateTxs = append(report.PrivateTxs, hash.Hex())
+		case int64(firstSeen) > report.Timestamp:
+			report.LateTxs = append(report.LateTxs, hash.Hex())
+		default:
+			report.Public++
+		}
+	}
+	report.Private = len(report.PrivateTxs)
+	report.Late = len(report.LateTxs)
+	if report.Txs > 0 {
+		report.PrivateRatio = float64(report.Private) / float64(report.Txs)
+	}
+
+	data, err := json.Marshal(report)
+	if err != nil {
+		return
+	}
+
+	number := strconv.FormatUint(report.Number, 10)
+	pipe = t.redis.Pipeline()
+	pipe.ZRemRangeByScore(ctx, utils.RedisPrivateFlowKey(), number, number)
+	pipe.ZAdd(ctx, utils.RedisPrivateFlowKey(), redis.Z{Score: float64(report.Number), Member: data})
+	pipe.ZRemRangeByRank(ctx, utils.RedisPrivateFlowKey(), 0, -maxPrivateFlowBlocks-1)
+	if _, err := pipe.Exec(ctx); err != nil {
+		t.logger.Error("Error storing private flow", logger.Fields{"endpoint": t.endpoint, "block": report.Number, "error": err.Error()})
+	}
+}
+
+// builderName returns the printable part of a block's extra data, where most builders sign their blocks
+func builderName(extra []byte) string {
+	name := strings.Map(func(r rune) rune {
+		if r > unicode.MaxASCII || !unicode.IsPrint(r) {
+			return -1
+		}
+		return r
+	}, string(extra))
+	return strings.TrimSpace(name)
+}
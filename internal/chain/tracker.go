@@ -0,0 +1,206 @@
+package chain
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"sync"
+
+	"txpool-viz/internal/clock"
+	"txpool-viz/internal/config"
+	"txpool-viz/internal/logger"
+	"txpool-viz/internal/model"
+	"txpool-viz/internal/service"
+	"txpool-viz/internal/storage"
+	"txpool-viz/utils"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// canonicalWindow is how many recent blocks are kept to find where a reorg forked off
+	canonicalWindow = 128
+	// maxReorgEvents is how many reorg events the log keeps
+	maxReorgEvents = 1000
+)
+
+// block is a canonical chain entry
+type block struct {
+	number uint64
+	hash   common.Hash
+	parent common.Hash
+}
+
+func (b block) ref() model.BlockRef {
+	return model.BlockRef{Number: b.number, Hash: b.hash.Hex()}
+}
+
+// Tracker follows an endpoint's canonical chain through its heads. When a head doesn't extend the
+// chain it walks back to the common ancestor, reverts the txs mined in the removed blocks and logs
+// the reorg. The fee state and private flow of each head are recorded too.
+type Tracker struct {
+	endpoint string
+	rpc      *rpc.Client
+	storage  *storage.ClientStorage
+	redis    *redis.Client
+	logger   logger.Logger
+	clock    clock.Clock
+
+	mu        sync.Mutex
+	canonical map[uint64]block
+	head      block
+}
+
+// NewTracker creates the tracker of an endpoint's canonical chain. Reorgs are dated with clk.
+func NewTracker(endpoint config.Endpoint, srvc *service.Service, clk clock.Clock) *Tracker {
+	return &Tracker{
+		endpoint:  endpoint.Name,
+		rpc:       endpoint.Client.Client(),
+		storage:   storage.NewClientStorage(endpoint.Name, srvc.Redis, srvc.Logger),
+		redis:     srvc.Redis,
+		logger:    srvc.Logger,
+		clock:     clk,
+		canonical: make(map[uint64]block),
+	}
+}
+
+// Observe records a new head, handling the reorg it reveals if it doesn't extend the known chain
+func (t *Tracker) Observe(ctx context.Context, head *Head) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.recordFees(ctx, head)
+	t.recordPrivateFlow(ctx, head)
+
+	oldHead := t.head
+	added, removed, err := t.advance(ctx, head)
+	if err != nil {
+		t.logger.Warn("Error following the canonical chain", logger.Fields{"endpoint": t.endpoint, "block": head.Number(), "error": err.Error()})
+		return
+	}
+	t.prune(ctx)
+	if len(removed) == 0 {
+		return
+	}
+
+	event := model.ReorgEvent{
+		Endpoint:    t.endpoint,
+		DetectedAt:  t.clock.Now().Unix(),
+		Depth:       len(removed),
+		OldHead:     oldHead.ref(),
+		NewHead:     added[0].ref(),
+		AffectedTxs: []string{},
+	}
+	if fork := added[len(added)-1].number; fork > 0 {
+		event.CommonAncestor = fork - 1
+	}
+	for i := len(added) - 1; i >= 0; i-- {
+		event.Added = append(event.Added, added[i].ref())
+	}
+	for _, b := range removed {
+		event.Removed = append(event.Removed, b.ref())
+
+		reverted, err := t.storage.RevertMinedTransactions(ctx, b.hash, event.DetectedAt)
+		if err != nil {
+			t.logger.Error("Error reverting txs of a reorged block", logger.Fields{"endpoint": t.endpoint, "block": b.hash.Hex(), "error": err.Error()})
+		}
+		event.AffectedTxs = append(event.AffectedTxs, reverted...)
+	}
+
+	t.logger.Warn("Reorg detected", logger.Fields{
+		"endpoint": t.endpoint,
+		"depth":    event.Depth,
+		"ancestor": event.CommonAncestor,
+		"newHead":  event.NewHead.Hash,
+		"txs":      len(event.AffectedTxs),
+	})
+
+	if err := t.logReorg(ctx, event); err != nil {
+		t.logger.Error("Error storing reorg event", logger.Fields{"endpoint": t.endpoint, "error": err.Error()})
+	}
+}
+
+// advance makes head the tip of the canonical chain. It returns the blocks that became canonical,
+// newest first, and the ones that no longer are, oldest first.
+func (t *Tracker) advance(ctx context.Context, head *Head) ([]block, []block, error) {
+	next := block{number: head.Number(), hash: head.Hash, parent: head.Header.ParentHash}
+	added := []block{next}
+
+	// Walk back along the new chain until it meets a known block. The walk ends at the window's edge.
+	for next.number > 0 {
+		known, ok := t.canonical[next.number-1]
+		if !ok || known.hash == next.parent {
+			break
+		}
+
+		parent, err := HeadByHash(ctx, t.rpc, next.parent)
+		if err != nil {
+			// Start over from this head rather than walking back again on every head
+			t.canonical = map[uint64]block{added[0].number: added[0]}
+			t.head = added[0]
+			return nil, nil, err
+		}
+		next = block{number: parent.Number(), hash: parent.Hash, parent: parent.Header.ParentHash}
+		added = append(added, next)
+	}
+
+	fork := added[len(added)-1].number
+	newChain := make(map[common.Hash]bool, len(added))
+	for _, b := range added {
+		newChain[b.hash] = true
+	}
+
+	var removed []block
+	for number, b := range t.canonical {
+		if number < fork {
+			continue
+		}
+		if !newChain[b.hash] {
+			removed = append(removed, b)
+		}
+		delete(t.canonical, number)
+	}
+	sort.Slice(removed, func(i, j int) bool { return removed[i].number < removed[j].number })
+
+	for _, b := range added {
+		t.canonical[b.number] = b
+	}
+	t.head = added[0]
+
+	return added, removed, nil
+}
+
+// prune drops the blocks that left the canonical window along with the sets of txs stored as mined in
+// them, a reorg that deep isn't followed
+func (t *Tracker) prune(ctx context.Context) {
+	var keys []string
+	for number, b := range t.canonical {
+		if number+canonicalWindow <= t.head.number {
+			delete(t.canonical, number)
+			keys = append(keys, utils.RedisBlockTxsKey(t.endpoint, b.hash.Hex()))
+		}
+	}
+	if len(keys) == 0 {
+		return
+	}
+
+	if err := t.redis.Del(ctx, keys...).Err(); err != nil {
+		t.logger.Warn("Error deleting txs of old blocks", logger.Fields{"endpoint": t.endpoint, "blocks": len(keys), "error": err.Error()})
+	}
+}
+
+// logReorg prepends the event to the reorg log, keeping the newest maxReorgEvents
+func (t *Tracker) logReorg(ctx context.Context, event model.ReorgEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	pipe := t.redis.Pipeline()
+	pipe.LPush(ctx, utils.RedisReorgLogKey(), data)
+	pipe.LTrim(ctx, utils.RedisReorgLogKey(), 0, maxReorgEvents-1)
+	_, err = pipe.Exec(ctx)
+	return err
+}
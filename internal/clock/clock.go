@@ -0,0 +1,18 @@
+package clock
+
+import "time"
+
+// Clock tells the time the processors stamp and schedule txs with. Live endpoints use the wall clock,
+// a replay the capture time it has reached.
+type Clock interface {
+	Now() time.Time
+}
+
+type wall struct{}
+
+func (wall) Now() time.Time {
+	return time.Now()
+}
+
+// Wall is the system clock
+var Wall Clock = wall{}
@@ -0,0 +1,227 @@
+package config
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/gorilla/websocket"
+)
+
+// jwtRefresh is how long a JWT is reused. Execution clients reject tokens whose iat is more than 60s off.
+const jwtRefresh = 30 * time.Second
+
+// TLS configures the client side of TLS connections to a node
+type TLS struct {
+	CertFile           string `yaml:"cert_file" json:"cert_file"`                       // PEM client certificate, sent when the node asks for one
+	KeyFile            string `yaml:"key_file" json:"key_file"`                         // PEM key of cert_file
+	CAFile             string `yaml:"ca_file" json:"ca_file"`                           // PEM CA bundle trusted instead of the system roots
+	ServerName         string `yaml:"server_name" json:"server_name"`                   // Name to verify instead of the url host
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify" json:"insecure_skip_verify"` // Accept any server certificate
+}
+
+// Credentials authenticate the connections to a node with static headers, an engine API style JWT
+// and TLS client settings
+type Credentials struct {
+	headers map[string]string
+	jwt     *jwtSigner // nil without a jwt_secret_file
+	tls     *tls.Config
+}
+
+// Credentials loads the JWT secret and TLS files of the endpoint
+func (e *Endpoint) Credentials() (*Credentials, error) {
+	creds, err := newCredentials(e.AuthHeaders, e.JWTSecretFile, e.TLS)
+	if err != nil {
+		return nil, fmt.Errorf("endpoint %s: %w", e.Name, err)
+	}
+	return creds, nil
+}
+
+// Credentials loads the JWT secret and TLS files of the beacon node
+func (b *BeaconEndpoint) Credentials() (*Credentials, error) {
+	creds, err := newCredentials(b.AuthHeaders, b.JWTSecretFile, b.TLS)
+	if err != nil {
+		return nil, fmt.Errorf("beacon node %s: %w", b.Name, err)
+	}
+	return creds, nil
+}
+
+func newCredentials(headers map[string]string, jwtSecretFile string, cfg TLS) (*Credentials, error) {
+	creds := &Credentials{headers: headers}
+
+	if jwtSecretFile != "" {
+		secret, err := ReadJWTSecret(jwtSecretFile)
+		if err != nil {
+			return nil, err
+		}
+		creds.jwt = &jwtSigner{secret: secret}
+	}
+
+	tlsConfig, err := cfg.load()
+	if err != nil {
+		return nil, err
+	}
+	creds.tls = tlsConfig
+
+	return creds, nil
+}
+
+// Apply sets the auth headers and a current JWT on h
+func (c *Credentials) Apply(h http.Header) error {
+	for name, value := range c.headers {
+		h.Set(name, value)
+	}
+	if c.jwt != nil {
+		h.Set("Authorization", "Bearer "+c.jwt.token())
+	}
+	return nil
+}
+
+// Header returns the headers to send when opening a connection
+func (c *Credentials) Header() http.Header {
+	h := make(http.Header)
+	c.Apply(h)
+	return h
+}
+
+// Transport returns an HTTP transport using the TLS settings. It adds no headers.
+func (c *Credentials) Transport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = c.tls
+	return transport
+}
+
+// HTTPClient returns a client sending the headers with every request
+func (c *Credentials) HTTPClient(timeout time.Duration) *http.Client {
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: &authTransport{creds: c, next: c.Transport()},
+	}
+}
+
+// DialRPC connects a JSON-RPC client over HTTP or websocket. wrap, when given, wraps the HTTP transport,
+// e.g. to record the responses.
+func (c *Credentials) DialRPC(ctx context.Context, rawurl string, wrap func(http.RoundTripper) http.RoundTripper) (*rpc.Client, error) {
+	var transport http.RoundTripper = c.Transport()
+	if wrap != nil {
+		transport = wrap(transport)
+	}
+
+	return rpc.DialOptions(ctx, rawurl,
+		rpc.WithHTTPClient(&http.Client{Transport: transport}),
+		rpc.WithWebsocketDialer(websocket.Dialer{
+			ReadBufferSize:  1024,
+			WriteBufferSize: 1024,
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: c.tls,
+		}),
+		rpc.WithHTTPAuth(c.Apply),
+	)
+}
+
+// authTransport adds the credentials to every request
+type authTransport struct {
+	creds *Credentials
+	next  http.RoundTripper
+}
+
+func (t *authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	if err := t.creds.Apply(req.Header); err != nil {
+		return nil, err
+	}
+	return t.next.RoundTrip(req)
+}
+
+// load builds the tls.Config, or returns nil to use the defaults
+func (t TLS) load() (*tls.Config, error) {
+	if t == (TLS{}) {
+		return nil, nil
+	}
+
+	cfg := &tls.Config{
+		ServerName:         t.ServerName,
+		InsecureSkipVerify: t.InsecureSkipVerify,
+	}
+
+	if t.CertFile != "" || t.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("loading tls client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	if t.CAFile != "" {
+		pem, err := os.ReadFile(t.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading tls ca file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", t.CAFile)
+		}
+		cfg.RootCAs = pool
+	}
+
+	return cfg, nil
+}
+
+// ReadJWTSecret reads a hex encoded 32 byte secret, the format execution clients write to jwt.hex
+func ReadJWTSecret(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading jwt secret: %w", err)
+	}
+
+	secret, err := hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(string(data)), "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("jwt secret in %s is not hex", path)
+	}
+	if len(secret) != 32 {
+		return nil, fmt.Errorf("jwt secret in %s is %d bytes, expected 32", path, len(secret))
+	}
+	return secret, nil
+}
+
+// jwtSigner issues HS256 tokens carrying only an iat claim, as the engine API does, and reissues
+// them before they go stale
+type jwtSigner struct {
+	secret []byte
+
+	mu     sync.Mutex
+	issued time.Time
+	cached string
+}
+
+var jwtHeader = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+func (j *jwtSigner) token() string {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	now := time.Now()
+	if j.cached != "" && now.Sub(j.issued) < jwtRefresh {
+		return j.cached
+	}
+
+	claims := base64.RawURLEncoding.EncodeToString([]byte(`{"iat":` + strconv.FormatInt(now.Unix(), 10) + `}`))
+	mac := hmac.New(sha256.New, j.secret)
+	mac.Write([]byte(jwtHeader + "." + claims))
+
+	j.cached = jwtHeader + "." + claims + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+	j.issued = now
+	return j.cached
+}
@@ -1,25 +1,38 @@
 package config
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
 	_ "github.com/joho/godotenv/autoload"
 	"gopkg.in/yaml.v3"
 )
 
 type Endpoint struct {
-	Name        string            `yaml:"name" json:"name"`
-	RPCUrl      string            `yaml:"rpc_url" json:"rpc_url"`
-	Websocket   string            `yaml:"socket" json:"socket"`
-	AuthHeaders map[string]string `yaml:"auth_headers" json:"auth_headers"`
-	Client      *ethclient.Client `yaml:"-" json:"-"`
+	Name          string            `yaml:"name" json:"name"`
+	RPCUrl        string            `yaml:"rpc_url" json:"rpc_url"`
+	Websocket     string            `yaml:"socket" json:"socket"`                 // Optional, without it pending txs are polled over rpc_url
+	PendingSource string            `yaml:"pending_source" json:"pending_source"` // subscription, filter or txpool, defaults by socket
+	AuthHeaders   map[string]string `yaml:"auth_headers" json:"auth_headers"`
+	JWTSecretFile string            `yaml:"jwt_secret_file" json:"jwt_secret_file"` // Hex secret for HS256 tokens, as used by the engine API
+	TLS           TLS               `yaml:"tls" json:"tls"`
+	EngineUrl     string            `yaml:"engine_url" json:"engine_url"`   // Optional engine API asked which blob sidecars the node holds
+	BatchSize     int               `yaml:"batch_size" json:"batch_size"`   // Overrides polling.batch_size
+	Concurrency   int               `yaml:"concurrency" json:"concurrency"` // Overrides polling.concurrency
+	Client        *ethclient.Client `yaml:"-" json:"-"`
+	Engine        *rpc.Client       `yaml:"-" json:"-"` // nil without an engine_url
 }
 
 type BeaconEndpoint struct {
-	Name      string `yaml:"name" json:"name"`
-	BeaconUrl string `yaml:"beacon_url" json:"beacon_url"`
+	Name          string            `yaml:"name" json:"name"`
+	BeaconUrl     string            `yaml:"beacon_url" json:"beacon_url"`
+	AuthHeaders   map[string]string `yaml:"auth_headers" json:"auth_headers"`
+	JWTSecretFile string            `yaml:"jwt_secret_file" json:"jwt_secret_file"` // Hex secret for HS256 tokens
+	TLS           TLS               `yaml:"tls" json:"tls"`
 }
 
 type Config struct {
@@ -29,21 +42,164 @@ type Config struct {
 	Filters      Filters          `yaml:"filters" json:"filters"`
 	LogLevel     string           `yaml:"log_level" json:"log_level"`
 	FocilEnabled string           `yaml:"focil_enabled" json:"focil_enabled"`
+
+	FocilVerification FocilVerification `yaml:"focil_verification" json:"focil_verification"`
+	FocilBackfill     FocilBackfill     `yaml:"focil_backfill" json:"focil_backfill"`
+	Capture           Capture           `yaml:"capture" json:"capture"`
+	Reload            Reload            `yaml:"reload" json:"reload"`
+	Stats             Stats             `yaml:"stats" json:"stats"`
+	Censorship        Censorship        `yaml:"censorship" json:"censorship"`
+	Decoding          Decoding          `yaml:"decoding" json:"decoding"`
+
+	File          string   `yaml:"-" json:"-"` // config file the values were read from
+	EnvOverrides  []string `yaml:"-" json:"-"` // TXPOOLVIZ_* variables applied on top of the file
+	FlagOverrides []string `yaml:"-" json:"-"` // field paths set from the command line
+
+	overrides []string // path=value pairs behind FlagOverrides, reapplied by Reresolve
+}
+
+// FocilVerification configures inclusion list signature and committee checks
+type FocilVerification struct {
+	Enabled       bool   `yaml:"enabled" json:"enabled"`
+	BeaconUrl     string `yaml:"beacon_url" json:"beacon_url"`         // Defaults to the first beacon_urls entry
+	CommitteePath string `yaml:"committee_path" json:"committee_path"` // Beacon API path template taking the slot
+	StubFile      string `yaml:"stub_file" json:"stub_file"`           // Local committee stub used instead of the beacon API
+}
+
+// FocilBackfill configures rebuilding inclusion reports for past slots
+type FocilBackfill struct {
+	InclusionListPath string `yaml:"inclusion_list_path" json:"inclusion_list_path"` // Beacon API path template taking the slot
+	CaptureDir        string `yaml:"capture_dir" json:"capture_dir"`                 // Directory of the captures a backfill request may name, unset disables them
+}
+
+// Capture configures recording a session to a capture file or replaying one instead of live nodes
+type Capture struct {
+	RecordFile  string  `yaml:"record_file" json:"record_file"`   // File or directory to record to
+	ReplayFile  string  `yaml:"replay_file" json:"replay_file"`   // Capture to replay, takes precedence over record_file
+	ReplaySpeed float64 `yaml:"replay_speed" json:"replay_speed"` // 1 replays in real time, 0 as fast as possible
+}
+
+// Reload configures changing endpoints and beacon nodes while running
+type Reload struct {
+	Watch      bool   `yaml:"watch" json:"watch"`             // Apply config file changes as they are saved
+	Interval   string `yaml:"interval" json:"interval"`       // How often the file is checked, defaults to 2s
+	AdminToken string `yaml:"admin_token" json:"admin_token"` // Bearer token required by the admin API when set
+}
+
+// Stats configures the mempool time series sampler
+type Stats struct {
+	Interval string `yaml:"interval" json:"interval"` // How often the clients' pools are sampled, defaults to 5s
+}
+
+// Censorship configures the detector of txs left pending by block after block
+type Censorship struct {
+	MinBlocks int    `yaml:"min_blocks" json:"min_blocks"` // A tx is reported once more blocks than this pass it over, defaults to 3
+	Interval  string `yaml:"interval" json:"interval"`     // How often stuck txs are looked for to raise alerts, defaults to 12s
+}
+
+// Decoding configures the signatures calldata is decoded against, on top of the bundled ones
+type Decoding struct {
+	SignaturesFile string   `yaml:"signatures_file" json:"signatures_file"` // Function signatures, one per line like transfer(address,uint256)
+	ABIFiles       []string `yaml:"abi_files" json:"abi_files"`             // Contract ABI JSON files whose functions name their arguments
 }
 
 type Polling struct {
-	Interval string `yaml:"interval" json:"interval"`
-	Timeout  string `yaml:"timeout" json:"timeout"`
+	Interval        string `yaml:"interval" json:"interval"`
+	Timeout         string `yaml:"timeout" json:"timeout"`
+	PendingInterval string `yaml:"pending_interval" json:"pending_interval"` // How often endpoints without a subscription are polled for new txs, defaults to 1s
+	BatchSize       int    `yaml:"batch_size" json:"batch_size"`             // Queued txs checked per JSON-RPC batch, defaults to 100
+	Concurrency     int    `yaml:"concurrency" json:"concurrency"`           // Batches in flight per endpoint, defaults to 4
+	RecheckMin      string `yaml:"recheck_min" json:"recheck_min"`           // Shortest wait between checks of a tx, defaults to 1s
+	RecheckMax      string `yaml:"recheck_max" json:"recheck_max"`           // Longest wait between checks of a tx, defaults to 1m
 }
 
 type Filters struct {
 	MinGasPrice string `yaml:"min_gas_price" json:"min_gas_price"`
 }
 
+// Sources of an endpoint's new pending txs
+const (
+	PendingSubscription = "subscription" // newPendingTransactions over the socket
+	PendingFilter       = "filter"       // eth_newPendingTransactionFilter, falling back to txpool when unsupported
+	PendingTxpool       = "txpool"       // txpool_content diffs
+)
+
+// DefaultPendingInterval is how often pending txs are polled when polling.pending_interval is unset
+const DefaultPendingInterval = time.Second
+
+// DefaultStatsInterval is how often the pools are sampled when stats.interval is unset
+const DefaultStatsInterval = 5 * time.Second
+
+// Defaults for censorship.min_blocks and censorship.interval
+const (
+	DefaultCensorshipMinBlocks = 3
+	DefaultCensorshipInterval  = 12 * time.Second
+)
+
+// Defaults for polling.batch_size and polling.concurrency
+const (
+	DefaultBatchSize   = 100
+	DefaultConcurrency = 4
+)
+
+// Defaults for polling.recheck_min and polling.recheck_max
+const (
+	DefaultRecheckMin = time.Second
+	DefaultRecheckMax = time.Minute
+)
+
+// Batching returns the endpoint's batch size and concurrency, falling back to the polling defaults
+func (e *Endpoint) Batching(polling Polling) (batchSize, concurrency int) {
+	batchSize, concurrency = polling.BatchSize, polling.Concurrency
+	if e.BatchSize > 0 {
+		batchSize = e.BatchSize
+	}
+	if e.Concurrency > 0 {
+		concurrency = e.Concurrency
+	}
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+	if concurrency <= 0 {
+		concurrency = DefaultConcurrency
+	}
+	return batchSize, concurrency
+}
+
+// Pending returns where the endpoint's new pending txs come from: the subscription when a socket is
+// configured, a pending tx filter otherwise
+func (e *Endpoint) Pending() string {
+	if e.PendingSource != "" {
+		return e.PendingSource
+	}
+	if e.Websocket != "" {
+		return PendingSubscription
+	}
+	return PendingFilter
+}
+
+// DefaultPath is the config file read when no other path is given
+const DefaultPath = "cfg/config.yaml"
+
+// Load resolves the config from its file and environment and connects to the endpoints
 func Load() (*Config, error) {
+	userConfig, err := Resolve("")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := userConfig.Connect(); err != nil {
+		return nil, err
+	}
+
+	return userConfig, nil
+}
+
+// LoadFile parses a config file without connecting to any endpoint. A missing file yields an empty config.
+func LoadFile(path string) (*Config, error) {
 	userConfig := &Config{}
 	// Attempt to read config.yaml first
-	cfgData, err := os.ReadFile("cfg/config.yaml")
+	cfgData, err := os.ReadFile(path)
 	if err == nil {
 		err = yaml.Unmarshal(cfgData, userConfig)
 		if err != nil {
@@ -51,16 +207,51 @@ func Load() (*Config, error) {
 		}
 	}
 
-	// Create clients for the Endpoints
-	for i := range userConfig.Endpoints {
-		client, err := ethclient.Dial(userConfig.Endpoints[i].RPCUrl)
+	return userConfig, nil
+}
 
-		if err != nil {
-			return nil, fmt.Errorf("Error connecting to client %s. rpc url: %s", userConfig.Endpoints[i].Name, userConfig.Endpoints[i].RPCUrl)
+// Connect creates the RPC clients for the Endpoints
+func (c *Config) Connect() error {
+	for i := range c.Endpoints {
+		if err := c.Endpoints[i].Connect(); err != nil {
+			return err
 		}
+	}
+
+	return nil
+}
 
-		userConfig.Endpoints[i].Client = client
+// Connect creates the RPC client of the endpoint, authenticated with its credentials
+func (e *Endpoint) Connect() error {
+	creds, err := e.Credentials()
+	if err != nil {
+		return err
 	}
 
-	return userConfig, nil
+	client, err := creds.DialRPC(context.Background(), e.RPCUrl, nil)
+	if err != nil {
+		return fmt.Errorf("Error connecting to client %s. rpc url: %s", e.Name, e.RPCUrl)
+	}
+
+	e.Client = ethclient.NewClient(client)
+
+	if e.EngineUrl != "" {
+		engine, err := creds.DialRPC(context.Background(), e.EngineUrl, nil)
+		if err != nil {
+			client.Close()
+			return fmt.Errorf("Error connecting to client %s. engine url: %s", e.Name, e.EngineUrl)
+		}
+		e.Engine = engine
+	}
+	return nil
+}
+
+// Close closes the endpoint's clients
+func (e *Endpoint) Close() {
+	if e.Client != nil {
+		e.Client.Close()
+	}
+	if e.Engine != nil {
+		e.Engine.Close()
+	}
 }
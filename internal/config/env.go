@@ -0,0 +1,175 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+const (
+	// EnvPrefix marks environment variables that override config fields, e.g. TXPOOLVIZ_POLLING_INTERVAL
+	// or TXPOOLVIZ_ENDPOINTS_0_RPC_URL. Map entries keep the case of their key: TXPOOLVIZ_BEACON_URLS_0_AUTH_HEADERS_Authorization.
+	EnvPrefix = "TXPOOLVIZ_"
+
+	// EnvConfigPath selects the config file when no path is given
+	EnvConfigPath = EnvPrefix + "CONFIG"
+)
+
+// Resolve reads the config file at path, or TXPOOLVIZ_CONFIG / DefaultPath when path is empty, and applies
+// the TXPOOLVIZ_* environment overrides. It does not connect to any endpoint.
+func Resolve(path string) (*Config, error) {
+	if path == "" {
+		path = os.Getenv(EnvConfigPath)
+	}
+	if path == "" {
+		path = DefaultPath
+	}
+
+	cfg, err := LoadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	cfg.File = path
+
+	if err := cfg.ApplyEnv(os.Environ()); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Override sets a field like Set and remembers it as a command line override, so it survives Reresolve.
+func (c *Config) Override(path, value string) error {
+	if err := c.Set(path, value); err != nil {
+		return err
+	}
+	c.FlagOverrides = append(c.FlagOverrides, path)
+	c.overrides = append(c.overrides, path+"="+value)
+	return nil
+}
+
+// Reresolve reads the config again from the same file and environment and reapplies the command
+// line overrides. The endpoints of the returned config are not connected.
+func (c *Config) Reresolve() (*Config, error) {
+	next, err := Resolve(c.File)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, override := range c.overrides {
+		path, value, _ := strings.Cut(override, "=")
+		if err := next.Override(path, value); err != nil {
+			return nil, err
+		}
+	}
+	return next, nil
+}
+
+// ApplyEnv applies every TXPOOLVIZ_* variable of environ, in name order so list entries are appended by index.
+func (c *Config) ApplyEnv(environ []string) error {
+	var names []string
+	values := make(map[string]string)
+	for _, kv := range environ {
+		name, value, ok := strings.Cut(kv, "=")
+		if !ok || !strings.HasPrefix(name, EnvPrefix) || name == EnvConfigPath {
+			continue
+		}
+		names = append(names, name)
+		values[name] = value
+	}
+
+	// Numeric order keeps ENDPOINTS_2 after ENDPOINTS_10 from appending out of order
+	sort.Slice(names, func(i, j int) bool { return naturalLess(names[i], names[j]) })
+
+	for _, name := range names {
+		path, err := envPath(reflect.TypeOf(Config{}), strings.TrimPrefix(name, EnvPrefix))
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		if err := c.Set(path, values[name]); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		c.EnvOverrides = append(c.EnvOverrides, name)
+	}
+	return nil
+}
+
+// envPath translates the rest of a variable name into a dotted field path. Field names contain
+// underscores themselves, so the config type decides where one segment ends.
+func envPath(t reflect.Type, rest string) (string, error) {
+	switch t.Kind() {
+	case reflect.Struct:
+		upper := strings.ToUpper(rest)
+		match := ""
+		var matchField reflect.StructField
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name := yamlName(field)
+			if name == "" {
+				continue
+			}
+			key := strings.ToUpper(name)
+			if (upper == key || strings.HasPrefix(upper, key+"_")) && len(name) > len(match) {
+				match, matchField = name, field
+			}
+		}
+		if match == "" {
+			return "", fmt.Errorf("unknown field %q", rest)
+		}
+		if len(rest) == len(match) {
+			return match, nil
+		}
+		sub, err := envPath(matchField.Type, rest[len(match)+1:])
+		if err != nil {
+			return "", err
+		}
+		return match + "." + sub, nil
+
+	case reflect.Slice:
+		index, sub, found := strings.Cut(rest, "_")
+		if !found {
+			return index, nil
+		}
+		subPath, err := envPath(t.Elem(), sub)
+		if err != nil {
+			return "", err
+		}
+		return index + "." + subPath, nil
+
+	case reflect.Map:
+		return rest, nil
+	}
+
+	return "", fmt.Errorf("unknown field %q", rest)
+}
+
+// naturalLess compares names with runs of digits compared by value
+func naturalLess(a, b string) bool {
+	for a != "" && b != "" {
+		da, db := digitPrefix(a), digitPrefix(b)
+		if da != "" && db != "" {
+			if len(da) != len(db) {
+				return len(da) < len(db)
+			}
+			if da != db {
+				return da < db
+			}
+			a, b = a[len(da):], b[len(db):]
+			continue
+		}
+		if a[0] != b[0] {
+			return a[0] < b[0]
+		}
+		a, b = a[1:], b[1:]
+	}
+	return len(a) < len(b)
+}
+
+func digitPrefix(s string) string {
+	i := 0
+	for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+		i++
+	}
+	return s[:i]
+}
@@ -0,0 +1,156 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Set overrides a single field addressed by its dotted yaml path, e.g. "polling.interval",
+// "endpoints.0.rpc_url" or "beacon_urls.1.auth_headers.Authorization". Indexing one past the
+// end of a list appends an entry.
+func (c *Config) Set(path, value string) error {
+	if path == "" {
+		return fmt.Errorf("empty config path")
+	}
+	if err := setPath(reflect.ValueOf(c).Elem(), strings.Split(path, "."), value); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// Field is a scalar config field that can be overridden
+type Field struct {
+	Path string // dotted yaml path
+	Bool bool
+}
+
+// Fields lists every scalar field outside of lists and maps.
+func Fields() []Field {
+	var fields []Field
+	collectFields(reflect.TypeOf(Config{}), "", &fields)
+	return fields
+}
+
+func collectFields(t reflect.Type, prefix string, fields *[]Field) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := yamlName(field)
+		if name == "" {
+			continue
+		}
+
+		switch field.Type.Kind() {
+		case reflect.Struct:
+			collectFields(field.Type, prefix+name+".", fields)
+		case reflect.String, reflect.Bool, reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64, reflect.Float64:
+			*fields = append(*fields, Field{Path: prefix + name, Bool: field.Type.Kind() == reflect.Bool})
+		}
+	}
+}
+
+func setPath(v reflect.Value, path []string, value string) error {
+	switch v.Kind() {
+	case reflect.Struct:
+		if len(path) == 0 {
+			return fmt.Errorf("is a section, not a field")
+		}
+		for i := 0; i < v.NumField(); i++ {
+			if yamlName(v.Type().Field(i)) == path[0] {
+				return setPath(v.Field(i), path[1:], value)
+			}
+		}
+		return fmt.Errorf("unknown field %q", path[0])
+
+	case reflect.Slice:
+		if len(path) == 0 {
+			// Scalar lists take comma separated values
+			if v.Type().Elem().Kind() != reflect.String {
+				return fmt.Errorf("is a list, address an entry by index")
+			}
+			items := []string{}
+			for _, item := range strings.Split(value, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					items = append(items, item)
+				}
+			}
+			v.Set(reflect.ValueOf(items))
+			return nil
+		}
+
+		index, err := strconv.Atoi(path[0])
+		if err != nil || index < 0 || index > v.Len() {
+			return fmt.Errorf("invalid index %q, list has %d entries", path[0], v.Len())
+		}
+		if index == v.Len() {
+			v.Set(reflect.Append(v, reflect.Zero(v.Type().Elem())))
+		}
+		return setPath(v.Index(index), path[1:], value)
+
+	case reflect.Map:
+		if len(path) != 1 {
+			return fmt.Errorf("address a map entry by key")
+		}
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(v.Type()))
+		}
+		elem := reflect.New(v.Type().Elem()).Elem()
+		if err := setScalar(elem, value); err != nil {
+			return err
+		}
+		v.SetMapIndex(reflect.ValueOf(path[0]), elem)
+		return nil
+	}
+
+	if len(path) != 0 {
+		return fmt.Errorf("unknown field %q", strings.Join(path, "."))
+	}
+	return setScalar(v, value)
+}
+
+func setScalar(v reflect.Value, value string) error {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid bool %q", value)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", value)
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", value)
+		}
+		v.SetUint(n)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", value)
+		}
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported field type %s", v.Type())
+	}
+	return nil
+}
+
+// yamlName returns the yaml key of a field, or "" for fields not read from the config file
+func yamlName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+	if name == "-" || !field.IsExported() {
+		return ""
+	}
+	if name == "" {
+		return strings.ToLower(field.Name)
+	}
+	return name
+}
@@ -0,0 +1,111 @@
+package config
+
+import (
+	"net/url"
+	"strings"
+)
+
+const redacted = "REDACTED"
+
+// Resolved describes the running config and where its values came from
+type Resolved struct {
+	File          string   `json:"file"`
+	EnvOverrides  []string `json:"env_overrides"`
+	FlagOverrides []string `json:"flag_overrides"`
+	Config        Config   `json:"config"`
+}
+
+// Resolved returns the redacted config along with its sources.
+func (c *Config) Resolved() Resolved {
+	return Resolved{
+		File:          c.File,
+		EnvOverrides:  append([]string{}, c.EnvOverrides...),
+		FlagOverrides: append([]string{}, c.FlagOverrides...),
+		Config:        c.Redacted(),
+	}
+}
+
+// Redacted returns a copy of the config that is safe to show, with auth header values,
+// URL credentials, URL paths and URL query values masked.
+func (c *Config) Redacted() Config {
+	out := *c
+
+	out.Endpoints = make([]Endpoint, len(c.Endpoints))
+	for i, endpoint := range c.Endpoints {
+		endpoint.RPCUrl = redactURL(endpoint.RPCUrl)
+		endpoint.Websocket = redactURL(endpoint.Websocket)
+		endpoint.EngineUrl = redactURL(endpoint.EngineUrl)
+		endpoint.AuthHeaders = redactHeaders(endpoint.AuthHeaders)
+		endpoint.Client, endpoint.Engine = nil, nil
+		out.Endpoints[i] = endpoint
+	}
+
+	out.BeaconUrls = make([]BeaconEndpoint, len(c.BeaconUrls))
+	for i, beacon := range c.BeaconUrls {
+		beacon.BeaconUrl = redactURL(beacon.BeaconUrl)
+		beacon.AuthHeaders = redactHeaders(beacon.AuthHeaders)
+		out.BeaconUrls[i] = beacon
+	}
+
+	out.FocilVerification.BeaconUrl = redactURL(c.FocilVerification.BeaconUrl)
+	if out.Reload.AdminToken != "" {
+		out.Reload.AdminToken = redacted
+	}
+
+	return out
+}
+
+func redactHeaders(headers map[string]string) map[string]string {
+	if headers == nil {
+		return nil
+	}
+	out := make(map[string]string, len(headers))
+	for name := range headers {
+		out[name] = redacted
+	}
+	return out
+}
+
+// redactURL masks the userinfo, path segments and query values of a URL, which commonly carry API
+// keys, e.g. Infura's /v3/<key>. Only the scheme, host and port are kept.
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || raw == "" {
+		return raw
+	}
+	if u.Host == "" {
+		// Not an absolute URL, nothing can be told apart from a key
+		return redacted
+	}
+
+	if u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(redacted, redacted)
+		} else {
+			u.User = url.User(redacted)
+		}
+	}
+
+	if u.Path != "" {
+		segments := strings.Split(u.Path, "/")
+		for i, segment := range segments {
+			if segment != "" {
+				segments[i] = redacted
+			}
+		}
+		u.Path = strings.Join(segments, "/")
+		u.RawPath = ""
+	}
+	u.Fragment = ""
+	u.RawFragment = ""
+
+	if u.RawQuery != "" {
+		query := u.Query()
+		for key := range query {
+			query.Set(key, redacted)
+		}
+		u.RawQuery = query.Encode()
+	}
+
+	return u.String()
+}
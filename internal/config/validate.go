@@ -0,0 +1,236 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"time"
+)
+
+// Validate checks the config for mistakes that would only surface once the services are running.
+// Every problem found is returned, not just the first.
+func (c *Config) Validate() []error {
+	var errs []error
+	addErr := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if len(c.Endpoints) == 0 {
+		addErr("endpoints: at least one endpoint is required")
+	}
+
+	names := make(map[string]bool)
+	for i, endpoint := range c.Endpoints {
+		if endpoint.Name == "" {
+			addErr("endpoints.%d.name: required", i)
+		} else if names[endpoint.Name] {
+			addErr("endpoints.%d.name: duplicate name %q", i, endpoint.Name)
+		}
+		names[endpoint.Name] = true
+
+		if err := checkURL(endpoint.RPCUrl, "http", "https", "ws", "wss"); err != nil {
+			addErr("endpoints.%d.rpc_url: %s", i, err)
+		}
+		if endpoint.Websocket != "" {
+			if err := checkURL(endpoint.Websocket, "ws", "wss"); err != nil {
+				addErr("endpoints.%d.socket: %s", i, err)
+			}
+		}
+		switch endpoint.PendingSource {
+		case "", PendingFilter, PendingTxpool:
+		case PendingSubscription:
+			if endpoint.Websocket == "" {
+				addErr("endpoints.%d.pending_source: subscription needs a socket", i)
+			}
+		default:
+			addErr("endpoints.%d.pending_source: must be subscription, filter or txpool, got %q", i, endpoint.PendingSource)
+		}
+		if endpoint.BatchSize < 0 {
+			addErr("endpoints.%d.batch_size: must not be negative", i)
+		}
+		if endpoint.Concurrency < 0 {
+			addErr("endpoints.%d.concurrency: must not be negative", i)
+		}
+		if endpoint.EngineUrl != "" {
+			if err := checkURL(endpoint.EngineUrl, "http", "https", "ws", "wss"); err != nil {
+				addErr("endpoints.%d.engine_url: %s", i, err)
+			} else if endpoint.JWTSecretFile == "" {
+				addErr("endpoints.%d.engine_url: the engine API needs a jwt_secret_file", i)
+			}
+		}
+		for _, err := range checkCredentials(endpoint.JWTSecretFile, endpoint.TLS) {
+			addErr("endpoints.%d.%s", i, err)
+		}
+	}
+
+	beaconNames := make(map[string]bool)
+	for i, beacon := range c.BeaconUrls {
+		if beacon.Name == "" {
+			addErr("beacon_urls.%d.name: required", i)
+		} else if beaconNames[beacon.Name] {
+			addErr("beacon_urls.%d.name: duplicate name %q", i, beacon.Name)
+		}
+		beaconNames[beacon.Name] = true
+
+		if err := checkURL(beacon.BeaconUrl, "http", "https"); err != nil {
+			addErr("beacon_urls.%d.beacon_url: %s", i, err)
+		}
+		for _, err := range checkCredentials(beacon.JWTSecretFile, beacon.TLS) {
+			addErr("beacon_urls.%d.%s", i, err)
+		}
+	}
+
+	if c.Polling.Interval == "" {
+		addErr("polling.interval: required")
+	} else if d, err := time.ParseDuration(c.Polling.Interval); err != nil || d <= 0 {
+		addErr("polling.interval: invalid duration %q", c.Polling.Interval)
+	}
+	if c.Polling.PendingInterval != "" {
+		if d, err := time.ParseDuration(c.Polling.PendingInterval); err != nil || d <= 0 {
+			addErr("polling.pending_interval: invalid duration %q", c.Polling.PendingInterval)
+		}
+	}
+	for _, recheck := range []struct{ field, value string }{
+		{"recheck_min", c.Polling.RecheckMin},
+		{"recheck_max", c.Polling.RecheckMax},
+	} {
+		if recheck.value == "" {
+			continue
+		}
+		if d, err := time.ParseDuration(recheck.value); err != nil || d <= 0 {
+			addErr("polling.%s: invalid duration %q", recheck.field, recheck.value)
+		}
+	}
+	if c.Polling.RecheckMin != "" && c.Polling.RecheckMax != "" {
+		minWait, minErr := time.ParseDuration(c.Polling.RecheckMin)
+		maxWait, maxErr := time.ParseDuration(c.Polling.RecheckMax)
+		if minErr == nil && maxErr == nil && minWait > maxWait {
+			addErr("polling.recheck_max: must not be shorter than recheck_min")
+		}
+	}
+	if c.Polling.BatchSize < 0 {
+		addErr("polling.batch_size: must not be negative")
+	}
+	if c.Polling.Concurrency < 0 {
+		addErr("polling.concurrency: must not be negative")
+	}
+	if c.Polling.Timeout != "" {
+		if _, err := time.ParseDuration(c.Polling.Timeout); err != nil {
+			addErr("polling.timeout: invalid duration %q", c.Polling.Timeout)
+		}
+	}
+
+	switch c.FocilEnabled {
+	case "", "true", "false":
+	default:
+		addErr("focil_enabled: must be \"true\" or \"false\", got %q", c.FocilEnabled)
+	}
+	if c.FocilEnabled == "true" && len(c.BeaconUrls) == 0 {
+		addErr("beacon_urls: required when focil_enabled is true")
+	}
+
+	if c.FocilVerification.Enabled {
+		if c.FocilVerification.StubFile != "" {
+			if _, err := os.Stat(c.FocilVerification.StubFile); err != nil {
+				addErr("focil_verification.stub_file: %s", err)
+			}
+		} else if c.FocilVerification.BeaconUrl == "" && len(c.BeaconUrls) == 0 {
+			addErr("focil_verification: needs a beacon_url, a beacon_urls entry or a stub_file")
+		}
+	}
+
+	if c.FocilBackfill.CaptureDir != "" {
+		if info, err := os.Stat(c.FocilBackfill.CaptureDir); err != nil {
+			addErr("focil_backfill.capture_dir: %s", err)
+		} else if !info.IsDir() {
+			addErr("focil_backfill.capture_dir: %s is not a directory", c.FocilBackfill.CaptureDir)
+		}
+	}
+
+	if c.Capture.ReplayFile != "" {
+		if _, err := os.Stat(c.Capture.ReplayFile); err != nil {
+			addErr("capture.replay_file: %s", err)
+		}
+	}
+	if c.Capture.ReplaySpeed < 0 {
+		addErr("capture.replay_speed: must not be negative")
+	}
+
+	if c.Reload.Interval != "" {
+		if d, err := time.ParseDuration(c.Reload.Interval); err != nil || d <= 0 {
+			addErr("reload.interval: invalid duration %q", c.Reload.Interval)
+		}
+	}
+	if c.Stats.Interval != "" {
+		if d, err := time.ParseDuration(c.Stats.Interval); err != nil || d <= 0 {
+			addErr("stats.interval: invalid duration %q", c.Stats.Interval)
+		}
+	}
+	if c.Censorship.MinBlocks < 0 {
+		addErr("censorship.min_blocks: must not be negative")
+	}
+	if c.Censorship.Interval != "" {
+		if d, err := time.ParseDuration(c.Censorship.Interval); err != nil || d <= 0 {
+			addErr("censorship.interval: invalid duration %q", c.Censorship.Interval)
+		}
+	}
+	if c.Decoding.SignaturesFile != "" {
+		if _, err := os.Stat(c.Decoding.SignaturesFile); err != nil {
+			addErr("decoding.signatures_file: %s", err)
+		}
+	}
+	for i, file := range c.Decoding.ABIFiles {
+		if _, err := os.Stat(file); err != nil {
+			addErr("decoding.abi_files[%d]: %s", i, err)
+		}
+	}
+
+	return errs
+}
+
+// checkCredentials reports unreadable jwt secrets and incomplete tls settings
+func checkCredentials(jwtSecretFile string, t TLS) []error {
+	var errs []error
+	if jwtSecretFile != "" {
+		if _, err := ReadJWTSecret(jwtSecretFile); err != nil {
+			errs = append(errs, fmt.Errorf("jwt_secret_file: %s", err))
+		}
+	}
+
+	if (t.CertFile == "") != (t.KeyFile == "") {
+		errs = append(errs, fmt.Errorf("tls: cert_file and key_file must be set together"))
+	}
+	for _, file := range []struct{ field, path string }{
+		{"cert_file", t.CertFile},
+		{"key_file", t.KeyFile},
+		{"ca_file", t.CAFile},
+	} {
+		if file.path == "" {
+			continue
+		}
+		if _, err := os.Stat(file.path); err != nil {
+			errs = append(errs, fmt.Errorf("tls.%s: %s", file.field, err))
+		}
+	}
+	return errs
+}
+
+func checkURL(raw string, schemes ...string) error {
+	if raw == "" {
+		return fmt.Errorf("required")
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("invalid url %q", raw)
+	}
+	for _, scheme := range schemes {
+		if u.Scheme == scheme {
+			if u.Host == "" {
+				return fmt.Errorf("missing host in %q", raw)
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported scheme in %q", raw)
+}
@@ -0,0 +1,105 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"txpool-viz/internal/capture"
+	"txpool-viz/internal/chain"
+	"txpool-viz/internal/focil"
+	"txpool-viz/internal/logger"
+	"txpool-viz/internal/transactions"
+)
+
+// startRecording captures every websocket message, RPC response and beacon event of the session
+func (c *Controller) startRecording(ctx context.Context) error {
+	rec, err := capture.NewRecorder(c.Config.Capture.RecordFile)
+	if err != nil {
+		return err
+	}
+
+	if err := capture.RecordEndpoints(ctx, c.Config, rec); err != nil {
+		rec.Close()
+		return err
+	}
+
+	c.Services.Recorder = rec
+	c.Services.Logger.Info("Recording session", logger.Fields{"file": rec.Path()})
+	return nil
+}
+
+// loadReplay points the endpoint clients at a capture so no live node is contacted
+func (c *Controller) loadReplay(ctx context.Context) (*capture.Player, error) {
+	player, err := capture.NewPlayer(c.Config.Capture.ReplayFile, c.Config.Capture.ReplaySpeed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load capture: %w", err)
+	}
+
+	if err := capture.ReplayEndpoints(ctx, c.Config, player); err != nil {
+		return nil, fmt.Errorf("failed to set up replay clients: %w", err)
+	}
+
+	return player, nil
+}
+
+// replay feeds the capture through the same processors as the live streams
+func (c *Controller) replay(ctx context.Context, player *capture.Player, focilService *focil.FocilService, wg *sync.WaitGroup) {
+	l := c.Services.Logger
+
+	// The processors and trackers run on the capture's time rather than the wall clock
+	transactions.ProcessTransactions(ctx, c.Config, c.Services, player)
+
+	handlers := capture.Handlers{
+		Stream: func(ctx context.Context, endpoint string, msg []byte, receivedAt time.Time) {
+			transactions.ReplayStreamMessage(ctx, c.Services, endpoint, msg, receivedAt)
+		},
+	}
+
+	trackers := make(map[string]*chain.Tracker, len(c.Config.Endpoints))
+	for _, e := range c.Config.Endpoints {
+		trackers[e.Name] = chain.NewTracker(e, c.Services, player)
+	}
+	handlers.Head = func(ctx context.Context, endpoint string, header []byte) {
+		if tracker, ok := trackers[endpoint]; ok {
+			var head chain.Head
+			if err := json.Unmarshal(header, &head); err != nil {
+				l.Error("Failed to decode captured header", logger.Fields{"endpoint": endpoint, "error": err.Error()})
+				return
+			}
+			tracker.Observe(ctx, &head)
+		}
+		if focilService == nil {
+			return
+		}
+		for _, e := range c.Config.Endpoints {
+			if e.Name == endpoint {
+				focilService.ReplayHead(ctx, e.Client, header)
+				return
+			}
+		}
+	}
+
+	if focilService != nil {
+		handlers.Event = focilService.ReplayEvent
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		l.Info("Replaying capture", logger.Fields{
+			"file":     c.Config.Capture.ReplayFile,
+			"messages": player.Len(),
+			"speed":    c.Config.Capture.ReplaySpeed,
+		})
+
+		if err := player.Run(ctx, handlers); err != nil {
+			l.Warn("Replay stopped", logger.Fields{"error": err.Error()})
+			return
+		}
+		l.Info("Replay finished")
+	}()
+}
@@ -11,13 +11,17 @@ import (
 	"syscall"
 	"time"
 
+	"txpool-viz/internal/calldata"
+	"txpool-viz/internal/capture"
+	"txpool-viz/internal/clock"
 	"txpool-viz/internal/config"
 	"txpool-viz/internal/controller/handler"
 	route "txpool-viz/internal/controller/routes"
 	"txpool-viz/internal/focil"
 	"txpool-viz/internal/logger"
 	"txpool-viz/internal/service"
-	"txpool-viz/internal/transactions"
+	"txpool-viz/internal/stats"
+	"txpool-viz/internal/supervisor"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -43,7 +47,6 @@ func NewController(cfg *config.Config, srvc *service.Service) *Controller {
 
 func (c *Controller) Serve() error {
 	ctx, cancel := context.WithCancel(context.Background())
-	l := c.Services.Logger
 	defer cancel()
 
 	var wg sync.WaitGroup
@@ -55,34 +58,87 @@ func (c *Controller) Serve() error {
 	if err := c.initialize(); err != nil {
 		return fmt.Errorf("failed to initialize: %w", err)
 	}
+	l := c.Services.Logger
+
+	var player *capture.Player
+	switch {
+	case c.Config.Capture.ReplayFile != "":
+		p, err := c.loadReplay(ctx)
+		if err != nil {
+			return err
+		}
+		player = p
+	case c.Config.Capture.RecordFile != "":
+		if err := c.startRecording(ctx); err != nil {
+			return fmt.Errorf("failed to start recording: %w", err)
+		}
+		defer c.Services.Recorder.Close()
+	}
 
-	c.configureRouter(ctx, c.Services.Redis, l)
+	var focilService *focil.FocilService
+	if c.Config.FocilEnabled == "true" {
+		focilService = focil.NewFocilService(l, c.Services.Redis, c.newInclusionListVerifier(), c.Services.Recorder)
+	}
 
-	// Start HTTP server
+	if focilService != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			focilService.RunVerifications(ctx)
+		}()
+	}
+
+	// Live endpoints can be added, removed or changed while running, replayed ones are fixed
+	var sup *supervisor.Supervisor
+	if player == nil {
+		sup = supervisor.New(ctx, c.Config, c.Services, focilService)
+	}
+
+	// Replayed txs are aged on the capture's time
+	var clk clock.Clock = clock.Wall
+	if player != nil {
+		clk = player
+	}
+	txService := c.configureRouter(ctx, c.Services.Redis, l, clk, focilService, sup)
+
+	// Sample the pools for the time series, following the endpoints as they are reloaded
+	sampler := stats.NewSampler(c.Services.Redis, l, c.Config)
+	if sup != nil {
+		sup.OnReload(func(cfg *config.Config) {
+			sampler.SetEndpoints(cfg.Endpoints)
+		})
+	}
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		l.Info("Serving txpool-viz at http://localhost:" + os.Getenv("PORT"))
-		if err := c.httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
-			l.Error("server failed to start", logger.Fields{"error": err.Error()})
-		}
+		sampler.Run(ctx)
 	}()
 
-	// Start transaction streams and respective processors
+	// Alert on txs every client holds that block after block leaves out
+	minBlocks, interval := c.censorshipSettings()
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		transactions.Stream(ctx, c.Config, c.Services, &wg)
+		txService.WatchCensorship(ctx, minBlocks, interval)
 	}()
 
-	if c.Config.FocilEnabled == "true" {
-		// Start inclusion list SSE listener if url is configured
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			inclusionListsService := focil.NewFocilService(l, c.Services.Redis)
-			inclusionListsService.Stream(ctx, c.Config.Endpoints, c.Config.BeaconUrls)
-		}()
+	// Start HTTP server
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		l.Info("Serving txpool-viz at http://localhost:" + os.Getenv("PORT"))
+		if err := c.httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			l.Error("server failed to start", logger.Fields{"error": err.Error()})
+		}
+	}()
+
+	if player != nil {
+		// Replay the capture through the processors instead of streaming from live nodes
+		c.replay(ctx, player, focilService, &wg)
+	} else {
+		// Start transaction streams, inclusion list streams and their processors
+		sup.Start()
+		c.watchConfig(sup)
 	}
 
 	// Wait for shutdown signal
@@ -95,6 +151,9 @@ func (c *Controller) Serve() error {
 
 	l.Info("Waiting for background routines to finish...")
 	wg.Wait()
+	if sup != nil {
+		sup.Wait()
+	}
 
 	l.Info("All services shut down cleanly")
 	return nil
@@ -108,32 +167,127 @@ func (c *Controller) handleShutdown(cancel context.CancelFunc) {
 	cancel() // Cancel the context to unblock Serve()
 }
 
+// initialize loads the config and services unless the caller already provided them
 func (c *Controller) initialize() error {
-	cfg, err := config.Load()
-	if err != nil {
-		return fmt.Errorf("failed to load config: %w", err)
+	if c.Config == nil {
+		cfg, err := config.Load()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+		c.Config = cfg
 	}
-	c.Config = cfg
 
-	srvc, err := service.NewService(c.Config)
-	if err != nil {
-		return fmt.Errorf("failed to set up services: %w", err)
+	if c.Services == nil {
+		srvc, err := service.NewService(c.Config)
+		if err != nil {
+			return fmt.Errorf("failed to set up services: %w", err)
+		}
+		c.Services = srvc
 	}
-	c.Services = srvc
 	return nil
 }
 
-func (c *Controller) configureRouter(ctx context.Context, r *redis.Client, l logger.Logger) {
+// newInclusionListVerifier builds the optional inclusion list verifier, returning nil when disabled or misconfigured
+func (c *Controller) newInclusionListVerifier() *focil.Verifier {
+	if !c.Config.FocilVerification.Enabled {
+		return nil
+	}
+
+	source, err := focil.NewCommitteeSource(c.Config.FocilVerification, c.Config.BeaconUrls)
+	if err != nil {
+		c.Services.Logger.Error("Inclusion list verification disabled", logger.Fields{"error": err.Error()})
+		return nil
+	}
+
+	return focil.NewVerifier(source)
+}
+
+// watchConfig applies config file changes to the endpoints when reload.watch is set
+func (c *Controller) watchConfig(sup *supervisor.Supervisor) {
+	if !c.Config.Reload.Watch {
+		return
+	}
+
+	interval := supervisor.DefaultWatchInterval
+	if c.Config.Reload.Interval != "" {
+		d, err := time.ParseDuration(c.Config.Reload.Interval)
+		if err != nil || d <= 0 {
+			c.Services.Logger.Warn("Invalid reload interval, using the default", logger.Fields{"interval": c.Config.Reload.Interval})
+		} else {
+			interval = d
+		}
+	}
+
+	sup.Watch(interval)
+}
+
+// censorshipSettings returns the configured censorship.min_blocks and censorship.interval or their defaults
+func (c *Controller) censorshipSettings() (int, time.Duration) {
+	minBlocks := config.DefaultCensorshipMinBlocks
+	if c.Config.Censorship.MinBlocks > 0 {
+		minBlocks = c.Config.Censorship.MinBlocks
+	}
+
+	interval := config.DefaultCensorshipInterval
+	if c.Config.Censorship.Interval != "" {
+		d, err := time.ParseDuration(c.Config.Censorship.Interval)
+		if err != nil || d <= 0 {
+			c.Services.Logger.Warn("Invalid censorship interval, using the default", logger.Fields{"interval": c.Config.Censorship.Interval})
+		} else {
+			interval = d
+		}
+	}
+	return minBlocks, interval
+}
+
+// newCallRegistry loads the signatures calldata is decoded against, warning about each entry skipped
+func newCallRegistry(cfg config.Decoding, l logger.Logger) *calldata.Registry {
+	calls, errs := calldata.NewRegistry(cfg)
+	for _, err := range errs {
+		l.Warn("Calldata signature skipped", logger.Fields{"error": err.Error()})
+	}
+	return calls
+}
+
+// configureRouter sets up the API and frontend server, returning the tx service behind the API
+func (c *Controller) configureRouter(ctx context.Context, r *redis.Client, l logger.Logger, clk clock.Clock, focilService *focil.FocilService, sup *supervisor.Supervisor) *service.TransactionServiceImpl {
 	//Initialize handler with needed services
-	txService := service.NewTransactionService(ctx, r, l, c.Config.Endpoints)
-	ilService := service.NewInclusionListService(r, l, c.Config.FocilEnabled == "true")
-	handler := handler.NewHandler(txService, ilService)
+	txService := service.NewTransactionService(ctx, r, l, clk, c.Config.Endpoints)
+	txService.SetCallRegistry(newCallRegistry(c.Config.Decoding, l))
+	ilService := service.NewInclusionListService(r, l, c.Config.FocilEnabled == "true", c.Config.Endpoints)
+
+	// Backfill needs a beacon node for canonical blocks and an execution client for their txs
+	var backfiller *focil.Backfiller
+	if focilService != nil && len(c.Config.BeaconUrls) > 0 && len(c.Config.Endpoints) > 0 {
+		b, err := focil.NewBackfiller(ctx, focilService, c.Config.BeaconUrls[0], c.Config.Endpoints[0].Client, c.Config.FocilBackfill)
+		if err != nil {
+			l.Error("FOCIL backfill disabled", logger.Fields{"error": err.Error()})
+		} else {
+			backfiller = b
+		}
+	}
+
+	if sup != nil {
+		// Keep the services in step with the endpoints running
+		sup.OnReload(func(cfg *config.Config) {
+			txService.SetEndpoints(cfg.Endpoints)
+			txService.SetCallRegistry(newCallRegistry(cfg.Decoding, l))
+			ilService.SetEndpoints(cfg.Endpoints)
+			if backfiller != nil && len(cfg.BeaconUrls) > 0 && len(cfg.Endpoints) > 0 {
+				if err := backfiller.SetSources(cfg.BeaconUrls[0], cfg.Endpoints[0].Client); err != nil {
+					l.Error("FOCIL backfill kept its previous beacon node", logger.Fields{"error": err.Error()})
+				}
+			}
+		})
+	}
+
+	handler := handler.NewHandler(txService, ilService, backfiller, c.Config, sup)
 
 	c.router.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"*"},
-		AllowMethods:     []string{"GET", "POST", "OPTIONS"}, // Restrict to required methods
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}, // Restrict to required methods
+		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization"},
+		ExposeHeaders:    []string{"Content-Length", "X-Total-Count"},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}))
@@ -159,4 +313,5 @@ func (c *Controller) configureRouter(ctx context.Context, r *redis.Client, l log
 		WriteTimeout: 30 * time.Second,
 		IdleTimeout:  120 * time.Second,
 	}
+	return txService
 }
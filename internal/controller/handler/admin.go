@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+
+	"txpool-viz/internal/config"
+	"txpool-viz/internal/model"
+	"txpool-viz/internal/supervisor"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireAdminToken rejects admin requests without the configured bearer token. Without a token
+// configured the admin API is open, like the rest of the API.
+func (h *Handler) RequireAdminToken(c *gin.Context) {
+	token := h.currentConfig().Reload.AdminToken
+	if token == "" {
+		c.Next()
+		return
+	}
+
+	given, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid admin token"})
+		return
+	}
+	c.Next()
+}
+
+// GetEndpointStatus lists the running endpoint and beacon node workers
+func (h *Handler) GetEndpointStatus(c *gin.Context) {
+	if !h.requireSupervisor(c) {
+		return
+	}
+	c.JSON(http.StatusOK, h.Supervisor.Status())
+}
+
+// PutEndpoint adds or replaces the endpoint named in the path
+func (h *Handler) PutEndpoint(c *gin.Context) {
+	if !h.requireSupervisor(c) {
+		return
+	}
+
+	var endpoint config.Endpoint
+	if err := c.ShouldBindJSON(&endpoint); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	endpoint.Name = c.Param("name")
+
+	h.respondReload(c, func() (model.ReloadResult, error) { return h.Supervisor.PutEndpoint(endpoint) })
+}
+
+// DeleteEndpoint stops and removes the endpoint named in the path
+func (h *Handler) DeleteEndpoint(c *gin.Context) {
+	if !h.requireSupervisor(c) {
+		return
+	}
+	h.respondReload(c, func() (model.ReloadResult, error) { return h.Supervisor.RemoveEndpoint(c.Param("name")) })
+}
+
+// PutBeacon adds or replaces the beacon node named in the path
+func (h *Handler) PutBeacon(c *gin.Context) {
+	if !h.requireSupervisor(c) {
+		return
+	}
+
+	var beacon config.BeaconEndpoint
+	if err := c.ShouldBindJSON(&beacon); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	beacon.Name = c.Param("name")
+
+	h.respondReload(c, func() (model.ReloadResult, error) { return h.Supervisor.PutBeacon(beacon) })
+}
+
+// DeleteBeacon stops and removes the beacon node named in the path
+func (h *Handler) DeleteBeacon(c *gin.Context) {
+	if !h.requireSupervisor(c) {
+		return
+	}
+	h.respondReload(c, func() (model.ReloadResult, error) { return h.Supervisor.RemoveBeacon(c.Param("name")) })
+}
+
+// ReloadConfig applies the config file again
+func (h *Handler) ReloadConfig(c *gin.Context) {
+	if !h.requireSupervisor(c) {
+		return
+	}
+	h.respondReload(c, h.Supervisor.ReloadFile)
+}
+
+func (h *Handler) requireSupervisor(c *gin.Context) bool {
+	if h.Supervisor == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "endpoints can't be changed while replaying a capture"})
+		return false
+	}
+	return true
+}
+
+func (h *Handler) respondReload(c *gin.Context, reload func() (model.ReloadResult, error)) {
+	result, err := reload()
+	switch {
+	case errors.Is(err, supervisor.ErrEndpointNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, supervisor.ErrInvalidConfig):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusOK, result)
+	}
+}
@@ -1,24 +1,47 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
+	"txpool-viz/internal/config"
+	"txpool-viz/internal/focil"
+	"txpool-viz/internal/model"
 	"txpool-viz/internal/service"
+	"txpool-viz/internal/supervisor"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/gin-gonic/gin"
 )
 
 type Handler struct {
 	TxService            *service.TransactionServiceImpl
 	InclusionListService *service.InclusionListService
+	Backfiller           *focil.Backfiller // nil when FOCIL is disabled
+	Config               *config.Config
+	Supervisor           *supervisor.Supervisor // nil while replaying a capture
 }
 
-const DefaultTxCount = 1000
+const (
+	DefaultTxCount               = 1000
+	DefaultInclusionListPageSize = 100
+	DefaultReorgCount            = 100
+	DefaultResolution            = "1m"
+	DefaultFeeBlockCount         = 20
+	DefaultMissedBlockCount      = 10
+	DefaultPrivateBlockCount     = 50
+	DefaultCensorshipBlockCount  = 64
+	DefaultCensorshipAlertCount  = 100
+	DefaultBlobBlockCount        = 20
+)
 
-func NewHandler(txService *service.TransactionServiceImpl, ilService *service.InclusionListService) *Handler {
+func NewHandler(txService *service.TransactionServiceImpl, ilService *service.InclusionListService, backfiller *focil.Backfiller, cfg *config.Config, sup *supervisor.Supervisor) *Handler {
 	return &Handler{
 		TxService:            txService,
 		InclusionListService: ilService,
+		Backfiller:           backfiller,
+		Config:               cfg,
+		Supervisor:           sup,
 	}
 }
 
@@ -58,18 +81,404 @@ func (h *Handler) GetTransactionDetails(c *gin.Context) {
 }
 
 func (h *Handler) GetInclusionLists(c *gin.Context) {
+	query := model.InclusionListQuery{}
+
+	var err error
+	if query.Page, err = strconv.Atoi(c.DefaultQuery("page", "1")); err != nil || query.Page <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid page parameter"})
+		return
+	}
+	if query.PageSize, err = strconv.Atoi(c.DefaultQuery("page_size", strconv.Itoa(DefaultInclusionListPageSize))); err != nil || query.PageSize <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid page_size parameter"})
+		return
+	}
+	if fromSlot, ok := c.GetQuery("from_slot"); ok {
+		slot, err := strconv.Atoi(fromSlot)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from_slot parameter"})
+			return
+		}
+		query.FromSlot = &slot
+	}
+	if toSlot, ok := c.GetQuery("to_slot"); ok {
+		slot, err := strconv.Atoi(toSlot)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to_slot parameter"})
+			return
+		}
+		query.ToSlot = &slot
+	}
+
 	ctx := c.Request.Context()
 
-	inclusionReports, err := h.InclusionListService.GetInclusionLists(ctx)
+	inclusionReports, total, err := h.InclusionListService.GetInclusionLists(ctx, query)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	c.Header("X-Total-Count", strconv.Itoa(total))
 	c.JSON(http.StatusOK, inclusionReports)
 }
 
+func (h *Handler) GetInclusionListDetail(c *gin.Context) {
+	slot, err := strconv.Atoi(c.Param("slot"))
+	if err != nil || slot < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid slot parameter"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	detail, err := h.InclusionListService.GetInclusionListDetail(ctx, slot)
+	if errors.Is(err, service.ErrInclusionListNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, detail)
+}
+
+func (h *Handler) GetInclusionListMempoolView(c *gin.Context) {
+	slot, err := strconv.Atoi(c.Param("slot"))
+	if err != nil || slot < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid slot parameter"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	view, err := h.InclusionListService.GetInclusionListMempoolView(ctx, slot)
+	if errors.Is(err, service.ErrInclusionListNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, view)
+}
+
+func (h *Handler) StartFocilBackfill(c *gin.Context) {
+	if h.Backfiller == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "FOCIL backfill is not available"})
+		return
+	}
+
+	var req model.BackfillRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := h.Backfiller.Start(req)
+	if errors.Is(err, focil.ErrBackfillRunning) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, h.Backfiller.Status())
+}
+
+func (h *Handler) GetFocilBackfillStatus(c *gin.Context) {
+	if h.Backfiller == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "FOCIL backfill is not available"})
+		return
+	}
+
+	c.JSON(http.StatusOK, h.Backfiller.Status())
+}
+
 func (h *Handler) GetFocilFeatureFlag(c *gin.Context) {
     enabled := h.InclusionListService.IsFocilEnabled()
     c.JSON(http.StatusOK, gin.H{"status": enabled})
-}
\ No newline at end of file
+}
+
+// GetReorgs lists the newest reorgs seen by the endpoints
+func (h *Handler) GetReorgs(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(DefaultReorgCount)))
+	if err != nil || limit <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit parameter"})
+		return
+	}
+
+	events, err := h.TxService.GetReorgs(c.Request.Context(), c.Query("endpoint"), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, events)
+}
+
+// GetClientPolicies infers each client's tx acceptance policies from the txs it held
+func (h *Handler) GetClientPolicies(c *gin.Context) {
+	policies, err := h.TxService.GetClientPolicies(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, policies)
+}
+
+// GetTimeSeries returns the pool samples of a resolution, for charting pool growth
+func (h *Handler) GetTimeSeries(c *gin.Context) {
+	var bounds [2]int64
+	for i, param := range []string{"from", "to"} {
+		value, ok := c.GetQuery(param)
+		if !ok {
+			continue
+		}
+		ts, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || ts < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + param + " parameter"})
+			return
+		}
+		bounds[i] = ts
+	}
+
+	series, err := h.TxService.GetTimeSeries(c.Request.Context(), c.DefaultQuery("resolution", DefaultResolution), bounds[0], bounds[1], c.Query("client"))
+	if errors.Is(err, service.ErrUnknownResolution) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, series)
+}
+
+// GetBlockFeeReports returns the fee reports of the latest blocks
+func (h *Handler) GetBlockFeeReports(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(DefaultFeeBlockCount)))
+	if err != nil || limit <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit parameter"})
+		return
+	}
+
+	reports, err := h.TxService.GetBlockFeeReports(c.Request.Context(), c.Query("client"), limit)
+	if errors.Is(err, service.ErrUnknownClient) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, reports)
+}
+
+// GetBlockFeeReport returns the fee report of one block
+func (h *Handler) GetBlockFeeReport(c *gin.Context) {
+	number, err := strconv.ParseUint(c.Param("number"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid block number"})
+		return
+	}
+
+	report, err := h.TxService.GetBlockFeeReport(c.Request.Context(), c.Query("client"), number)
+	if errors.Is(err, service.ErrUnknownClient) || errors.Is(err, service.ErrBlockNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// GetPoolFeeReport returns the fee market view of a client's pool
+func (h *Handler) GetPoolFeeReport(c *gin.Context) {
+	blocks, err := strconv.Atoi(c.DefaultQuery("blocks", strconv.Itoa(DefaultMissedBlockCount)))
+	if err != nil || blocks < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid blocks parameter"})
+		return
+	}
+
+	report, err := h.TxService.GetPoolFeeReport(c.Request.Context(), c.Query("client"), blocks)
+	if errors.Is(err, service.ErrUnknownClient) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// GetPrivateFlow returns how many of the latest blocks' txs never went through a public pool
+func (h *Handler) GetPrivateFlow(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(DefaultPrivateBlockCount)))
+	if err != nil || limit <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit parameter"})
+		return
+	}
+
+	reports, err := h.TxService.GetPrivateFlow(c.Request.Context(), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, reports)
+}
+
+// GetBuilderPrivateFlow returns the private flow of the analysed blocks by coinbase
+func (h *Handler) GetBuilderPrivateFlow(c *gin.Context) {
+	builders, err := h.TxService.GetBuilderPrivateFlow(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, builders)
+}
+
+// GetStuckTxs returns the txs every client holds that the latest blocks kept leaving out
+func (h *Handler) GetStuckTxs(c *gin.Context) {
+	minBlocks := config.DefaultCensorshipMinBlocks
+	if configured := h.currentConfig().Censorship.MinBlocks; configured > 0 {
+		minBlocks = configured
+	}
+	minBlocks, err := strconv.Atoi(c.DefaultQuery("min_blocks", strconv.Itoa(minBlocks)))
+	if err != nil || minBlocks <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid min_blocks parameter"})
+		return
+	}
+	blocks, err := strconv.Atoi(c.DefaultQuery("blocks", strconv.Itoa(DefaultCensorshipBlockCount)))
+	if err != nil || blocks <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid blocks parameter"})
+		return
+	}
+
+	report, err := h.TxService.GetStuckTxs(c.Request.Context(), minBlocks, blocks)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// GetCensorshipAlerts returns the latest stuck tx alerts
+func (h *Handler) GetCensorshipAlerts(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(DefaultCensorshipAlertCount)))
+	if err != nil || limit <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit parameter"})
+		return
+	}
+
+	alerts, err := h.TxService.GetCensorshipAlerts(c.Request.Context(), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, alerts)
+}
+
+// GetAddressView returns an account's txs across clients with its nonce timeline, gaps and balance
+func (h *Handler) GetAddressView(c *gin.Context) {
+	address := c.Param("addr")
+	if !common.IsHexAddress(address) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid address"})
+		return
+	}
+
+	view, err := h.TxService.GetAddressView(c.Request.Context(), address)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, view)
+}
+
+// GetBlobPool returns each client's blob txs, sidecars and blob fee caps
+func (h *Handler) GetBlobPool(c *gin.Context) {
+	view, err := h.TxService.GetBlobPool(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, view)
+}
+
+// GetBlockBlobs returns the blob usage of the latest blocks
+func (h *Handler) GetBlockBlobs(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(DefaultBlobBlockCount)))
+	if err != nil || limit <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit parameter"})
+		return
+	}
+
+	blocks, err := h.TxService.GetBlockBlobs(c.Request.Context(), c.Query("client"), limit)
+	if errors.Is(err, service.ErrUnknownClient) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, blocks)
+}
+
+// GetResolvedConfig returns the running config with secrets redacted and the sources of its values
+func (h *Handler) GetResolvedConfig(c *gin.Context) {
+	c.JSON(http.StatusOK, h.currentConfig().Resolved())
+}
+
+// currentConfig returns the config in effect, which changes as endpoints are reloaded
+func (h *Handler) currentConfig() *config.Config {
+	if h.Supervisor != nil {
+		return h.Supervisor.Config()
+	}
+	return h.Config
+}
+
+// GetSetCodeReport returns each client's set code txs and the txs they treat differently
+func (h *Handler) GetSetCodeReport(c *gin.Context) {
+	report, err := h.TxService.GetSetCodeReport(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// GetDelegations returns the set code txs authorizing a delegate contract
+func (h *Handler) GetDelegations(c *gin.Context) {
+	delegate := c.Param("addr")
+	if !common.IsHexAddress(delegate) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid address"})
+		return
+	}
+
+	view, err := h.TxService.GetDelegations(c.Request.Context(), delegate)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, view)
+}
@@ -17,6 +17,34 @@ func RegisterRoutes(router *gin.Engine, handler *handler.Handler) {
 
 	api.GET("/transactions", handler.GetLatestTxSummaries)
 	api.GET("/transaction/:txHash", handler.GetTransactionDetails)
+	api.GET("/address/:addr", handler.GetAddressView)
+	api.GET("/reorgs", handler.GetReorgs)
+	api.GET("/policies", handler.GetClientPolicies)
+	api.GET("/stats/timeseries", handler.GetTimeSeries)
+	api.GET("/fees/blocks", handler.GetBlockFeeReports)
+	api.GET("/fees/blocks/:number", handler.GetBlockFeeReport)
+	api.GET("/fees/pool", handler.GetPoolFeeReport)
+	api.GET("/blobs/pool", handler.GetBlobPool)
+	api.GET("/blobs/blocks", handler.GetBlockBlobs)
+	api.GET("/setcode", handler.GetSetCodeReport)
+	api.GET("/setcode/delegates/:addr", handler.GetDelegations)
+	api.GET("/private/blocks", handler.GetPrivateFlow)
+	api.GET("/private/builders", handler.GetBuilderPrivateFlow)
+	api.GET("/censorship", handler.GetStuckTxs)
+	api.GET("/censorship/alerts", handler.GetCensorshipAlerts)
 	api.GET("/inclusion-lists", handler.GetInclusionLists)
+	api.GET("/inclusion-lists/:slot", handler.GetInclusionListDetail)
+	api.GET("/inclusion-lists/:slot/mempool", handler.GetInclusionListMempoolView)
 	api.GET("/feature/focil", handler.GetFocilFeatureFlag)
+
+	admin := api.Group("/admin", handler.RequireAdminToken)
+	admin.GET("/endpoints", handler.GetEndpointStatus)
+	admin.PUT("/endpoints/:name", handler.PutEndpoint)
+	admin.DELETE("/endpoints/:name", handler.DeleteEndpoint)
+	admin.PUT("/beacons/:name", handler.PutBeacon)
+	admin.DELETE("/beacons/:name", handler.DeleteBeacon)
+	admin.POST("/reload", handler.ReloadConfig)
+	admin.GET("/config", handler.GetResolvedConfig)
+	admin.POST("/focil/backfill", handler.StartFocilBackfill)
+	admin.GET("/focil/backfill", handler.GetFocilBackfillStatus)
 }
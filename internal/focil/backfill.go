@@ -0,0 +1,354 @@
+package focil
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"txpool-viz/internal/capture"
+	"txpool-viz/internal/config"
+	"txpool-viz/internal/logger"
+	"txpool-viz/internal/model"
+
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+const (
+	// Like the committee path, inclusion list retrieval is not standardised yet
+	defaultInclusionListPath = "/eth/v1/beacon/inclusion_lists/%d"
+
+	backfillNode = "backfill"
+)
+
+// ErrBackfillRunning is returned when a backfill is requested while another is in progress
+var ErrBackfillRunning = errors.New("a backfill is already running")
+
+// ErrCaptureFilesDisabled is returned when a backfill names a capture but focil_backfill.capture_dir is unset
+var ErrCaptureFilesDisabled = errors.New("capture files are disabled, set focil_backfill.capture_dir")
+
+// Backfiller rebuilds inclusion reports for slots that passed while txpool-viz was not running
+type Backfiller struct {
+	ctx               context.Context
+	focil             *FocilService
+	beacon            *beaconClient
+	beaconName        string
+	el                *ethclient.Client
+	inclusionListPath string
+	captureDir        string
+
+	mu     sync.Mutex
+	status model.BackfillStatus
+}
+
+// NewBackfiller constructs a Backfiller. Jobs run under ctx so they stop on shutdown.
+func NewBackfiller(ctx context.Context, fs *FocilService, beacon config.BeaconEndpoint, el *ethclient.Client, cfg config.FocilBackfill) (*Backfiller, error) {
+	inclusionListPath := cfg.InclusionListPath
+	if inclusionListPath == "" {
+		inclusionListPath = defaultInclusionListPath
+	}
+
+	api, err := newBeaconClient(beacon)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Backfiller{
+		ctx:               ctx,
+		focil:             fs,
+		beacon:            api,
+		beaconName:        beacon.Name,
+		el:                el,
+		inclusionListPath: inclusionListPath,
+		captureDir:        cfg.CaptureDir,
+	}, nil
+}
+
+// SetSources replaces the beacon node and execution client used by later backfilled slots, e.g. after a config reload
+func (b *Backfiller) SetSources(beacon config.BeaconEndpoint, el *ethclient.Client) error {
+	api, err := newBeaconClient(beacon)
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.beacon = api
+	b.beaconName = beacon.Name
+	b.el = el
+	return nil
+}
+
+// sources returns the beacon node and execution client currently used
+func (b *Backfiller) sources() (*beaconClient, string, *ethclient.Client) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.beacon, b.beaconName, b.el
+}
+
+// Start launches a backfill job in the background.
+func (b *Backfiller) Start(req model.BackfillRequest) error {
+	if req.ToSlot < req.FromSlot {
+		return fmt.Errorf("to_slot must not be before from_slot")
+	}
+
+	capturePath, err := b.capturePath(req.CaptureFile)
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.status.Running {
+		return ErrBackfillRunning
+	}
+
+	b.status = model.BackfillStatus{
+		Running:   true,
+		Request:   req,
+		StartedAt: time.Now().Unix(),
+	}
+
+	go b.run(req, capturePath)
+
+	return nil
+}
+
+// capturePath resolves the capture a request names inside the capture directory. Only plain file
+// names are accepted so requests cannot read other files of the host.
+func (b *Backfiller) capturePath(name string) (string, error) {
+	if name == "" {
+		return "", nil
+	}
+	if b.captureDir == "" {
+		return "", ErrCaptureFilesDisabled
+	}
+	if name != filepath.Base(name) || name == "." || name == ".." {
+		return "", fmt.Errorf("capture_file must be a file name inside the capture directory")
+	}
+	return filepath.Join(b.captureDir, name), nil
+}
+
+// Status returns the progress of the current or last backfill job.
+func (b *Backfiller) Status() model.BackfillStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	status := b.status
+	status.Errors = append([]string(nil), b.status.Errors...)
+	return status
+}
+
+func (b *Backfiller) run(req model.BackfillRequest, capturePath string) {
+	l := b.focil.logger
+	l.Info("Starting FOCIL backfill", logger.Fields{"from_slot": req.FromSlot, "to_slot": req.ToSlot})
+
+	if capturePath != "" {
+		if err := b.importCaptureFile(req, capturePath); err != nil {
+			b.recordError(fmt.Sprintf("capture file: %s", err))
+		}
+	}
+
+	for slot := req.FromSlot; slot <= req.ToSlot; slot++ {
+		if b.ctx.Err() != nil {
+			b.recordError("backfill cancelled")
+			break
+		}
+
+		b.backfillSlot(slot, capturePath == "")
+	}
+
+	b.mu.Lock()
+	b.status.Running = false
+	b.status.FinishedAt = time.Now().Unix()
+	status := b.status
+	b.mu.Unlock()
+
+	l.Info("FOCIL backfill finished", logger.Fields{
+		"slots":   status.SlotsScanned,
+		"missed":  status.MissedSlots,
+		"lists":   status.ListsImported,
+		"reports": status.ReportsBuilt,
+		"errors":  len(status.Errors),
+	})
+}
+
+// backfillSlot builds the report for the canonical block of a slot from the lists live processing
+// would have used for the block, see inclusionListSlot
+func (b *Backfiller) backfillSlot(slot uint64, fetchLists bool) {
+	ctx := b.ctx
+	defer b.update(func(s *model.BackfillStatus) { s.SlotsScanned++ })
+
+	blockNumber, err := b.executionBlockNumber(ctx, slot)
+	if errors.Is(err, errBeaconNotFound) {
+		b.update(func(s *model.BackfillStatus) { s.MissedSlots++ })
+		return
+	}
+	if err != nil {
+		b.recordError(fmt.Sprintf("slot %d: %s", slot, err))
+		return
+	}
+
+	if blockNumber == 0 {
+		return
+	}
+	ilSlot := inclusionListSlot(blockNumber)
+
+	if fetchLists {
+		imported, err := b.importBeaconInclusionLists(ctx, ilSlot)
+		if err != nil {
+			b.recordError(fmt.Sprintf("inclusion lists for slot %d: %s", ilSlot, err))
+		}
+		b.update(func(s *model.BackfillStatus) { s.ListsImported += imported })
+	}
+
+	_, _, el := b.sources()
+	block, err := el.BlockByNumber(ctx, new(big.Int).SetUint64(blockNumber))
+	if err != nil {
+		b.recordError(fmt.Sprintf("block %d: %s", blockNumber, err))
+		return
+	}
+
+	report, err := b.focil.buildInclusionReport(ctx, block, strconv.FormatUint(ilSlot, 10))
+	if err != nil {
+		// No list was stored for the slot, nothing to report on
+		return
+	}
+
+	if err := b.focil.storeInclusionReport(ctx, strconv.FormatUint(blockNumber, 10), report); err != nil {
+		b.recordError(fmt.Sprintf("report for block %d: %s", blockNumber, err))
+		return
+	}
+
+	b.update(func(s *model.BackfillStatus) { s.ReportsBuilt++ })
+}
+
+// executionBlockNumber resolves the execution block included by the canonical beacon block of a slot
+func (b *Backfiller) executionBlockNumber(ctx context.Context, slot uint64) (uint64, error) {
+	var resp struct {
+		Data struct {
+			Message struct {
+				Body struct {
+					ExecutionPayload struct {
+						BlockNumber string `json:"block_number"`
+					} `json:"execution_payload"`
+				} `json:"body"`
+			} `json:"message"`
+		} `json:"data"`
+	}
+	beacon, _, _ := b.sources()
+	if err := beacon.get(ctx, fmt.Sprintf("/eth/v2/beacon/blocks/%d", slot), &resp); err != nil {
+		return 0, err
+	}
+
+	return strconv.ParseUint(resp.Data.Message.Body.ExecutionPayload.BlockNumber, 10, 64)
+}
+
+// importBeaconInclusionLists fetches and stores the inclusion lists the beacon node kept for a slot
+func (b *Backfiller) importBeaconInclusionLists(ctx context.Context, slot uint64) (int, error) {
+	var resp struct {
+		Data []model.Data `json:"data"`
+	}
+	beacon, beaconName, _ := b.sources()
+	if err := beacon.get(ctx, fmt.Sprintf(b.inclusionListPath, slot), &resp); err != nil {
+		return 0, err
+	}
+
+	imported := 0
+	for _, list := range resp.Data {
+		if err := b.focil.ingestInclusionList(ctx, beaconName, list, time.Time{}); err != nil {
+			return imported, err
+		}
+		imported++
+	}
+
+	return imported, nil
+}
+
+// importCaptureFile stores inclusion lists from a recorded capture, or a file of newline delimited
+// inclusion_list event payloads
+func (b *Backfiller) importCaptureFile(req model.BackfillRequest, path string) error {
+	payloads, err := readInclusionListPayloads(path)
+	if err != nil {
+		return err
+	}
+
+	for _, payload := range payloads {
+		msg, err := parseInclusionListMessage(payload)
+		if err != nil {
+			b.recordError(err.Error())
+			continue
+		}
+
+		slot, err := strconv.ParseUint(msg.Data.Message.Slot, 10, 64)
+		// Blocks are matched to lists by block number, which trails the slot after missed
+		// slots, so lists from before the range can be needed too
+		if err != nil || slot > req.ToSlot {
+			continue
+		}
+
+		if err := b.focil.ingestInclusionList(b.ctx, backfillNode, msg.Data, time.Time{}); err != nil {
+			b.recordError(fmt.Sprintf("slot %d: %s", slot, err))
+			continue
+		}
+		b.update(func(s *model.BackfillStatus) { s.ListsImported++ })
+	}
+
+	return nil
+}
+
+// readInclusionListPayloads returns the inclusion_list event payloads of a capture or plain payload file
+func readInclusionListPayloads(path string) ([][]byte, error) {
+	if records, err := capture.ReadFile(path); err == nil {
+		var payloads [][]byte
+		for _, rec := range records {
+			if rec.Source == capture.SourceEvent && rec.Event == inclusionListTopic {
+				payloads = append(payloads, rec.Data)
+			}
+		}
+		return payloads, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	var payloads [][]byte
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		payloads = append(payloads, append([]byte(nil), scanner.Bytes()...))
+	}
+
+	return payloads, scanner.Err()
+}
+
+func (b *Backfiller) update(fn func(*model.BackfillStatus)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	fn(&b.status)
+}
+
+// recordError keeps a bounded list of errors for the status endpoint
+func (b *Backfiller) recordError(msg string) {
+	b.focil.logger.Warn("FOCIL backfill error", logger.Fields{"error": msg})
+
+	b.update(func(s *model.BackfillStatus) {
+		if len(s.Errors) < 100 {
+			s.Errors = append(s.Errors, msg)
+		}
+	})
+}
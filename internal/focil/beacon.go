@@ -0,0 +1,57 @@
+package focil
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+	"txpool-viz/internal/config"
+)
+
+// errBeaconNotFound is returned for 404 responses, e.g. missed slots or unsupported endpoints
+var errBeaconNotFound = errors.New("beacon api resource not found")
+
+// beaconClient performs JSON requests against a beacon node API
+type beaconClient struct {
+	url        string
+	httpClient *http.Client
+}
+
+// newBeaconClient builds a client sending the beacon node's credentials with every request
+func newBeaconClient(endpoint config.BeaconEndpoint) (*beaconClient, error) {
+	creds, err := endpoint.Credentials()
+	if err != nil {
+		return nil, err
+	}
+
+	return &beaconClient{
+		url:        endpoint.BeaconUrl,
+		httpClient: creds.HTTPClient(10 * time.Second),
+	}, nil
+}
+
+// get performs a beacon API GET request and decodes the JSON body into out
+func (b *beaconClient) get(ctx context.Context, path string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, b.url+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := b.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("%w: %s", errBeaconNotFound, path)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("beacon api %s returned %s", path, resp.Status)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -5,10 +5,14 @@ import (
 	"encoding/json"
 	"fmt"
 	"math/big"
+	"net/http"
 	"sync"
+	"time"
+	"txpool-viz/internal/capture"
 	"txpool-viz/internal/config"
 	"txpool-viz/internal/logger"
 	"txpool-viz/internal/model"
+	"txpool-viz/internal/storage"
 	"txpool-viz/utils"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -19,89 +23,152 @@ import (
 	"github.com/redis/go-redis/v9"
 )
 
+const (
+	inclusionListTopic = "inclusion_list"
+	sseReconnectMin    = time.Second
+	sseReconnectMax    = 30 * time.Second
+
+	// verificationQueueSize is how many streamed lists may wait for verification before new ones are skipped
+	verificationQueueSize = 1024
+	// verificationTimeout bounds the beacon lookups of one verification
+	verificationTimeout = 30 * time.Second
+)
+
 // FocilService encapsulates the logger and Redis client.
 type FocilService struct {
-	logger logger.Logger
-	redis  *redis.Client
+	logger   logger.Logger
+	redis    *redis.Client
+	verifier *Verifier         // nil when verification is disabled
+	recorder *capture.Recorder // nil unless a capture is being recorded
+
+	// Streamed lists are verified by RunVerifications so beacon lookups don't hold up the SSE stream
+	verifications chan model.Data
 }
 
 // NewFocilService constructs a new InclusionListService instance.
-func NewFocilService(l logger.Logger, r *redis.Client) *FocilService {
+// Pass a nil verifier to skip signature and committee checks.
+func NewFocilService(l logger.Logger, r *redis.Client, v *Verifier, rec *capture.Recorder) *FocilService {
 	return &FocilService{
-		logger: l,
-		redis:  r,
+		logger:        l,
+		redis:         r,
+		verifier:      v,
+		recorder:      rec,
+		verifications: make(chan model.Data, verificationQueueSize),
 	}
 }
 
-// Stream connects to the Beacon SSE stream and processes inclusion list events.
-func (fs *FocilService) Stream(ctx context.Context, endpoints []config.Endpoint, beaconEndpoints []config.BeaconEndpoint) {
-
-	for _, beaconEndpoint := range beaconEndpoints {
-		go fs.streamBeaconUrl(ctx, beaconEndpoint)
+// RunVerifications verifies the streamed inclusion lists queued by ingestInclusionList until ctx is cancelled
+func (fs *FocilService) RunVerifications(ctx context.Context) {
+	if fs.verifier == nil {
+		return
 	}
 
-	for _, endpoint := range endpoints {
-		go fs.processClientInclusionList(ctx, endpoint)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case data := <-fs.verifications:
+			verifyCtx, cancel := context.WithTimeout(ctx, verificationTimeout)
+			fs.verifyInclusionList(verifyCtx, data)
+			cancel()
+		}
 	}
-
-	<-ctx.Done()
 }
 
-func (fs *FocilService) streamBeaconUrl(ctx context.Context, endpoint config.BeaconEndpoint) {
+// StreamBeacon follows a beacon node's SSE stream and processes its inclusion list events until ctx is cancelled.
+func (fs *FocilService) StreamBeacon(ctx context.Context, endpoint config.BeaconEndpoint) {
 	sseURL := fmt.Sprintf("%s/eth/v1/events?topics=block&topics=inclusion_list", endpoint.BeaconUrl)
 	fs.logger.Info("Attempting connection to Beacon SSE endpoint", logger.Fields{
-		"url": sseURL,
+		"url":    sseURL,
+		"beacon": endpoint.Name,
 	})
 
-	client := dialSSEConnection(sseURL)
+	retry := &reconnectBackoff{min: sseReconnectMin, max: sseReconnectMax}
+	client, err := dialSSEConnection(sseURL, endpoint)
+	if err != nil {
+		fs.logger.Error("Failed to load beacon node credentials", logger.Fields{"beacon": endpoint.Name, "error": err.Error()})
+		return
+	}
 
-	events := make(chan *sse.Event)
-	errs := make(chan error, 1)
+	// Only report the subscription once the beacon node has accepted it
+	client.ResponseValidator = func(_ *sse.Client, resp *http.Response) error {
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return fmt.Errorf("could not connect to stream: %s", resp.Status)
+		}
 
-	go func() {
-		err := client.SubscribeRaw(func(msg *sse.Event) {
-			if len(msg.Data) == 0 {
-				fs.logger.Warn("Received empty SSE event data")
-				return
-			}
+		retry.reset()
+		fs.logger.Info("Successfully subscribed to SSE stream", logger.Fields{"beacon": endpoint.Name})
+		return nil
+	}
 
-			select {
-			case <-ctx.Done():
+	for {
+		// The client keeps the last event id between attempts so reconnects resume where they left off
+		err := client.SubscribeRawWithContext(ctx, func(event *sse.Event) {
+			receivedAt := time.Now()
+			fs.recorder.RecordEvent(endpoint.Name, string(event.Event), event.Data, receivedAt)
+
+			if len(event.Data) == 0 {
+				fs.logger.Warn("Received empty SSE event data", logger.Fields{"beacon": endpoint.Name})
 				return
-			case events <- msg:
 			}
+
+			fs.handleEvent(ctx, endpoint.Name, string(event.Event), event.Data, receivedAt)
 		})
-		errs <- err
-	}()
 
-	fs.logger.Info("Successfully subscribed to SSE stream")
+		if ctx.Err() != nil {
+			return
+		}
+
+		wait := retry.next()
+		fs.logger.Warn("SSE stream disconnected, reconnecting", logger.Fields{
+			"beacon": endpoint.Name,
+			"error":  err,
+			"wait":   wait.String(),
+		})
 
-	for {
 		select {
 		case <-ctx.Done():
-			client.Unsubscribe(events)
 			return
+		case <-time.After(wait):
+		}
+	}
+}
 
-		case event := <-events:
-			if event == nil {
-				continue
-			}
+// handleEvent dispatches an SSE event from a beacon node
+func (fs *FocilService) handleEvent(ctx context.Context, node, event string, data []byte, receivedAt time.Time) {
+	// Block events share the stream but carry no inclusion list
+	if event != "" && event != inclusionListTopic {
+		return
+	}
 
-			if err := fs.handleInclusionListMessage(ctx, event.Data); err != nil {
-				fs.logger.Error("Failed to handle inclusion list message", err)
-			}
+	if err := fs.handleInclusionListMessage(ctx, node, data, receivedAt); err != nil {
+		fs.logger.Error("Failed to handle inclusion list message", err)
+	}
+}
 
-		case err := <-errs:
-			if err != nil {
-				fs.logger.Error("SSE subscription error", err)
-			}
-			return
-		}
+// ReplayEvent feeds a captured beacon SSE event through the same path as a live stream, received at
+// its capture time.
+func (fs *FocilService) ReplayEvent(ctx context.Context, node, event string, data []byte, receivedAt time.Time) {
+	if len(data) == 0 {
+		return
+	}
+	fs.handleEvent(ctx, node, event, data, receivedAt)
+}
+
+// ReplayHead builds the inclusion report for a captured block header using a replayed client.
+func (fs *FocilService) ReplayHead(ctx context.Context, client *ethclient.Client, data []byte) {
+	var header types.Header
+	if err := json.Unmarshal(data, &header); err != nil {
+		fs.logger.Error("Failed to decode captured header", "err", err)
+		return
 	}
+	fs.processBlock(ctx, client, header.Number)
 }
 
-// handleInclusionListMessage processes a single inclusion list message.
-func (fs *FocilService) handleInclusionListMessage(ctx context.Context, jsonData []byte) error {
+// handleInclusionListMessage processes a single inclusion list message received from a beacon node.
+// The same list arriving from several nodes is only processed once, but every node's arrival time is kept.
+func (fs *FocilService) handleInclusionListMessage(ctx context.Context, node string, jsonData []byte, receivedAt time.Time) error {
 	msg, err := parseInclusionListMessage(jsonData)
 	if err != nil {
 		fs.logger.Error("Failed to parse inclusion list message", logger.Fields{
@@ -111,9 +178,30 @@ func (fs *FocilService) handleInclusionListMessage(ctx context.Context, jsonData
 		return err
 	}
 
-	transactions := make([]*types.Transaction, 0, len(msg.Data.Message.Transactions))
+	return fs.ingestInclusionList(ctx, node, msg.Data, receivedAt)
+}
+
+// ingestInclusionList stores a signed inclusion list. receivedAt is zero for historical
+// lists whose arrival time is unknown, in which case no arrival times are recorded.
+func (fs *FocilService) ingestInclusionList(ctx context.Context, node string, data model.Data, receivedAt time.Time) error {
+	if data.Message.Slot == "" {
+		return nil
+	}
+
+	first, err := fs.recordInclusionListRoot(ctx, node, data.Message, receivedAt)
+	if err != nil {
+		fs.logger.Warn("Failed to deduplicate inclusion list", logger.Fields{
+			"error": err,
+			"slot":  data.Message.Slot,
+			"node":  node,
+		})
+	} else if !first {
+		return nil
+	}
+
+	transactions := make([]*types.Transaction, 0, len(data.Message.Transactions))
 
-	for _, txDataHex := range msg.Data.Message.Transactions {
+	for _, txDataHex := range data.Message.Transactions {
 		txData, err := hexutil.Decode(txDataHex)
 		if err != nil {
 			fs.logger.Error("Hex decode failed", "err", err)
@@ -130,11 +218,27 @@ func (fs *FocilService) handleInclusionListMessage(ctx context.Context, jsonData
 		transactions = append(transactions, tx)
 	}
 
-	slot := msg.Data.Message.Slot
+	slot := data.Message.Slot
 	txCount := len(transactions)
 
-	if txCount == 0 && slot == "" {
-		return nil
+	if !receivedAt.IsZero() {
+		if err := fs.recordInclusionListArrival(ctx, slot, receivedAt.Unix()); err != nil {
+			fs.logger.Warn("Failed to record inclusion list arrival", logger.Fields{
+				"error": err,
+				"slot":  slot,
+			})
+		}
+	}
+
+	if err := fs.storeValidatorInclusionList(ctx, slot, data.Message.ValidatorIndex, transactions); err != nil {
+		fs.logger.Warn("Failed to store validator inclusion list", logger.Fields{
+			"error": err,
+			"slot":  slot,
+		})
+	}
+
+	if fs.verifier != nil {
+		fs.queueVerification(ctx, data, receivedAt.IsZero())
 	}
 
 	updated, err := fs.updateInclusionScore(ctx, slot, txCount)
@@ -173,6 +277,100 @@ func (fs *FocilService) storeInclusionTransactions(ctx context.Context, slot str
 	return fs.redis.HSet(ctx, utils.RedisInclusionListTxnsKey(), slot, data).Err()
 }
 
+// storeValidatorInclusionList keeps the tx hashes each committee member listed for a slot, and adds
+// the txs to the slot's union of listed txs.
+func (fs *FocilService) storeValidatorInclusionList(ctx context.Context, slot, validatorIndex string, transactions []*types.Transaction) error {
+	hashes := make([]string, 0, len(transactions))
+	listed := make(map[string]any, len(transactions))
+	for _, tx := range transactions {
+		raw, err := tx.MarshalBinary()
+		if err != nil {
+			return err
+		}
+		hashes = append(hashes, tx.Hash().Hex())
+		listed[tx.Hash().Hex()] = hexutil.Encode(raw)
+	}
+
+	data, err := json.Marshal(hashes)
+	if err != nil {
+		return err
+	}
+
+	pipe := fs.redis.TxPipeline()
+	pipe.HSet(ctx, utils.RedisInclusionListValidatorsKey(slot), validatorIndex, data)
+	if len(listed) > 0 {
+		pipe.HSet(ctx, utils.RedisInclusionListListedKey(slot), listed)
+	}
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// queueVerification hands a streamed list to RunVerifications. Historical lists are verified in place,
+// their backfill isn't waiting on a stream.
+func (fs *FocilService) queueVerification(ctx context.Context, data model.Data, historical bool) {
+	if historical {
+		fs.verifyInclusionList(ctx, data)
+		return
+	}
+
+	select {
+	case fs.verifications <- data:
+	default:
+		fs.logger.Warn("Verification queue full, skipping inclusion list", logger.Fields{
+			"slot":            data.Message.Slot,
+			"validator_index": data.Message.ValidatorIndex,
+		})
+	}
+}
+
+// verifyInclusionList checks the list's signature and committee membership and flags the result in storage.
+func (fs *FocilService) verifyInclusionList(ctx context.Context, data model.Data) {
+	result := fs.verifier.Verify(ctx, data)
+
+	if result.Status != model.VerificationValid {
+		fs.logger.Warn("Inclusion list failed verification", logger.Fields{
+			"slot":            result.Slot,
+			"validator_index": result.ValidatorIndex,
+			"status":          result.Status,
+			"reason":          result.Reason,
+		})
+	}
+
+	resultJSON, err := json.Marshal(result)
+	if err != nil {
+		fs.logger.Error("Failed to marshal verification result", "err", err)
+		return
+	}
+
+	key := utils.RedisInclusionVerificationKey(result.Slot)
+	if err := fs.redis.HSet(ctx, key, result.ValidatorIndex, resultJSON).Err(); err != nil {
+		fs.logger.Error("Failed to store verification result", "err", err)
+	}
+}
+
+// recordInclusionListRoot stores when a beacon node delivered an inclusion list, keyed by the list's root.
+// It reports whether this is the first time the list has been seen from any node.
+func (fs *FocilService) recordInclusionListRoot(ctx context.Context, node string, msg model.SSEMessage, receivedAt time.Time) (bool, error) {
+	root, err := inclusionListMessageRoot(msg)
+	if err != nil {
+		return false, err
+	}
+
+	if !receivedAt.IsZero() {
+		if err := fs.redis.HSetNX(ctx, utils.RedisInclusionListArrivalsKey(root.Hex()), node, receivedAt.UnixMilli()).Err(); err != nil {
+			return false, err
+		}
+	}
+
+	return fs.redis.HSetNX(ctx, utils.RedisInclusionListRootsKey(msg.Slot), root.Hex(), msg.ValidatorIndex).Result()
+}
+
+// recordInclusionListArrival stores the time the first inclusion list for a slot was seen.
+// Later lists for the same slot do not overwrite it.
+func (fs *FocilService) recordInclusionListArrival(ctx context.Context, slot string, timestamp int64) error {
+	return fs.redis.HSetNX(ctx, utils.RedisInclusionListSeenKey(), slot, timestamp).Err()
+}
+
 // updateInclusionScore updates the transaction count score in the Redis ZSET, only if the new count is greater.
 func (fs *FocilService) updateInclusionScore(ctx context.Context, slot string, txCount int) (bool, error) {
 	z := redis.Z{
@@ -202,49 +400,55 @@ func parseInclusionListMessage(jsonData []byte) (model.MempoolMessage, error) {
 	return msg, nil
 }
 
-// dialSSEConnection establishes an SSE client connection.
-func dialSSEConnection(sseURL string) *sse.Client {
-	return sse.NewClient(sseURL)
-}
-
-func (fs *FocilService) processClientInclusionList(ctx context.Context, endpoint config.Endpoint) {
-	// Dial WebSocket endpoint directly
-	client, err := ethclient.DialContext(ctx, endpoint.Websocket)
+// dialSSEConnection configures an SSE client for a beacon endpoint.
+// Reconnects are driven by StreamBeacon, so the client's own retries are disabled.
+// The credentials are applied to each attempt, so every reconnect carries a fresh JWT.
+func dialSSEConnection(sseURL string, endpoint config.BeaconEndpoint) (*sse.Client, error) {
+	creds, err := endpoint.Credentials()
 	if err != nil {
-		fs.logger.Error("Failed to connect to WebSocket endpoint", "err", err.Error())
-		return
+		return nil, err
 	}
 
-	// Subscribe to new block headers
-	headers := make(chan *types.Header)
-	sub, err := client.SubscribeNewHead(ctx, headers)
-	if err != nil {
-		fs.logger.Error("Error subscribing to newHeads", "err", err.Error())
-		return
-	}
+	client := sse.NewClient(sseURL)
+	client.ReconnectStrategy = stopBackoff{}
+	client.Connection = creds.HTTPClient(0)
 
-	defer sub.Unsubscribe()
+	return client, nil
+}
 
-	fs.logger.Info("Subscribed to new block headers")
+// stopBackoff tells the SSE client to give up after a single attempt
+type stopBackoff struct{}
 
-	var wg sync.WaitGroup
-	defer wg.Wait()
+func (stopBackoff) NextBackOff() time.Duration { return -1 }
+func (stopBackoff) Reset()                     {}
 
-	// Process each new block as it arrives
-	for {
-		select {
-		case <-ctx.Done():
-			wg.Wait()
-			return
-		case err := <-sub.Err():
-			fs.logger.Error("Subscription error", "err", err)
-			return
-		case header := <-headers:
-			fs.logger.Info("New Block", "block_number", header.Number.String())
-			wg.Add(1)
-			go fs.processBlock(ctx, client, header.Number)
-		}
+// reconnectBackoff doubles the wait between reconnect attempts up to max
+type reconnectBackoff struct {
+	min, max, current time.Duration
+}
+
+func (b *reconnectBackoff) next() time.Duration {
+	if b.current == 0 {
+		b.current = b.min
+	} else {
+		b.current = min(b.current*2, b.max)
 	}
+	return b.current
+}
+
+func (b *reconnectBackoff) reset() {
+	b.current = 0
+}
+
+// HandleHead builds the inclusion report of a new head's block in the background
+func (fs *FocilService) HandleHead(ctx context.Context, endpoint config.Endpoint, header *types.Header, wg *sync.WaitGroup) {
+	fs.logger.Info("New Block", "block_number", header.Number.String())
+
+	wg.Add(1)
+	go func(blockNumber *big.Int) {
+		defer wg.Done()
+		fs.processBlock(ctx, endpoint.Client, blockNumber)
+	}(header.Number)
 }
 
 func (fs *FocilService) processBlock(ctx context.Context, ethClient *ethclient.Client, blockNumber *big.Int) {
@@ -263,68 +467,78 @@ func (fs *FocilService) processBlock(ctx context.Context, ethClient *ethclient.C
 			return
 		}
 
-		// Get tx hashes in this block
-		blockTxHashes := make(map[common.Hash]bool)
-		for _, tx := range block.Transactions() {
-			blockTxHashes[tx.Hash()] = true
+		if blockNumber.Sign() <= 0 {
+			return
 		}
-
-		// Retrieve inclusion list txs from storage
-		slotKey := utils.RedisInclusionListTxnsKey()
-		slotNumber := new(big.Int).Sub(blockNumber, big.NewInt(1))
-		ilTxData, err := fs.redis.HGet(ctx, slotKey, slotNumber.String()).Result()
+		slotNumber := new(big.Int).SetUint64(inclusionListSlot(blockNumber.Uint64()))
+		report, err := fs.buildInclusionReport(ctx, block, slotNumber.String())
 		if err != nil {
-			fs.logger.Warn("Failed to get inclusion list", "slotKey", slotKey, "err", err.Error(), "blocknumber", blockNumber)
+			fs.logger.Warn("Failed to get inclusion list", "slot", slotNumber.String(), "err", err.Error(), "blocknumber", blockNumber)
 			return
 		}
 
-		// Decode inclusion list txs (as array)
-		var ilTxs []*types.Transaction
-		if err := json.Unmarshal([]byte(ilTxData), &ilTxs); err != nil {
-			fs.logger.Error("Failed to decode IL txs array", "err", err)
-			return
+		if err := fs.storeInclusionReport(ctx, blockNumber.String(), report); err != nil {
+			fs.logger.Error("Failed to store inclusion report in hash", "err", err)
 		}
+	}
+}
 
-		// Extract hashes
-		var ilTxHashes []common.Hash
-		for _, tx := range ilTxs {
-			if tx != nil {
-				ilTxHashes = append(ilTxHashes, tx.Hash())
-			}
-		}
+// inclusionListSlot returns the slot whose inclusion lists constrain a block. Reports are keyed by
+// block number and the inclusion list views read the lists of the previous slot number, so live
+// processing and backfill both use it to line up.
+func inclusionListSlot(blockNumber uint64) uint64 {
+	return blockNumber - 1
+}
 
-		// Compare IL tx hashes with block tx hashes
-		var included, missing []common.Hash
-		for _, hash := range ilTxHashes {
-			if blockTxHashes[hash] {
-				included = append(included, hash)
-			} else {
-				missing = append(missing, hash)
-			}
-		}
+// buildInclusionReport compares a block's transactions against the txs any validator listed for ilSlot
+func (fs *FocilService) buildInclusionReport(ctx context.Context, block *types.Block, ilSlot string) (model.InclusionReport, error) {
+	// Get tx hashes in this block
+	blockTxHashes := make(map[common.Hash]bool)
+	for _, tx := range block.Transactions() {
+		blockTxHashes[tx.Hash()] = true
+	}
 
-		inclusionReportKey := utils.RedisInclusionListReportKey()
-		slot := blockNumber.String()
-
-		report := model.InclusionReport{
-			Included: included,
-			Missing:  missing,
-			Summary: model.InclusionSummary{
-				Total:    len(ilTxHashes),
-				Included: len(included),
-				Missing:  len(missing),
-			},
-		}
+	// Retrieve inclusion list txs from storage
+	ilTxs, err := storage.InclusionListTransactions(ctx, fs.redis, ilSlot)
+	if err != nil {
+		return model.InclusionReport{}, fmt.Errorf("failed to read IL txs: %w", err)
+	}
 
-		reportJSON, err := json.Marshal(report)
-		if err != nil {
-			fs.logger.Error("Failed to marshal inclusion report", "err", err)
-			return
+	// Extract hashes
+	var ilTxHashes []common.Hash
+	for _, tx := range ilTxs {
+		if tx != nil {
+			ilTxHashes = append(ilTxHashes, tx.Hash())
 		}
+	}
 
-		// Store in hash under slot field
-		if err := fs.redis.HSet(ctx, inclusionReportKey, slot, reportJSON).Err(); err != nil {
-			fs.logger.Error("Failed to store inclusion report in hash", "err", err)
+	// Compare IL tx hashes with block tx hashes
+	var included, missing []common.Hash
+	for _, hash := range ilTxHashes {
+		if blockTxHashes[hash] {
+			included = append(included, hash)
+		} else {
+			missing = append(missing, hash)
 		}
 	}
+
+	return model.InclusionReport{
+		Included: included,
+		Missing:  missing,
+		Summary: model.InclusionSummary{
+			Total:    len(ilTxHashes),
+			Included: len(included),
+			Missing:  len(missing),
+		},
+	}, nil
+}
+
+// storeInclusionReport saves a report in the report hash. Reports are keyed by block number.
+func (fs *FocilService) storeInclusionReport(ctx context.Context, blockNumber string, report model.InclusionReport) error {
+	reportJSON, err := json.Marshal(report)
+	if err != nil {
+		return fmt.Errorf("failed to marshal inclusion report: %w", err)
+	}
+
+	return fs.redis.HSet(ctx, utils.RedisInclusionListReportKey(), blockNumber, reportJSON).Err()
 }
@@ -0,0 +1,161 @@
+package focil
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"strconv"
+
+	"txpool-viz/internal/model"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+const (
+	// SSZ limits from the EIP-7805 consensus specs
+	maxBytesPerTransaction    = 1 << 30
+	maxTransactionsPerPayload = 1 << 20
+)
+
+// zeroHashes[i] is the root of a fully zeroed merkle tree of depth i
+var zeroHashes [64][32]byte
+
+func init() {
+	for i := 1; i < len(zeroHashes); i++ {
+		zeroHashes[i] = hashPair(zeroHashes[i-1], zeroHashes[i-1])
+	}
+}
+
+func hashPair(a, b [32]byte) [32]byte {
+	return sha256.Sum256(append(a[:], b[:]...))
+}
+
+// merkleize computes the SSZ merkle root of chunks padded with zero chunks up to limit
+func merkleize(chunks [][32]byte, limit uint64) [32]byte {
+	depth := 0
+	for uint64(1)<<depth < limit {
+		depth++
+	}
+
+	if len(chunks) == 0 {
+		return zeroHashes[depth]
+	}
+
+	layer := make([][32]byte, len(chunks))
+	copy(layer, chunks)
+
+	for d := 0; d < depth; d++ {
+		if len(layer)%2 == 1 {
+			layer = append(layer, zeroHashes[d])
+		}
+
+		next := make([][32]byte, len(layer)/2)
+		for i := range next {
+			next[i] = hashPair(layer[2*i], layer[2*i+1])
+		}
+		layer = next
+	}
+
+	return layer[0]
+}
+
+func mixInLength(root [32]byte, length uint64) [32]byte {
+	return hashPair(root, uint64Root(length))
+}
+
+func uint64Root(v uint64) [32]byte {
+	var chunk [32]byte
+	binary.LittleEndian.PutUint64(chunk[:8], v)
+	return chunk
+}
+
+// packBytes splits data into zero padded 32 byte chunks
+func packBytes(data []byte) [][32]byte {
+	chunks := make([][32]byte, (len(data)+31)/32)
+	for i := range chunks {
+		copy(chunks[i][:], data[i*32:])
+	}
+	return chunks
+}
+
+// packUint64s serializes a list of uint64 values into 32 byte chunks
+func packUint64s(values []uint64) [][32]byte {
+	data := make([]byte, len(values)*8)
+	for i, v := range values {
+		binary.LittleEndian.PutUint64(data[i*8:], v)
+	}
+	return packBytes(data)
+}
+
+// inclusionListRoot returns hash_tree_root(InclusionList)
+func inclusionListRoot(slot, validatorIndex uint64, committeeRoot [32]byte, transactions [][]byte) [32]byte {
+	txRoots := make([][32]byte, len(transactions))
+	for i, tx := range transactions {
+		txRoots[i] = mixInLength(merkleize(packBytes(tx), (maxBytesPerTransaction+31)/32), uint64(len(tx)))
+	}
+	txsRoot := mixInLength(merkleize(txRoots, maxTransactionsPerPayload), uint64(len(transactions)))
+
+	return merkleize([][32]byte{
+		uint64Root(slot),
+		uint64Root(validatorIndex),
+		committeeRoot,
+		txsRoot,
+	}, 4)
+}
+
+// inclusionListMessageRoot returns the hash tree root of an inclusion list as received over SSE
+func inclusionListMessageRoot(msg model.SSEMessage) (common.Hash, error) {
+	slot, err := strconv.ParseUint(msg.Slot, 10, 64)
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("invalid slot: %s", msg.Slot)
+	}
+
+	validatorIndex, err := strconv.ParseUint(msg.ValidatorIndex, 10, 64)
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("invalid validator index: %s", msg.ValidatorIndex)
+	}
+
+	transactions, err := decodeInclusionListTransactions(msg.Transactions)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	listedRoot := common.HexToHash(msg.InclusionListCommitteeRoot)
+	return inclusionListRoot(slot, validatorIndex, listedRoot, transactions), nil
+}
+
+func decodeInclusionListTransactions(txs []string) ([][]byte, error) {
+	transactions := make([][]byte, 0, len(txs))
+	for _, txHex := range txs {
+		tx, err := hexutil.Decode(txHex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid transaction encoding: %w", err)
+		}
+		transactions = append(transactions, tx)
+	}
+	return transactions, nil
+}
+
+// committeeRoot returns hash_tree_root(Vector[ValidatorIndex, len(committee)])
+func committeeRoot(committee []uint64) [32]byte {
+	chunks := packUint64s(committee)
+	return merkleize(chunks, uint64(len(chunks)))
+}
+
+// computeDomain mirrors compute_domain from the consensus specs
+func computeDomain(domainType [4]byte, forkVersion [4]byte, genesisValidatorsRoot [32]byte) [32]byte {
+	var version [32]byte
+	copy(version[:], forkVersion[:])
+	forkDataRoot := hashPair(version, genesisValidatorsRoot)
+
+	var domain [32]byte
+	copy(domain[:4], domainType[:])
+	copy(domain[4:], forkDataRoot[:28])
+	return domain
+}
+
+// computeSigningRoot mirrors compute_signing_root from the consensus specs
+func computeSigningRoot(objectRoot [32]byte, domain [32]byte) [32]byte {
+	return hashPair(objectRoot, domain)
+}
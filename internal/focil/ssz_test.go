@@ -0,0 +1,81 @@
+package focil
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Mainnet genesis_validators_root
+var mainnetGenesisValidatorsRoot = [32]byte(common.HexToHash("0x4b363db94e286120d76eb905340fdd4e54bfe9f06bf33ff6cf5ad27f511bfe95"))
+
+func TestZeroHashes(t *testing.T) {
+	// Roots of zeroed trees, as used by the deposit contract and SSZ list padding
+	want := []string{
+		"0x0000000000000000000000000000000000000000000000000000000000000000",
+		"0xf5a5fd42d16a20302798ef6ed309979b43003d2320d9f0e8ea9831a92759fb4b",
+		"0xdb56114e00fdd4c1f85c892bf35ac9a89289aaecb1ebd0a96cde606a748b5d71",
+		"0xc78009fdf07fc56a11f122370658a353aaa542ed63e44c4bc15ff4cd105ab33c",
+	}
+	for depth, root := range want {
+		if got := common.Hash(zeroHashes[depth]); got != common.HexToHash(root) {
+			t.Errorf("zero hash at depth %d: got %s, want %s", depth, got, root)
+		}
+		if got := common.Hash(merkleize(nil, 1<<depth)); got != common.HexToHash(root) {
+			t.Errorf("merkleize of no chunks with limit %d: got %s, want %s", 1<<depth, got, root)
+		}
+	}
+}
+
+func TestEmptyDepositRoot(t *testing.T) {
+	// get_deposit_root() of a deposit contract without deposits: List[DepositData, 2**32] of length 0
+	want := common.HexToHash("0xd70a234731285c6804c2a4f56711ddb8c82c99740f207854891028af34e27e5e")
+	if got := common.Hash(mixInLength(merkleize(nil, 1<<32), 0)); got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestComputeDomain(t *testing.T) {
+	// DOMAIN_DEPOSIT is computed against the genesis fork and a zero genesis_validators_root
+	want := common.HexToHash("0x03000000f5a5fd42d16a20302798ef6ed309979b43003d2320d9f0e8ea9831a9")
+	if got := common.Hash(computeDomain([4]byte{0x03}, [4]byte{}, [32]byte{})); got != want {
+		t.Errorf("deposit domain: got %s, want %s", got, want)
+	}
+
+	// The fork digests of mainnet's forks are the first 4 bytes of their fork data roots, which
+	// compute_domain places after the domain type
+	digests := []struct {
+		version [4]byte
+		digest  string
+	}{
+		{[4]byte{0x00, 0x00, 0x00, 0x00}, "0xb5303f2a"},
+		{[4]byte{0x01, 0x00, 0x00, 0x00}, "0xafcaaba0"},
+		{[4]byte{0x02, 0x00, 0x00, 0x00}, "0x4a26c58b"},
+		{[4]byte{0x03, 0x00, 0x00, 0x00}, "0xbba4da96"},
+		{[4]byte{0x04, 0x00, 0x00, 0x00}, "0x6a95a1a9"},
+	}
+	for _, tc := range digests {
+		domain := computeDomain(domainInclusionListCommittee, tc.version, mainnetGenesisValidatorsRoot)
+		if got := common.Bytes2Hex(domain[4:8]); "0x"+got != tc.digest {
+			t.Errorf("fork %x: got digest 0x%s, want %s", tc.version, got, tc.digest)
+		}
+		if [4]byte(domain[:4]) != domainInclusionListCommittee {
+			t.Errorf("fork %x: domain type %x", tc.version, domain[:4])
+		}
+	}
+}
+
+func TestCommitteeRoot(t *testing.T) {
+	// A Vector[uint64, 4] packs into a single chunk, which is its own root
+	want := common.HexToHash("0x0100000000000000020000000000000003000000000000000400000000000000")
+	if got := common.Hash(committeeRoot([]uint64{1, 2, 3, 4})); got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+
+	// Five indices take two chunks, hashed together
+	chunks := packUint64s([]uint64{1, 2, 3, 4, 5})
+	want = common.Hash(hashPair(chunks[0], chunks[1]))
+	if got := common.Hash(committeeRoot([]uint64{1, 2, 3, 4, 5})); got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
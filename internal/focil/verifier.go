@@ -0,0 +1,468 @@
+package focil
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"txpool-viz/internal/config"
+	"txpool-viz/internal/model"
+
+	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// Proof of possession ciphersuite used by the beacon chain
+	blsSignatureDST = "BLS_SIG_BLS12381G2_XMD:SHA-256_SSWU_RO_POP_"
+
+	// FOCIL beacon APIs are not standardised yet, so the committee path can be overridden in config
+	defaultCommitteePath = "/eth/v1/beacon/states/head/inclusion_list_committee?slot=%d"
+
+	// forkCacheTTL is how long the head state's fork is reused before it's fetched again
+	forkCacheTTL = time.Minute
+)
+
+// DOMAIN_INCLUSION_LIST_COMMITTEE
+var domainInclusionListCommittee = [4]byte{0x0c, 0x00, 0x00, 0x00}
+
+// CommitteeSource provides the beacon state needed to verify inclusion lists
+type CommitteeSource interface {
+	Committee(ctx context.Context, slot uint64) ([]uint64, error)
+	Pubkey(ctx context.Context, validatorIndex uint64) ([]byte, error)
+	ForkVersion(ctx context.Context, slot uint64) ([4]byte, error)
+	GenesisValidatorsRoot(ctx context.Context) ([32]byte, error)
+}
+
+// Verifier checks inclusion list committee membership and BLS signatures
+type Verifier struct {
+	source CommitteeSource
+}
+
+// NewVerifier constructs a Verifier backed by the given committee source.
+func NewVerifier(source CommitteeSource) *Verifier {
+	return &Verifier{source: source}
+}
+
+// NewCommitteeSource picks a stub file source when configured, otherwise the beacon API.
+func NewCommitteeSource(cfg config.FocilVerification, beaconEndpoints []config.BeaconEndpoint) (CommitteeSource, error) {
+	if cfg.StubFile != "" {
+		return NewStaticCommitteeSource(cfg.StubFile)
+	}
+
+	beacon := config.BeaconEndpoint{Name: "verification", BeaconUrl: cfg.BeaconUrl}
+	if beacon.BeaconUrl == "" && len(beaconEndpoints) > 0 {
+		beacon = beaconEndpoints[0]
+	}
+	if beacon.BeaconUrl == "" {
+		return nil, fmt.Errorf("no beacon url configured for inclusion list verification")
+	}
+
+	return NewBeaconCommitteeSource(beacon, cfg.CommitteePath)
+}
+
+// Verify checks a signed inclusion list against its slot's committee.
+// Lookup failures produce an "unverified" result rather than an invalid one.
+func (v *Verifier) Verify(ctx context.Context, data model.Data) model.InclusionListVerification {
+	msg := data.Message
+	result := model.InclusionListVerification{
+		Slot:           msg.Slot,
+		ValidatorIndex: msg.ValidatorIndex,
+		Status:         model.VerificationUnverified,
+		VerifiedAt:     time.Now().Unix(),
+	}
+
+	slot, err := strconv.ParseUint(msg.Slot, 10, 64)
+	if err != nil {
+		result.Status = model.VerificationInvalid
+		result.Reason = fmt.Sprintf("invalid slot: %s", msg.Slot)
+		return result
+	}
+
+	validatorIndex, err := strconv.ParseUint(msg.ValidatorIndex, 10, 64)
+	if err != nil {
+		result.Status = model.VerificationInvalid
+		result.Reason = fmt.Sprintf("invalid validator index: %s", msg.ValidatorIndex)
+		return result
+	}
+
+	committee, err := v.source.Committee(ctx, slot)
+	if err != nil {
+		result.Reason = fmt.Sprintf("committee lookup failed: %s", err)
+		return result
+	}
+
+	result.CommitteeMember = slices.Contains(committee, validatorIndex)
+	if !result.CommitteeMember {
+		result.Status = model.VerificationInvalid
+		result.Reason = "validator is not in the inclusion list committee"
+		return result
+	}
+
+	listedRoot := common.HexToHash(msg.InclusionListCommitteeRoot)
+	if listedRoot != common.Hash(committeeRoot(committee)) {
+		result.Status = model.VerificationInvalid
+		result.Reason = "inclusion list committee root mismatch"
+		return result
+	}
+
+	pubkey, err := v.source.Pubkey(ctx, validatorIndex)
+	if err != nil {
+		result.Reason = fmt.Sprintf("pubkey lookup failed: %s", err)
+		return result
+	}
+
+	objectRoot, err := inclusionListMessageRoot(msg)
+	if err != nil {
+		result.Status = model.VerificationInvalid
+		result.Reason = err.Error()
+		return result
+	}
+
+	signingRoot, err := v.signingRoot(ctx, slot, objectRoot)
+	if err != nil {
+		result.Reason = err.Error()
+		return result
+	}
+
+	signature, err := hexutil.Decode(data.Signature)
+	if err != nil {
+		result.Status = model.VerificationInvalid
+		result.Reason = fmt.Sprintf("invalid signature encoding: %s", err)
+		return result
+	}
+
+	result.SignatureValid, err = verifyBLSSignature(pubkey, signingRoot[:], signature)
+	if err != nil {
+		result.Status = model.VerificationInvalid
+		result.Reason = err.Error()
+		return result
+	}
+	if !result.SignatureValid {
+		result.Status = model.VerificationInvalid
+		result.Reason = "signature does not match validator pubkey"
+		return result
+	}
+
+	result.Status = model.VerificationValid
+	return result
+}
+
+// signingRoot computes the root the committee member is expected to have signed
+func (v *Verifier) signingRoot(ctx context.Context, slot uint64, objectRoot common.Hash) ([32]byte, error) {
+	forkVersion, err := v.source.ForkVersion(ctx, slot)
+	if err != nil {
+		return [32]byte{}, fmt.Errorf("fork version lookup failed: %w", err)
+	}
+
+	genesisValidatorsRoot, err := v.source.GenesisValidatorsRoot(ctx)
+	if err != nil {
+		return [32]byte{}, fmt.Errorf("genesis lookup failed: %w", err)
+	}
+
+	domain := computeDomain(domainInclusionListCommittee, forkVersion, genesisValidatorsRoot)
+
+	return computeSigningRoot(objectRoot, domain), nil
+}
+
+// verifyBLSSignature checks e(pubkey, H(msg)) == e(g1, signature)
+func verifyBLSSignature(pubkey, msg, signature []byte) (bool, error) {
+	var pk bls12381.G1Affine
+	if _, err := pk.SetBytes(pubkey); err != nil {
+		return false, fmt.Errorf("invalid pubkey: %w", err)
+	}
+	if pk.IsInfinity() {
+		return false, fmt.Errorf("invalid pubkey: point at infinity")
+	}
+
+	var sig bls12381.G2Affine
+	if _, err := sig.SetBytes(signature); err != nil {
+		return false, fmt.Errorf("invalid signature: %w", err)
+	}
+
+	hashedMsg, err := bls12381.HashToG2(msg, []byte(blsSignatureDST))
+	if err != nil {
+		return false, fmt.Errorf("hash to curve failed: %w", err)
+	}
+
+	_, _, g1, _ := bls12381.Generators()
+	var negG1 bls12381.G1Affine
+	negG1.Neg(&g1)
+
+	return bls12381.PairingCheck([]bls12381.G1Affine{pk, negG1}, []bls12381.G2Affine{hashedMsg, sig})
+}
+
+// BeaconCommitteeSource resolves committees and keys through the beacon node API
+type BeaconCommitteeSource struct {
+	api           *beaconClient
+	committeePath string
+
+	mu                    sync.Mutex
+	committees            map[uint64][]uint64
+	pubkeys               map[uint64][]byte
+	slotsPerEpoch         uint64
+	genesisValidatorsRoot *[32]byte
+	fork                  *beaconFork
+	forkFetchedAt         time.Time
+}
+
+// beaconFork is the fork of the head state
+type beaconFork struct {
+	PreviousVersion string `json:"previous_version"`
+	CurrentVersion  string `json:"current_version"`
+	Epoch           string `json:"epoch"`
+}
+
+// NewBeaconCommitteeSource constructs a committee source for a beacon node.
+func NewBeaconCommitteeSource(beacon config.BeaconEndpoint, committeePath string) (*BeaconCommitteeSource, error) {
+	if committeePath == "" {
+		committeePath = defaultCommitteePath
+	}
+
+	api, err := newBeaconClient(beacon)
+	if err != nil {
+		return nil, err
+	}
+
+	return &BeaconCommitteeSource{
+		api:           api,
+		committeePath: committeePath,
+		committees:    make(map[uint64][]uint64),
+		pubkeys:       make(map[uint64][]byte),
+	}, nil
+}
+
+func (b *BeaconCommitteeSource) Committee(ctx context.Context, slot uint64) ([]uint64, error) {
+	b.mu.Lock()
+	committee, ok := b.committees[slot]
+	b.mu.Unlock()
+	if ok {
+		return committee, nil
+	}
+
+	var resp struct {
+		Data []string `json:"data"`
+	}
+	if err := b.api.get(ctx, fmt.Sprintf(b.committeePath, slot), &resp); err != nil {
+		return nil, err
+	}
+
+	committee = make([]uint64, 0, len(resp.Data))
+	for _, index := range resp.Data {
+		v, err := strconv.ParseUint(index, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid committee index %q: %w", index, err)
+		}
+		committee = append(committee, v)
+	}
+
+	b.mu.Lock()
+	b.committees[slot] = committee
+	// Only recent slots are verified, keep the cache bounded
+	for cached := range b.committees {
+		if cached+64 < slot {
+			delete(b.committees, cached)
+		}
+	}
+	b.mu.Unlock()
+
+	return committee, nil
+}
+
+func (b *BeaconCommitteeSource) Pubkey(ctx context.Context, validatorIndex uint64) ([]byte, error) {
+	b.mu.Lock()
+	pubkey, ok := b.pubkeys[validatorIndex]
+	b.mu.Unlock()
+	if ok {
+		return pubkey, nil
+	}
+
+	var resp struct {
+		Data struct {
+			Validator struct {
+				Pubkey string `json:"pubkey"`
+			} `json:"validator"`
+		} `json:"data"`
+	}
+	if err := b.api.get(ctx, fmt.Sprintf("/eth/v1/beacon/states/head/validators/%d", validatorIndex), &resp); err != nil {
+		return nil, err
+	}
+
+	pubkey, err := hexutil.Decode(resp.Data.Validator.Pubkey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pubkey for validator %d: %w", validatorIndex, err)
+	}
+
+	b.mu.Lock()
+	b.pubkeys[validatorIndex] = pubkey
+	b.mu.Unlock()
+
+	return pubkey, nil
+}
+
+func (b *BeaconCommitteeSource) ForkVersion(ctx context.Context, slot uint64) ([4]byte, error) {
+	slotsPerEpoch, err := b.getSlotsPerEpoch(ctx)
+	if err != nil {
+		return [4]byte{}, err
+	}
+
+	fork, err := b.headFork(ctx)
+	if err != nil {
+		return [4]byte{}, err
+	}
+
+	forkEpoch, err := strconv.ParseUint(fork.Epoch, 10, 64)
+	if err != nil {
+		return [4]byte{}, fmt.Errorf("invalid fork epoch %q: %w", fork.Epoch, err)
+	}
+
+	version := fork.CurrentVersion
+	if slot/slotsPerEpoch < forkEpoch {
+		version = fork.PreviousVersion
+	}
+
+	return decodeForkVersion(version)
+}
+
+// headFork returns the head state's fork, fetched at most once per forkCacheTTL
+func (b *BeaconCommitteeSource) headFork(ctx context.Context) (beaconFork, error) {
+	b.mu.Lock()
+	cached, fetchedAt := b.fork, b.forkFetchedAt
+	b.mu.Unlock()
+	if cached != nil && time.Since(fetchedAt) < forkCacheTTL {
+		return *cached, nil
+	}
+
+	var resp struct {
+		Data beaconFork `json:"data"`
+	}
+	if err := b.api.get(ctx, "/eth/v1/beacon/states/head/fork", &resp); err != nil {
+		return beaconFork{}, err
+	}
+
+	b.mu.Lock()
+	b.fork = &resp.Data
+	b.forkFetchedAt = time.Now()
+	b.mu.Unlock()
+
+	return resp.Data, nil
+}
+
+func (b *BeaconCommitteeSource) GenesisValidatorsRoot(ctx context.Context) ([32]byte, error) {
+	b.mu.Lock()
+	cached := b.genesisValidatorsRoot
+	b.mu.Unlock()
+	if cached != nil {
+		return *cached, nil
+	}
+
+	var resp struct {
+		Data struct {
+			GenesisValidatorsRoot string `json:"genesis_validators_root"`
+		} `json:"data"`
+	}
+	if err := b.api.get(ctx, "/eth/v1/beacon/genesis", &resp); err != nil {
+		return [32]byte{}, err
+	}
+
+	root := [32]byte(common.HexToHash(resp.Data.GenesisValidatorsRoot))
+
+	b.mu.Lock()
+	b.genesisValidatorsRoot = &root
+	b.mu.Unlock()
+
+	return root, nil
+}
+
+func (b *BeaconCommitteeSource) getSlotsPerEpoch(ctx context.Context) (uint64, error) {
+	b.mu.Lock()
+	cached := b.slotsPerEpoch
+	b.mu.Unlock()
+	if cached != 0 {
+		return cached, nil
+	}
+
+	var resp struct {
+		Data struct {
+			SlotsPerEpoch string `json:"SLOTS_PER_EPOCH"`
+		} `json:"data"`
+	}
+	if err := b.api.get(ctx, "/eth/v1/config/spec", &resp); err != nil {
+		return 0, err
+	}
+
+	slotsPerEpoch, err := strconv.ParseUint(resp.Data.SlotsPerEpoch, 10, 64)
+	if err != nil || slotsPerEpoch == 0 {
+		return 0, fmt.Errorf("invalid SLOTS_PER_EPOCH %q", resp.Data.SlotsPerEpoch)
+	}
+
+	b.mu.Lock()
+	b.slotsPerEpoch = slotsPerEpoch
+	b.mu.Unlock()
+
+	return slotsPerEpoch, nil
+}
+
+// StaticCommitteeSource serves committees and keys from a local stub file
+type StaticCommitteeSource struct {
+	GenesisValidatorsRootHex string              `yaml:"genesis_validators_root"`
+	ForkVersionHex           string              `yaml:"fork_version"`
+	DefaultCommittee         []uint64            `yaml:"committee"`
+	Committees               map[uint64][]uint64 `yaml:"committees"`
+	Pubkeys                  map[uint64]string   `yaml:"pubkeys"`
+}
+
+// NewStaticCommitteeSource loads a committee stub file.
+func NewStaticCommitteeSource(path string) (*StaticCommitteeSource, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading committee stub file: %w", err)
+	}
+
+	source := &StaticCommitteeSource{}
+	if err := yaml.Unmarshal(data, source); err != nil {
+		return nil, fmt.Errorf("error parsing committee stub file: %w", err)
+	}
+
+	return source, nil
+}
+
+func (s *StaticCommitteeSource) Committee(_ context.Context, slot uint64) ([]uint64, error) {
+	if committee, ok := s.Committees[slot]; ok {
+		return committee, nil
+	}
+	if len(s.DefaultCommittee) > 0 {
+		return s.DefaultCommittee, nil
+	}
+	return nil, fmt.Errorf("no committee for slot %d", slot)
+}
+
+func (s *StaticCommitteeSource) Pubkey(_ context.Context, validatorIndex uint64) ([]byte, error) {
+	pubkey, ok := s.Pubkeys[validatorIndex]
+	if !ok {
+		return nil, fmt.Errorf("no pubkey for validator %d", validatorIndex)
+	}
+	return hexutil.Decode(pubkey)
+}
+
+func (s *StaticCommitteeSource) ForkVersion(_ context.Context, _ uint64) ([4]byte, error) {
+	return decodeForkVersion(s.ForkVersionHex)
+}
+
+func (s *StaticCommitteeSource) GenesisValidatorsRoot(_ context.Context) ([32]byte, error) {
+	return [32]byte(common.HexToHash(s.GenesisValidatorsRootHex)), nil
+}
+
+func decodeForkVersion(version string) ([4]byte, error) {
+	raw, err := hexutil.Decode(version)
+	if err != nil || len(raw) != 4 {
+		return [4]byte{}, fmt.Errorf("invalid fork version %q", version)
+	}
+	return [4]byte(raw), nil
+}
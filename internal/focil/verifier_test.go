@@ -0,0 +1,77 @@
+package focil
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Vectors from the consensus specs' BLS test suite (ethereum/bls12-381-tests, sign and verify)
+var blsVectors = []struct {
+	name      string
+	pubkey    string
+	message   []byte
+	signature string
+}{
+	{
+		name:      "zero message",
+		pubkey:    "0xa491d1b0ecd9bb917989f0e74f0dea0422eac4a873e5e2644f368dffb9a6e20fd6e10c1b77654d067c0618f6e5a7f79a",
+		message:   make([]byte, 32),
+		signature: "0xb6ed936746e01f8ecf281f020953fbf1f01debd5657c4a383940b020b26507f6076334f91e2366c96e9ab279fb5158090352ea1c5b0c9274504f4f0e7053af24802e51e4568d164fe986834f41e55c8e850ce1f98458c0cfc9ab380b55285a55",
+	},
+	{
+		name:      "0x56 message",
+		pubkey:    "0xa491d1b0ecd9bb917989f0e74f0dea0422eac4a873e5e2644f368dffb9a6e20fd6e10c1b77654d067c0618f6e5a7f79a",
+		message:   bytes.Repeat([]byte{0x56}, 32),
+		signature: "0x882730e5d03f6b42c3abc26d3372625034e1d871b65a8a6b900a56dae22da98abbe1b68f85e49fe7652a55ec3d0591c20767677e33e5cbb1207315c41a9ac03be39c2e7668edc043d6cb1d9fd93033caa8a1c5b0e84bedaeb6c64972503a43eb",
+	},
+	{
+		name:      "0xab message",
+		pubkey:    "0xa491d1b0ecd9bb917989f0e74f0dea0422eac4a873e5e2644f368dffb9a6e20fd6e10c1b77654d067c0618f6e5a7f79a",
+		message:   bytes.Repeat([]byte{0xab}, 32),
+		signature: "0x91347bccf740d859038fcdcaf233eeceb2a436bcaaee9b2aa3bfb70efe29dfb2677562ccbea1c8e061fb9971b0753c240622fab78489ce96768259fc01360346da5b9f579e5da0d941e4c6ba18a0e64906082375394f337fa1af2b7127b0d121",
+	},
+	{
+		name:      "other key",
+		pubkey:    "0xb53d21a4cfd562c469cc81514d4ce5a6b577d8403d32a394dc265dd190b47fa9f829fdd7963afdf972e5e77854051f6f",
+		message:   bytes.Repeat([]byte{0xab}, 32),
+		signature: "0xae82747ddeefe4fd64cf9cedb9b04ae3e8a43420cd255e3c7cd06a8d88b7c7f8638543719981c5d16fa3527c468c25f0026704a6951bde891360c7e8d12ddee0559004ccdbe6046b55bae1b257ee97f7cdb955773d7cf29adf3ccbb9975e4eb9",
+	},
+}
+
+func TestVerifyBLSSignature(t *testing.T) {
+	for _, tc := range blsVectors {
+		valid, err := verifyBLSSignature(common.FromHex(tc.pubkey), tc.message, common.FromHex(tc.signature))
+		if err != nil || !valid {
+			t.Errorf("%s: got valid=%v err=%v, want a valid signature", tc.name, valid, err)
+		}
+	}
+}
+
+func TestVerifyBLSSignatureRejects(t *testing.T) {
+	valid := blsVectors[0]
+
+	// Signature of another message
+	ok, err := verifyBLSSignature(common.FromHex(valid.pubkey), blsVectors[1].message, common.FromHex(valid.signature))
+	if err != nil || ok {
+		t.Errorf("wrong message: got valid=%v err=%v", ok, err)
+	}
+
+	// Signature by another key
+	ok, err = verifyBLSSignature(common.FromHex(blsVectors[3].pubkey), valid.message, common.FromHex(valid.signature))
+	if err != nil || ok {
+		t.Errorf("wrong key: got valid=%v err=%v", ok, err)
+	}
+
+	// The infinity pubkey never verifies
+	infinity := append([]byte{0xc0}, make([]byte, 47)...)
+	if ok, err := verifyBLSSignature(infinity, valid.message, common.FromHex(valid.signature)); err == nil || ok {
+		t.Errorf("infinity pubkey: got valid=%v err=%v, want an error", ok, err)
+	}
+
+	// A truncated signature doesn't decode
+	if ok, err := verifyBLSSignature(common.FromHex(valid.pubkey), valid.message, common.FromHex(valid.signature)[:95]); err == nil || ok {
+		t.Errorf("truncated signature: got valid=%v err=%v, want an error", ok, err)
+	}
+}
@@ -0,0 +1,266 @@
+package mocknode
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"txpool-viz/internal/logger"
+)
+
+// Beacon serves a mock beacon node per scenario node on /<node>, emitting the scripted SSE events.
+type Beacon struct {
+	scenario *BeaconScenario
+	events   []beaconEvent
+	speed    float64
+	logger   logger.Logger
+
+	mu        sync.Mutex
+	emitted   map[string][]beaconEvent // node -> events sent so far, indexed by event id - 1
+	listeners map[string]map[chan struct{}]struct{}
+}
+
+// NewBeacon builds a Beacon for a scenario. speed 1 plays the scenario in real time, higher values accelerate.
+func NewBeacon(s *BeaconScenario, speed float64, l logger.Logger) (*Beacon, error) {
+	events, err := s.events()
+	if err != nil {
+		return nil, err
+	}
+	if speed <= 0 {
+		speed = 1
+	}
+
+	return &Beacon{
+		scenario:  s,
+		events:    events,
+		speed:     speed,
+		logger:    l,
+		emitted:   make(map[string][]beaconEvent),
+		listeners: make(map[string]map[chan struct{}]struct{}),
+	}, nil
+}
+
+// ListenAndServe serves the mock beacon nodes on addr and plays the scenario until ctx is cancelled.
+func (b *Beacon) ListenAndServe(ctx context.Context, addr string) error {
+	server := &http.Server{Addr: addr, Handler: b}
+
+	go func() {
+		<-ctx.Done()
+		_ = server.Shutdown(context.Background())
+	}()
+
+	go b.Run(ctx)
+
+	for _, node := range b.scenario.Nodes {
+		b.logger.Info("Mock beacon node ready", logger.Fields{"node": node, "beacon_url": fmt.Sprintf("http://%s/%s", addr, node)})
+	}
+
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// Run emits the scenario events on the scenario clock. It returns once every event is emitted.
+func (b *Beacon) Run(ctx context.Context) {
+	start := time.Now()
+
+	for _, event := range b.events {
+		wait := time.Until(start.Add(time.Duration(float64(event.at) / b.speed)))
+		if wait > 0 {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(wait):
+			}
+		}
+
+		b.emit(event)
+	}
+
+	b.logger.Info("Beacon scenario finished", logger.Fields{"events": len(b.events)})
+}
+
+func (b *Beacon) emit(event beaconEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.emitted[event.node] = append(b.emitted[event.node], event)
+	for listener := range b.listeners[event.node] {
+		select {
+		case listener <- struct{}{}:
+		default:
+		}
+	}
+
+	b.logger.Debug("Beacon event emitted", logger.Fields{"node": event.node, "topic": event.topic, "slot": event.slot})
+}
+
+// ServeHTTP serves the events stream and the lookups used by backfill.
+func (b *Beacon) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	node, path, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if !slices.Contains(b.scenario.Nodes, node) {
+		http.Error(w, fmt.Sprintf("unknown node %q", node), http.StatusNotFound)
+		return
+	}
+
+	switch {
+	case path == "eth/v1/events":
+		b.serveEvents(w, r, node)
+	case strings.HasPrefix(path, "eth/v1/beacon/inclusion_lists/"):
+		b.serveInclusionLists(w, node, strings.TrimPrefix(path, "eth/v1/beacon/inclusion_lists/"))
+	case strings.HasPrefix(path, "eth/v2/beacon/blocks/"):
+		b.serveBlock(w, node, strings.TrimPrefix(path, "eth/v2/beacon/blocks/"))
+	default:
+		writeBeaconError(w, http.StatusNotFound, "not found")
+	}
+}
+
+// serveEvents streams the node's events for the requested topics, resuming after Last-Event-ID
+func (b *Beacon) serveEvents(w http.ResponseWriter, r *http.Request, node string) {
+	topics := r.URL.Query()["topics"]
+	if len(topics) == 0 {
+		writeBeaconError(w, http.StatusBadRequest, "no topics requested")
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeBeaconError(w, http.StatusInternalServerError, "streaming unsupported")
+		return
+	}
+
+	// Without Last-Event-ID a subscriber only receives events from now on
+	b.mu.Lock()
+	next := len(b.emitted[node])
+	b.mu.Unlock()
+	if lastID, err := strconv.Atoi(r.Header.Get("Last-Event-ID")); err == nil && lastID >= 0 && lastID < next {
+		next = lastID
+	}
+
+	wake := make(chan struct{}, 1)
+	b.mu.Lock()
+	if b.listeners[node] == nil {
+		b.listeners[node] = make(map[chan struct{}]struct{})
+	}
+	b.listeners[node][wake] = struct{}{}
+	b.mu.Unlock()
+
+	defer func() {
+		b.mu.Lock()
+		delete(b.listeners[node], wake)
+		b.mu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		b.mu.Lock()
+		pending := b.emitted[node][next:]
+		b.mu.Unlock()
+
+		for _, event := range pending {
+			next++
+			if !slices.Contains(topics, event.topic) {
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", next, event.topic, event.data); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-wake:
+		}
+	}
+}
+
+// serveInclusionLists returns the lists a node has seen for a slot
+func (b *Beacon) serveInclusionLists(w http.ResponseWriter, node, slotParam string) {
+	slot, err := strconv.ParseUint(slotParam, 10, 64)
+	if err != nil {
+		writeBeaconError(w, http.StatusBadRequest, "invalid slot")
+		return
+	}
+
+	b.mu.Lock()
+	lists := []json.RawMessage{}
+	for _, event := range b.emitted[node] {
+		if event.topic != topicInclusionList || event.slot != slot {
+			continue
+		}
+		var msg struct {
+			Data json.RawMessage `json:"data"`
+		}
+		if json.Unmarshal(event.data, &msg) == nil {
+			lists = append(lists, msg.Data)
+		}
+	}
+	b.mu.Unlock()
+
+	writeBeaconJSON(w, map[string]any{"data": lists})
+}
+
+// serveBlock returns the execution payload number of a slot's block, 404 when the slot was missed
+func (b *Beacon) serveBlock(w http.ResponseWriter, node, slotParam string) {
+	slot, err := strconv.ParseUint(slotParam, 10, 64)
+	if err != nil {
+		writeBeaconError(w, http.StatusBadRequest, "invalid block id")
+		return
+	}
+
+	b.mu.Lock()
+	seen := slices.ContainsFunc(b.emitted[node], func(e beaconEvent) bool {
+		return e.topic == topicBlock && e.slot == slot
+	})
+	b.mu.Unlock()
+
+	var spec *SlotSpec
+	for i := range b.scenario.Slots {
+		if b.scenario.Slots[i].Slot == slot {
+			spec = &b.scenario.Slots[i]
+		}
+	}
+	if !seen || spec == nil || spec.ExecutionBlock == nil {
+		writeBeaconError(w, http.StatusNotFound, "block not found")
+		return
+	}
+
+	writeBeaconJSON(w, map[string]any{
+		"version": "electra",
+		"data": map[string]any{
+			"message": map[string]any{
+				"slot": strconv.FormatUint(slot, 10),
+				"body": map[string]any{
+					"execution_payload": map[string]any{
+						"block_number": strconv.FormatUint(*spec.ExecutionBlock, 10),
+					},
+				},
+			},
+		},
+	})
+}
+
+func writeBeaconJSON(w http.ResponseWriter, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func writeBeaconError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]any{"code": status, "message": message})
+}
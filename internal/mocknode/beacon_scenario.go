@@ -0,0 +1,249 @@
+package mocknode
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"txpool-viz/internal/model"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	defaultSlotDuration       = 12 * time.Second
+	defaultInclusionListDelay = 8 * time.Second
+
+	topicBlock         = "block"
+	topicInclusionList = "inclusion_list"
+)
+
+// BeaconScenario scripts the inclusion lists and blocks announced by the mock beacon nodes
+type BeaconScenario struct {
+	SlotDuration       time.Duration `yaml:"slot_duration"`
+	InclusionListDelay time.Duration `yaml:"inclusion_list_delay"` // default offset of lists into their slot
+	ExecutionScenario  string        `yaml:"execution_scenario"`   // mocknode scenario whose tx ids lists may reference
+	Nodes              []string      `yaml:"nodes"`
+	Slots              []SlotSpec    `yaml:"slots"`
+}
+
+// SlotSpec describes what the beacon nodes see in a slot
+type SlotSpec struct {
+	Slot           uint64              `yaml:"slot"`
+	Missed         bool                `yaml:"missed"`          // no block event is emitted
+	ExecutionBlock *uint64             `yaml:"execution_block"` // execution payload number served for backfill
+	InclusionLists []InclusionListSpec `yaml:"inclusion_lists"`
+}
+
+// InclusionListSpec is a list published by a validator. Publishing two lists for the same slot is an equivocation.
+type InclusionListSpec struct {
+	ValidatorIndex uint64                   `yaml:"validator_index"`
+	Txs            []string                 `yaml:"txs"`         // execution scenario tx ids or raw 0x encoded txs
+	Delay          *time.Duration           `yaml:"delay"`       // offset into the slot
+	Nodes          []string                 `yaml:"nodes"`       // defaults to every node
+	NodeDelays     map[string]time.Duration `yaml:"node_delays"` // extra propagation delay per node
+	CommitteeRoot  string                   `yaml:"committee_root"`
+	Signature      string                   `yaml:"signature"`
+}
+
+// beaconEvent is a scheduled SSE event
+type beaconEvent struct {
+	at    time.Duration
+	node  string
+	slot  uint64
+	topic string
+	data  json.RawMessage
+}
+
+// LoadBeaconScenario reads and validates a beacon scenario file.
+func LoadBeaconScenario(path string) (*BeaconScenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var s BeaconScenario
+	if err := yaml.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("error parsing beacon scenario: %w", err)
+	}
+
+	// The execution scenario is resolved next to the beacon scenario
+	if s.ExecutionScenario != "" && !filepath.IsAbs(s.ExecutionScenario) {
+		s.ExecutionScenario = filepath.Join(filepath.Dir(path), s.ExecutionScenario)
+	}
+
+	if err := s.validate(); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (s *BeaconScenario) validate() error {
+	if len(s.Nodes) == 0 {
+		return fmt.Errorf("beacon scenario needs at least one node")
+	}
+	if s.SlotDuration <= 0 {
+		s.SlotDuration = defaultSlotDuration
+	}
+	if s.InclusionListDelay <= 0 {
+		s.InclusionListDelay = defaultInclusionListDelay
+	}
+
+	slices.SortStableFunc(s.Slots, func(a, b SlotSpec) int {
+		return cmp.Compare(a.Slot, b.Slot)
+	})
+
+	for _, slot := range s.Slots {
+		for i, list := range slot.InclusionLists {
+			for _, node := range list.Nodes {
+				if !slices.Contains(s.Nodes, node) {
+					return fmt.Errorf("slot %d list %d: unknown node %q", slot.Slot, i, node)
+				}
+			}
+			for node := range list.NodeDelays {
+				if !slices.Contains(s.Nodes, node) {
+					return fmt.Errorf("slot %d list %d: unknown node %q", slot.Slot, i, node)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// events builds the SSE timeline of every node. Slot n starts n slot durations after the scenario starts.
+func (s *BeaconScenario) events() ([]beaconEvent, error) {
+	txs, err := s.executionTransactions()
+	if err != nil {
+		return nil, err
+	}
+
+	var events []beaconEvent
+	for _, slot := range s.Slots {
+		slotStart := time.Duration(slot.Slot) * s.SlotDuration
+		slotNumber := strconv.FormatUint(slot.Slot, 10)
+
+		if !slot.Missed {
+			data, _ := json.Marshal(map[string]any{
+				"slot":                 slotNumber,
+				"block":                blockRoot(slot.Slot),
+				"execution_optimistic": false,
+			})
+			for _, node := range s.Nodes {
+				events = append(events, beaconEvent{at: slotStart, node: node, slot: slot.Slot, topic: topicBlock, data: data})
+			}
+		}
+
+		for i, list := range slot.InclusionLists {
+			encoded, err := encodeTransactions(list.Txs, txs)
+			if err != nil {
+				return nil, fmt.Errorf("slot %d list %d: %w", slot.Slot, i, err)
+			}
+
+			data, _ := json.Marshal(model.MempoolMessage{
+				Version: "eip7805",
+				Data: model.Data{
+					Message: model.SSEMessage{
+						Slot:                       slotNumber,
+						ValidatorIndex:             strconv.FormatUint(list.ValidatorIndex, 10),
+						InclusionListCommitteeRoot: orZeroHex(list.CommitteeRoot, common.HashLength),
+						Transactions:               encoded,
+					},
+					Signature: orZeroHex(list.Signature, 96),
+				},
+			})
+
+			delay := s.InclusionListDelay
+			if list.Delay != nil {
+				delay = *list.Delay
+			}
+
+			nodes := list.Nodes
+			if len(nodes) == 0 {
+				nodes = s.Nodes
+			}
+			for _, node := range nodes {
+				at := slotStart + delay + list.NodeDelays[node]
+				events = append(events, beaconEvent{at: at, node: node, slot: slot.Slot, topic: topicInclusionList, data: data})
+			}
+		}
+	}
+
+	slices.SortStableFunc(events, func(a, b beaconEvent) int {
+		return cmp.Compare(a.at, b.at)
+	})
+	return events, nil
+}
+
+// executionTransactions signs every tx of the execution scenario so lists can reference them by id
+func (s *BeaconScenario) executionTransactions() (map[string]*types.Transaction, error) {
+	if s.ExecutionScenario == "" {
+		return nil, nil
+	}
+
+	execution, err := LoadScenario(s.ExecutionScenario)
+	if err != nil {
+		return nil, fmt.Errorf("execution scenario: %w", err)
+	}
+	return execution.SignedTransactions()
+}
+
+// SignedTransactions returns the signed tx of every send and replace step by id.
+func (s *Scenario) SignedTransactions() (map[string]*types.Transaction, error) {
+	c := newChain(s, time.Now())
+
+	txs := make(map[string]*types.Transaction)
+	for _, step := range s.Steps {
+		if step.Tx == nil {
+			continue
+		}
+		ptx, err := c.sign(step.Tx)
+		if err != nil {
+			return nil, err
+		}
+		txs[step.Tx.ID] = ptx.tx
+	}
+	return txs, nil
+}
+
+func encodeTransactions(refs []string, txs map[string]*types.Transaction) ([]string, error) {
+	encoded := make([]string, 0, len(refs))
+	for _, ref := range refs {
+		if strings.HasPrefix(ref, "0x") {
+			encoded = append(encoded, ref)
+			continue
+		}
+
+		tx, ok := txs[ref]
+		if !ok {
+			return nil, fmt.Errorf("unknown tx %q", ref)
+		}
+		raw, err := tx.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		encoded = append(encoded, hexutil.Encode(raw))
+	}
+	return encoded, nil
+}
+
+// blockRoot is a stand in beacon block root, unique per slot
+func blockRoot(slot uint64) common.Hash {
+	return crypto.Keccak256Hash([]byte("txpool-viz mockbeacon block " + strconv.FormatUint(slot, 10)))
+}
+
+func orZeroHex(value string, size int) string {
+	if value != "" {
+		return value
+	}
+	return hexutil.Encode(make([]byte, size))
+}
@@ -0,0 +1,711 @@
+package mocknode
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/holiman/uint256"
+)
+
+// blobBaseFee is the blob base fee of every block, the excess blob gas staying at zero
+var blobBaseFee = big.NewInt(params.BlobTxMinBlobGasprice)
+
+// pooledTx is a signed scenario tx
+type pooledTx struct {
+	id   string
+	tx   *types.Transaction
+	from common.Address
+}
+
+// minedTx locates a tx in the chain
+type minedTx struct {
+	pooledTx
+	block   *types.Block
+	index   uint
+	receipt *types.Receipt
+}
+
+// chain holds the canonical chain shared by every mock client and each client's own mempool
+type chain struct {
+	mu      sync.RWMutex
+	signer  types.Signer
+	baseFee *big.Int
+	balance *big.Int // every account's balance before the mined txs
+
+	blocks []*types.Block
+	nonces map[common.Address]uint64
+	pools  map[string]map[common.Hash]*pooledTx // client -> tx hash -> tx
+	byID   map[string]*pooledTx
+	mined  map[common.Hash]*minedTx
+}
+
+func newChain(s *Scenario, genesisTime time.Time) *chain {
+	baseFee, _ := parseAmount(s.BaseFee)
+	balance, _ := parseAmount(s.Balance)
+
+	c := &chain{
+		signer:  types.LatestSignerForChainID(new(big.Int).SetUint64(s.ChainID)),
+		baseFee: baseFee,
+		balance: balance,
+		nonces:  make(map[common.Address]uint64),
+		pools:   make(map[string]map[common.Hash]*pooledTx),
+		byID:    make(map[string]*pooledTx),
+		mined:   make(map[common.Hash]*minedTx),
+	}
+	for _, client := range s.Clients {
+		c.pools[client] = make(map[common.Hash]*pooledTx)
+	}
+
+	genesis := types.NewBlock(&types.Header{
+		Number:     new(big.Int),
+		Difficulty: new(big.Int),
+		GasLimit:   defaultGasLimit,
+		Time:       uint64(genesisTime.Unix()),
+		BaseFee:    baseFee,
+	}, nil, nil, trie.NewStackTrie(nil))
+	c.blocks = append(c.blocks, genesis)
+
+	return c
+}
+
+// sign builds and signs the tx described by spec
+func (c *chain) sign(spec *TxSpec) (*pooledTx, error) {
+	value, err := parseAmount(spec.Value)
+	if err != nil {
+		return nil, fmt.Errorf("tx %s value: %w", spec.ID, err)
+	}
+
+	var data []byte
+	if spec.Data != "" {
+		if data, err = hexutil.Decode(spec.Data); err != nil {
+			return nil, fmt.Errorf("tx %s data: %w", spec.ID, err)
+		}
+	}
+
+	gas := spec.Gas
+	if gas == 0 {
+		gas = defaultGas
+	}
+
+	var to *common.Address
+	if spec.To != "" {
+		addr := AccountAddress(spec.To)
+		to = &addr
+	}
+
+	var inner types.TxData
+	if spec.Blobs > 0 {
+		if inner, err = c.blobTx(spec, *to, value, gas, data); err != nil {
+			return nil, err
+		}
+	} else if len(spec.Authorize) > 0 {
+		if inner, err = c.setCodeTx(spec, *to, value, gas, data); err != nil {
+			return nil, err
+		}
+	} else if spec.GasPrice != "" {
+		gasPrice, err := parseAmount(spec.GasPrice)
+		if err != nil {
+			return nil, fmt.Errorf("tx %s gas_price: %w", spec.ID, err)
+		}
+		inner = &types.LegacyTx{Nonce: spec.Nonce, GasPrice: gasPrice, Gas: gas, To: to, Value: value, Data: data}
+	} else {
+		tip, err := parseAmount(spec.Tip)
+		if err != nil {
+			return nil, fmt.Errorf("tx %s tip: %w", spec.ID, err)
+		}
+		feeCap, err := parseAmount(spec.FeeCap)
+		if err != nil {
+			return nil, fmt.Errorf("tx %s fee_cap: %w", spec.ID, err)
+		}
+		if spec.FeeCap == "" {
+			feeCap = new(big.Int).Add(new(big.Int).Mul(c.baseFee, big.NewInt(2)), tip)
+		}
+		inner = &types.DynamicFeeTx{
+			ChainID:   c.signer.ChainID(),
+			Nonce:     spec.Nonce,
+			GasTipCap: tip,
+			GasFeeCap: feeCap,
+			Gas:       gas,
+			To:        to,
+			Value:     value,
+			Data:      data,
+		}
+	}
+
+	if len(spec.AccessList) > 0 {
+		accessList, err := accessListOf(spec)
+		if err != nil {
+			return nil, err
+		}
+		switch inner := inner.(type) {
+		case *types.DynamicFeeTx:
+			inner.AccessList = accessList
+		case *types.BlobTx:
+			inner.AccessList = accessList
+		case *types.SetCodeTx:
+			inner.AccessList = accessList
+		}
+	}
+
+	tx, err := types.SignNewTx(AccountKey(spec.From), c.signer, inner)
+	if err != nil {
+		return nil, fmt.Errorf("tx %s: %w", spec.ID, err)
+	}
+
+	return &pooledTx{id: spec.ID, tx: tx, from: AccountAddress(spec.From)}, nil
+}
+
+// accessListOf builds the access list of spec.AccessList
+func accessListOf(spec *TxSpec) (types.AccessList, error) {
+	accessList := make(types.AccessList, 0, len(spec.AccessList))
+	for _, tuple := range spec.AccessList {
+		entry := types.AccessTuple{Address: AccountAddress(tuple.Address), StorageKeys: []common.Hash{}}
+		for _, key := range tuple.StorageKeys {
+			raw, err := hexutil.Decode(key)
+			if err != nil || len(raw) > common.HashLength {
+				return nil, fmt.Errorf("tx %s access list key %q: not a hex word", spec.ID, key)
+			}
+			entry.StorageKeys = append(entry.StorageKeys, common.BytesToHash(raw))
+		}
+		accessList = append(accessList, entry)
+	}
+	return accessList, nil
+}
+
+// blobTx builds a blob tx carrying spec.Blobs empty blobs along with their sidecar
+func (c *chain) blobTx(spec *TxSpec, to common.Address, value *big.Int, gas uint64, data []byte) (*types.BlobTx, error) {
+	tip, err := parseAmount(spec.Tip)
+	if err != nil {
+		return nil, fmt.Errorf("tx %s tip: %w", spec.ID, err)
+	}
+	feeCap, err := parseAmount(spec.FeeCap)
+	if err != nil {
+		return nil, fmt.Errorf("tx %s fee_cap: %w", spec.ID, err)
+	}
+	if spec.FeeCap == "" {
+		feeCap = new(big.Int).Add(new(big.Int).Mul(c.baseFee, big.NewInt(2)), tip)
+	}
+	blobFeeCap := spec.BlobFeeCap
+	if blobFeeCap == "" {
+		blobFeeCap = defaultBlobFee
+	}
+	blobFee, err := parseAmount(blobFeeCap)
+	if err != nil {
+		return nil, fmt.Errorf("tx %s blob_fee_cap: %w", spec.ID, err)
+	}
+
+	sidecar := &types.BlobTxSidecar{}
+	for range spec.Blobs {
+		var blob kzg4844.Blob
+		commitment, err := kzg4844.BlobToCommitment(&blob)
+		if err != nil {
+			return nil, fmt.Errorf("tx %s blob commitment: %w", spec.ID, err)
+		}
+		proof, err := kzg4844.ComputeBlobProof(&blob, commitment)
+		if err != nil {
+			return nil, fmt.Errorf("tx %s blob proof: %w", spec.ID, err)
+		}
+		sidecar.Blobs = append(sidecar.Blobs, blob)
+		sidecar.Commitments = append(sidecar.Commitments, commitment)
+		sidecar.Proofs = append(sidecar.Proofs, proof)
+	}
+
+	return &types.BlobTx{
+		ChainID:    uint256.MustFromBig(c.signer.ChainID()),
+		Nonce:      spec.Nonce,
+		GasTipCap:  uint256.MustFromBig(tip),
+		GasFeeCap:  uint256.MustFromBig(feeCap),
+		Gas:        gas,
+		To:         to,
+		Value:      uint256.MustFromBig(value),
+		Data:       data,
+		BlobFeeCap: uint256.MustFromBig(blobFee),
+		BlobHashes: sidecar.BlobHashes(),
+		Sidecar:    sidecar,
+	}, nil
+}
+
+// setCodeTx builds a set code tx carrying the authorizations of spec.Authorize
+func (c *chain) setCodeTx(spec *TxSpec, to common.Address, value *big.Int, gas uint64, data []byte) (*types.SetCodeTx, error) {
+	tip, err := parseAmount(spec.Tip)
+	if err != nil {
+		return nil, fmt.Errorf("tx %s tip: %w", spec.ID, err)
+	}
+	feeCap, err := parseAmount(spec.FeeCap)
+	if err != nil {
+		return nil, fmt.Errorf("tx %s fee_cap: %w", spec.ID, err)
+	}
+	if spec.FeeCap == "" {
+		feeCap = new(big.Int).Add(new(big.Int).Mul(c.baseFee, big.NewInt(2)), tip)
+	}
+
+	chainID := uint256.MustFromBig(c.signer.ChainID())
+	auths := make([]types.SetCodeAuthorization, 0, len(spec.Authorize))
+	for _, auth := range spec.Authorize {
+		signed, err := types.SignSetCode(AccountKey(auth.Account), types.SetCodeAuthorization{
+			ChainID: *chainID,
+			Address: AccountAddress(auth.Delegate),
+			Nonce:   auth.Nonce,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("tx %s authorization by %s: %w", spec.ID, auth.Account, err)
+		}
+		auths = append(auths, signed)
+	}
+
+	return &types.SetCodeTx{
+		ChainID:   chainID,
+		Nonce:     spec.Nonce,
+		GasTipCap: uint256.MustFromBig(tip),
+		GasFeeCap: uint256.MustFromBig(feeCap),
+		Gas:       gas,
+		To:        to,
+		Value:     uint256.MustFromBig(value),
+		Data:      data,
+		AuthList:  auths,
+	}, nil
+}
+
+// send adds a tx to the clients' mempools, replacing any tx with the same sender and nonce.
+// It returns whether a tx was replaced in any of them.
+func (c *chain) send(clients []string, ptx *pooledTx) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.byID[ptx.id] = ptx
+
+	replaced := false
+	for _, client := range clients {
+		pool := c.pools[client]
+		for hash, existing := range pool {
+			if existing.from == ptx.from && existing.tx.Nonce() == ptx.tx.Nonce() {
+				delete(pool, hash)
+				replaced = true
+			}
+		}
+		pool[ptx.tx.Hash()] = ptx
+	}
+	return replaced
+}
+
+// hasNonce reports whether any mempool holds a tx from the sender with the nonce
+func (c *chain) hasNonce(from common.Address, nonce uint64) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for _, pool := range c.pools {
+		for _, ptx := range pool {
+			if ptx.from == from && ptx.tx.Nonce() == nonce {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (c *chain) drop(clients []string, ids []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, id := range ids {
+		ptx, ok := c.byID[id]
+		if !ok {
+			continue
+		}
+		for _, client := range clients {
+			delete(c.pools[client], ptx.tx.Hash())
+		}
+	}
+}
+
+// mine appends a block with the given txs, or every executable tx when ids is empty,
+// and evicts txs whose nonce is now used from every mempool
+func (c *chain) mine(ids []string, at time.Time) *types.Block {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(ids) > 0 {
+		return c.appendBlock(c.byIDs(ids), at, nil)
+	}
+	return c.appendBlock(c.executable(), at, nil)
+}
+
+// reorg replaces the last depth blocks with depth+1 new ones, the first holding the txs of ids.
+// Txs of the orphaned blocks return to every mempool.
+func (c *chain) reorg(depth int, ids []string, at time.Time) []*types.Block {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	depth = min(depth, len(c.blocks)-1)
+	orphaned := c.blocks[len(c.blocks)-depth:]
+	c.blocks = c.blocks[:len(c.blocks)-depth]
+
+	for _, block := range orphaned {
+		for _, tx := range block.Transactions() {
+			mined, ok := c.mined[tx.Hash()]
+			if !ok {
+				continue
+			}
+			delete(c.mined, tx.Hash())
+			for _, pool := range c.pools {
+				ptx := mined.pooledTx
+				pool[tx.Hash()] = &ptx
+			}
+		}
+	}
+
+	c.nonces = make(map[common.Address]uint64)
+	for _, mined := range c.mined {
+		if mined.tx.Nonce() >= c.nonces[mined.from] {
+			c.nonces[mined.from] = mined.tx.Nonce() + 1
+		}
+	}
+
+	// The extra data sets the replacements apart from the orphaned blocks
+	blocks := []*types.Block{c.appendBlock(c.byIDs(ids), at, []byte("reorg"))}
+	for range depth {
+		blocks = append(blocks, c.appendBlock(nil, at, []byte("reorg")))
+	}
+	return blocks
+}
+
+// byIDs returns the unmined txs of ids
+func (c *chain) byIDs(ids []string) []*pooledTx {
+	var txs []*pooledTx
+	for _, id := range ids {
+		if ptx, ok := c.byID[id]; ok && c.mined[ptx.tx.Hash()] == nil {
+			txs = append(txs, ptx)
+		}
+	}
+	return txs
+}
+
+// appendBlock mines txs on top of the head and evicts txs whose nonce is now used from every
+// mempool. Callers hold mu.
+func (c *chain) appendBlock(txs []*pooledTx, at time.Time, extra []byte) *types.Block {
+	parent := c.blocks[len(c.blocks)-1]
+	timestamp := uint64(at.Unix())
+	if timestamp <= parent.Time() {
+		timestamp = parent.Time() + 1
+	}
+
+	header := &types.Header{
+		ParentHash: parent.Hash(),
+		Number:     new(big.Int).Add(parent.Number(), big.NewInt(1)),
+		Difficulty: new(big.Int),
+		GasLimit:   defaultGasLimit,
+		Time:       timestamp,
+		BaseFee:    c.baseFee,
+		Extra:      extra,
+	}
+	var blobGasUsed, excessBlobGas uint64
+	header.BlobGasUsed = &blobGasUsed
+	header.ExcessBlobGas = &excessBlobGas
+
+	transactions := make([]*types.Transaction, 0, len(txs))
+	receipts := make([]*types.Receipt, 0, len(txs))
+	var cumulativeGas uint64
+	for i, ptx := range txs {
+		cumulativeGas += ptx.tx.Gas()
+		blobGasUsed += ptx.tx.BlobGas()
+		// Blocks carry blob txs without their sidecars
+		transactions = append(transactions, ptx.tx.WithoutBlobTxSidecar())
+		receipts = append(receipts, &types.Receipt{
+			Type:              ptx.tx.Type(),
+			Status:            types.ReceiptStatusSuccessful,
+			CumulativeGasUsed: cumulativeGas,
+			Logs:              []*types.Log{},
+			TxHash:            ptx.tx.Hash(),
+			GasUsed:           ptx.tx.Gas(),
+			EffectiveGasPrice: effectiveGasPrice(ptx.tx, c.baseFee),
+			BlobGasUsed:       ptx.tx.BlobGas(),
+			BlobGasPrice:      blobBaseFee,
+			BlockNumber:       header.Number,
+			TransactionIndex:  uint(i),
+		})
+	}
+	header.GasUsed = cumulativeGas
+
+	block := types.NewBlock(header, &types.Body{Transactions: transactions}, receipts, trie.NewStackTrie(nil))
+	c.blocks = append(c.blocks, block)
+
+	for i, ptx := range txs {
+		receipts[i].BlockHash = block.Hash()
+		c.mined[ptx.tx.Hash()] = &minedTx{pooledTx: *ptx, block: block, index: uint(i), receipt: receipts[i]}
+		if ptx.tx.Nonce() >= c.nonces[ptx.from] {
+			c.nonces[ptx.from] = ptx.tx.Nonce() + 1
+		}
+	}
+
+	// Mined txs and txs they replaced leave every mempool
+	for _, pool := range c.pools {
+		for hash, ptx := range pool {
+			if ptx.tx.Nonce() < c.nonces[ptx.from] {
+				delete(pool, hash)
+			}
+		}
+	}
+
+	return block
+}
+
+// executable picks, per sender, the best tx for each consecutive nonce across all mempools
+func (c *chain) executable() []*pooledTx {
+	best := make(map[common.Address]map[uint64]*pooledTx)
+	for _, pool := range c.pools {
+		for _, ptx := range pool {
+			byNonce := best[ptx.from]
+			if byNonce == nil {
+				byNonce = make(map[uint64]*pooledTx)
+				best[ptx.from] = byNonce
+			}
+			current := byNonce[ptx.tx.Nonce()]
+			if current == nil || ptx.tx.GasTipCapIntCmp(current.tx.GasTipCap()) > 0 {
+				byNonce[ptx.tx.Nonce()] = ptx
+			}
+		}
+	}
+
+	senders := make([]common.Address, 0, len(best))
+	for from := range best {
+		senders = append(senders, from)
+	}
+	sort.Slice(senders, func(i, j int) bool { return senders[i].Cmp(senders[j]) < 0 })
+
+	var txs []*pooledTx
+	for _, from := range senders {
+		for nonce := c.nonces[from]; best[from][nonce] != nil; nonce++ {
+			txs = append(txs, best[from][nonce])
+		}
+	}
+	return txs
+}
+
+func effectiveGasPrice(tx *types.Transaction, baseFee *big.Int) *big.Int {
+	if tx.Type() == types.LegacyTxType {
+		return tx.GasPrice()
+	}
+	price := new(big.Int).Add(baseFee, tx.GasTipCap())
+	if price.Cmp(tx.GasFeeCap()) > 0 {
+		return new(big.Int).Set(tx.GasFeeCap())
+	}
+	return price
+}
+
+func (c *chain) head() *types.Block {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.blocks[len(c.blocks)-1]
+}
+
+// blockByNumber resolves a block tag or hex number, returning nil when unknown
+func (c *chain) blockByNumber(tag string) *types.Block {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	switch tag {
+	case "latest", "pending", "safe", "finalized", "":
+		return c.blocks[len(c.blocks)-1]
+	case "earliest":
+		return c.blocks[0]
+	}
+
+	number, err := hexutil.DecodeUint64(tag)
+	if err != nil || number >= uint64(len(c.blocks)) {
+		return nil
+	}
+	return c.blocks[number]
+}
+
+func (c *chain) blockByHash(hash common.Hash) *types.Block {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for _, block := range c.blocks {
+		if block.Hash() == hash {
+			return block
+		}
+	}
+	return nil
+}
+
+// transaction returns the RPC representation of a tx as seen by a client, or nil when the client does not know it
+func (c *chain) transaction(client string, hash common.Hash) map[string]any {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if mined, ok := c.mined[hash]; ok {
+		return rpcTransaction(mined.tx.WithoutBlobTxSidecar(), mined.from, mined.block, mined.index, c.baseFee)
+	}
+	if ptx, ok := c.pools[client][hash]; ok {
+		// Like real clients, the sidecar is only served by the engine API
+		return rpcTransaction(ptx.tx.WithoutBlobTxSidecar(), ptx.from, nil, 0, c.baseFee)
+	}
+	return nil
+}
+
+// blobsAndProofs answers engine_getBlobsV1 from a client's pool, nil for each blob it doesn't hold
+func (c *chain) blobsAndProofs(client string, hashes []common.Hash) []map[string]hexutil.Bytes {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	results := make([]map[string]hexutil.Bytes, len(hashes))
+	for _, ptx := range c.pools[client] {
+		sidecar := ptx.tx.BlobTxSidecar()
+		if sidecar == nil {
+			continue
+		}
+		for i, blobHash := range sidecar.BlobHashes() {
+			for j, hash := range hashes {
+				if hash == blobHash {
+					results[j] = map[string]hexutil.Bytes{"blob": sidecar.Blobs[i][:], "proof": sidecar.Proofs[i][:]}
+				}
+			}
+		}
+	}
+	return results
+}
+
+func (c *chain) receipt(hash common.Hash) *types.Receipt {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if mined, ok := c.mined[hash]; ok {
+		return mined.receipt
+	}
+	return nil
+}
+
+func (c *chain) nonce(addr common.Address, pending bool, client string) uint64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	nonce := c.nonces[addr]
+	if !pending {
+		return nonce
+	}
+	for {
+		found := false
+		for _, ptx := range c.pools[client] {
+			if ptx.from == addr && ptx.tx.Nonce() == nonce {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nonce
+		}
+		nonce++
+	}
+}
+
+// balanceOf is the starting balance of an account with the value and fees of the mined txs applied
+func (c *chain) balanceOf(addr common.Address) *big.Int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	balance := new(big.Int).Set(c.balance)
+	for _, mined := range c.mined {
+		if to := mined.tx.To(); to != nil && *to == addr {
+			balance.Add(balance, mined.tx.Value())
+		}
+		if mined.from == addr {
+			fee := new(big.Int).Mul(new(big.Int).SetUint64(mined.receipt.GasUsed), mined.receipt.EffectiveGasPrice)
+			balance.Sub(balance, fee)
+			balance.Sub(balance, new(big.Int).Mul(new(big.Int).SetUint64(mined.receipt.BlobGasUsed), blobBaseFee))
+			balance.Sub(balance, mined.tx.Value())
+		}
+	}
+	return balance
+}
+
+// txpoolContent splits a client's mempool into executable and nonce gapped txs like txpool_content
+func (c *chain) txpoolContent(client string) map[string]map[common.Address]map[string]map[string]any {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	bySender := make(map[common.Address]map[uint64]*pooledTx)
+	for _, ptx := range c.pools[client] {
+		if bySender[ptx.from] == nil {
+			bySender[ptx.from] = make(map[uint64]*pooledTx)
+		}
+		bySender[ptx.from][ptx.tx.Nonce()] = ptx
+	}
+
+	content := map[string]map[common.Address]map[string]map[string]any{
+		"pending": {},
+		"queued":  {},
+	}
+	for from, byNonce := range bySender {
+		next := c.nonces[from]
+		for byNonce[next] != nil {
+			next++
+		}
+
+		for nonce, ptx := range byNonce {
+			status := "pending"
+			if nonce >= next {
+				status = "queued"
+			}
+			if content[status][from] == nil {
+				content[status][from] = make(map[string]map[string]any)
+			}
+			content[status][from][strconv.FormatUint(nonce, 10)] = rpcTransaction(ptx.tx, ptx.from, nil, 0, c.baseFee)
+		}
+	}
+	return content
+}
+
+// rpcTransaction renders a tx the way eth_getTransactionByHash does
+func rpcTransaction(tx *types.Transaction, from common.Address, block *types.Block, index uint, baseFee *big.Int) map[string]any {
+	fields := make(map[string]any)
+	raw, _ := tx.MarshalJSON()
+	_ = json.Unmarshal(raw, &fields)
+
+	fields["from"] = from
+	fields["blockHash"] = nil
+	fields["blockNumber"] = nil
+	fields["transactionIndex"] = nil
+
+	if block != nil {
+		fields["blockHash"] = block.Hash()
+		fields["blockNumber"] = (*hexutil.Big)(block.Number())
+		fields["transactionIndex"] = hexutil.Uint64(index)
+		fields["gasPrice"] = (*hexutil.Big)(effectiveGasPrice(tx, baseFee))
+	}
+	return fields
+}
+
+// rpcBlock renders a block the way eth_getBlockByNumber does
+func (c *chain) rpcBlock(block *types.Block, fullTx bool) map[string]any {
+	fields := make(map[string]any)
+	raw, _ := json.Marshal(block.Header())
+	_ = json.Unmarshal(raw, &fields)
+
+	fields["size"] = hexutil.Uint64(block.Size())
+	fields["uncles"] = []common.Hash{}
+
+	txs := make([]any, 0, len(block.Transactions()))
+	for i, tx := range block.Transactions() {
+		if !fullTx {
+			txs = append(txs, tx.Hash())
+			continue
+		}
+		from, _ := types.Sender(c.signer, tx)
+		txs = append(txs, rpcTransaction(tx, from, block, uint(i), c.baseFee))
+	}
+	fields["transactions"] = txs
+
+	return fields
+}
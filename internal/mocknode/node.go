@@ -0,0 +1,517 @@
+package mocknode
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"txpool-viz/internal/logger"
+
+	"github.com/coder/websocket"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+const (
+	subscriptionNewPendingTxs = "newPendingTransactions"
+	subscriptionNewHeads      = "newHeads"
+
+	// Notifications buffered per websocket before a slow reader is disconnected
+	subscriptionBuffer = 4096
+)
+
+// Node serves a mock execution client per scenario client on /<client>, over HTTP and websocket.
+type Node struct {
+	scenario *Scenario
+	chain    *chain
+	speed    float64
+	logger   logger.Logger
+
+	mu      sync.Mutex
+	subs    map[string]map[*wsConn]struct{} // client -> live websocket connections
+	filters map[string]*pendingFilter       // filter id -> pending tx filter
+	nextID  atomic.Uint64
+}
+
+// pendingFilter collects the pending tx hashes of a client between eth_getFilterChanges calls
+type pendingFilter struct {
+	client string
+	hashes []common.Hash
+}
+
+type rpcRequest struct {
+	ID     json.RawMessage   `json:"id"`
+	Method string            `json:"method"`
+	Params []json.RawMessage `json:"params"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type rpcResponse struct {
+	Jsonrpc string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+// wsConn is a websocket connection and the subscriptions opened on it
+type wsConn struct {
+	out  chan []byte
+	mu   sync.Mutex
+	subs map[string]string // subscription id -> kind
+}
+
+// New builds a Node for a scenario. speed 1 plays the scenario in real time, higher values accelerate.
+func New(s *Scenario, speed float64, l logger.Logger) *Node {
+	if speed <= 0 {
+		speed = 1
+	}
+	return &Node{
+		scenario: s,
+		chain:    newChain(s, time.Now()),
+		speed:    speed,
+		logger:   l,
+		subs:     make(map[string]map[*wsConn]struct{}),
+		filters:  make(map[string]*pendingFilter),
+	}
+}
+
+// ListenAndServe serves the mock clients on addr and plays the scenario until ctx is cancelled.
+func (n *Node) ListenAndServe(ctx context.Context, addr string) error {
+	server := &http.Server{Addr: addr, Handler: n}
+
+	go func() {
+		<-ctx.Done()
+		_ = server.Shutdown(context.Background())
+	}()
+
+	go n.Run(ctx)
+
+	for _, client := range n.scenario.Clients {
+		n.logger.Info("Mock execution client ready", logger.Fields{
+			"client": client,
+			"rpc":    fmt.Sprintf("http://%s/%s", addr, client),
+			"socket": fmt.Sprintf("ws://%s/%s", addr, client),
+		})
+	}
+
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// Run applies the scenario steps on the scenario clock. It returns once every step is applied.
+func (n *Node) Run(ctx context.Context) {
+	start := time.Now()
+	genesis := time.Unix(int64(n.chain.head().Time()), 0)
+
+	for _, step := range n.scenario.Steps {
+		wait := time.Until(start.Add(time.Duration(float64(step.At) / n.speed)))
+		if wait > 0 {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(wait):
+			}
+		}
+
+		if err := n.apply(step, genesis.Add(step.At)); err != nil {
+			n.logger.Error("Scenario step failed", logger.Fields{"action": step.Action, "at": step.At.String(), "error": err.Error()})
+		}
+	}
+
+	n.logger.Info("Scenario finished", logger.Fields{"steps": len(n.scenario.Steps)})
+}
+
+func (n *Node) apply(step Step, at time.Time) error {
+	switch step.Action {
+	case ActionSend, ActionReplace:
+		ptx, err := n.chain.sign(step.Tx)
+		if err != nil {
+			return err
+		}
+
+		if step.Action == ActionReplace && !n.chain.hasNonce(ptx.from, ptx.tx.Nonce()) {
+			n.logger.Warn("Replacement has no tx to replace", logger.Fields{"tx": step.Tx.ID})
+		}
+
+		n.chain.send(step.Clients, ptx)
+		for _, client := range step.Clients {
+			n.notify(client, subscriptionNewPendingTxs, ptx.tx.Hash())
+			n.addToFilters(client, ptx.tx.Hash())
+		}
+		n.logger.Debug("Scenario tx sent", logger.Fields{"tx": step.Tx.ID, "hash": ptx.tx.Hash().Hex(), "clients": step.Clients})
+
+	case ActionDrop:
+		n.chain.drop(step.Clients, step.IDs)
+		n.logger.Debug("Scenario txs dropped", logger.Fields{"txs": step.IDs, "clients": step.Clients})
+
+	case ActionMine:
+		block := n.chain.mine(step.IDs, at)
+		for _, client := range n.scenario.Clients {
+			n.notify(client, subscriptionNewHeads, block.Header())
+		}
+		n.logger.Debug("Scenario block mined", logger.Fields{"number": block.NumberU64(), "txs": len(block.Transactions())})
+
+	case ActionReorg:
+		blocks := n.chain.reorg(step.Depth, step.IDs, at)
+		for _, block := range blocks {
+			for _, client := range n.scenario.Clients {
+				n.notify(client, subscriptionNewHeads, block.Header())
+			}
+		}
+		n.logger.Debug("Scenario reorg", logger.Fields{"depth": step.Depth, "head": blocks[len(blocks)-1].NumberU64()})
+	}
+	return nil
+}
+
+// ServeHTTP answers JSON-RPC over HTTP and upgrades websocket requests.
+func (n *Node) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	client := strings.Trim(r.URL.Path, "/")
+	if !slices.Contains(n.scenario.Clients, client) {
+		http.Error(w, fmt.Sprintf("unknown client %q", client), http.StatusNotFound)
+		return
+	}
+
+	if strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+		n.serveWebsocket(w, r, client)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(n.handleBody(client, body, nil))
+}
+
+// handleBody answers a single or batched JSON-RPC body. conn is nil over HTTP.
+func (n *Node) handleBody(client string, body []byte, conn *wsConn) []byte {
+	trimmed := strings.TrimSpace(string(body))
+	if strings.HasPrefix(trimmed, "[") {
+		var batch []rpcRequest
+		if err := json.Unmarshal(body, &batch); err != nil {
+			resp, _ := json.Marshal(rpcResponse{Jsonrpc: "2.0", ID: json.RawMessage("null"), Error: &rpcError{Code: -32700, Message: err.Error()}})
+			return resp
+		}
+
+		responses := make([]rpcResponse, 0, len(batch))
+		for _, req := range batch {
+			responses = append(responses, n.handle(client, req, conn))
+		}
+		resp, _ := json.Marshal(responses)
+		return resp
+	}
+
+	var req rpcRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		resp, _ := json.Marshal(rpcResponse{Jsonrpc: "2.0", ID: json.RawMessage("null"), Error: &rpcError{Code: -32700, Message: err.Error()}})
+		return resp
+	}
+	resp, _ := json.Marshal(n.handle(client, req, conn))
+	return resp
+}
+
+func (n *Node) handle(client string, req rpcRequest, conn *wsConn) rpcResponse {
+	result, err := n.call(client, req, conn)
+	resp := rpcResponse{Jsonrpc: "2.0", ID: req.ID, Result: result}
+	if err != nil {
+		resp.Result = nil
+		resp.Error = err
+	}
+	return resp
+}
+
+func (n *Node) call(client string, req rpcRequest, conn *wsConn) (any, *rpcError) {
+	switch req.Method {
+	case "eth_chainId":
+		return hexutil.Uint64(n.scenario.ChainID), nil
+
+	case "net_version":
+		return fmt.Sprintf("%d", n.scenario.ChainID), nil
+
+	case "eth_blockNumber":
+		return hexutil.Uint64(n.chain.head().NumberU64()), nil
+
+	case "eth_getBlockByNumber", "eth_getBlockByHash":
+		var key string
+		var fullTx bool
+		if err := parseParams(req.Params, &key, &fullTx); err != nil {
+			return nil, err
+		}
+
+		block := n.chain.blockByNumber(key)
+		if req.Method == "eth_getBlockByHash" {
+			block = n.chain.blockByHash(common.HexToHash(key))
+		}
+		if block == nil {
+			return nil, nil
+		}
+		return n.chain.rpcBlock(block, fullTx), nil
+
+	case "eth_getTransactionByHash":
+		var hash common.Hash
+		if err := parseParams(req.Params, &hash); err != nil {
+			return nil, err
+		}
+		if tx := n.chain.transaction(client, hash); tx != nil {
+			return tx, nil
+		}
+		return nil, nil
+
+	case "engine_getBlobsV1":
+		var hashes []common.Hash
+		if err := parseParams(req.Params, &hashes); err != nil {
+			return nil, err
+		}
+		if slices.Contains(n.scenario.NoSidecars, client) {
+			return make([]any, len(hashes)), nil
+		}
+		return n.chain.blobsAndProofs(client, hashes), nil
+
+	case "eth_getTransactionReceipt":
+		var hash common.Hash
+		if err := parseParams(req.Params, &hash); err != nil {
+			return nil, err
+		}
+		if receipt := n.chain.receipt(hash); receipt != nil {
+			return receipt, nil
+		}
+		return nil, nil
+
+	case "eth_getTransactionCount":
+		var addr common.Address
+		var tag string
+		if err := parseParams(req.Params, &addr, &tag); err != nil {
+			return nil, err
+		}
+		return hexutil.Uint64(n.chain.nonce(addr, tag == "pending", client)), nil
+
+	case "eth_blobBaseFee":
+		return (*hexutil.Big)(blobBaseFee), nil
+
+	case "eth_getBalance":
+		var addr common.Address
+		var tag string
+		if err := parseParams(req.Params, &addr, &tag); err != nil {
+			return nil, err
+		}
+		return (*hexutil.Big)(n.chain.balanceOf(addr)), nil
+
+	case "txpool_content":
+		return n.chain.txpoolContent(client), nil
+
+	case "eth_newPendingTransactionFilter", "eth_getFilterChanges", "eth_uninstallFilter":
+		if slices.Contains(n.scenario.NoFilters, client) {
+			break
+		}
+		return n.callFilter(client, req)
+
+	case "eth_subscribe":
+		if conn == nil {
+			return nil, &rpcError{Code: -32601, Message: "notifications not supported"}
+		}
+		var kind string
+		if err := parseParams(req.Params, &kind); err != nil {
+			return nil, err
+		}
+		if kind != subscriptionNewPendingTxs && kind != subscriptionNewHeads {
+			return nil, &rpcError{Code: -32602, Message: fmt.Sprintf("unsupported subscription %q", kind)}
+		}
+
+		id := hexutil.EncodeUint64(n.nextID.Add(1))
+		conn.mu.Lock()
+		conn.subs[id] = kind
+		conn.mu.Unlock()
+		return id, nil
+
+	case "eth_unsubscribe":
+		if conn == nil {
+			return false, nil
+		}
+		var id string
+		if err := parseParams(req.Params, &id); err != nil {
+			return nil, err
+		}
+		conn.mu.Lock()
+		_, ok := conn.subs[id]
+		delete(conn.subs, id)
+		conn.mu.Unlock()
+		return ok, nil
+	}
+
+	return nil, &rpcError{Code: -32601, Message: fmt.Sprintf("the method %s does not exist/is not available", req.Method)}
+}
+
+// parseParams decodes positional params, leaving missing trailing params at their zero value
+func parseParams(params []json.RawMessage, out ...any) *rpcError {
+	for i, param := range params {
+		if i >= len(out) {
+			break
+		}
+		if err := json.Unmarshal(param, out[i]); err != nil {
+			return &rpcError{Code: -32602, Message: fmt.Sprintf("invalid argument %d: %s", i, err)}
+		}
+	}
+	return nil
+}
+
+func (n *Node) serveWebsocket(w http.ResponseWriter, r *http.Request, client string) {
+	ws, err := websocket.Accept(w, r, &websocket.AcceptOptions{InsecureSkipVerify: true})
+	if err != nil {
+		return
+	}
+	ws.SetReadLimit(16 * 1024 * 1024)
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	conn := &wsConn{out: make(chan []byte, subscriptionBuffer), subs: make(map[string]string)}
+	n.mu.Lock()
+	if n.subs[client] == nil {
+		n.subs[client] = make(map[*wsConn]struct{})
+	}
+	n.subs[client][conn] = struct{}{}
+	n.mu.Unlock()
+
+	defer func() {
+		n.mu.Lock()
+		delete(n.subs[client], conn)
+		n.mu.Unlock()
+	}()
+
+	// A single writer keeps responses and notifications from interleaving
+	go func() {
+		defer cancel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-conn.out:
+				if !ok {
+					return
+				}
+				if err := ws.Write(ctx, websocket.MessageText, msg); err != nil {
+					return
+				}
+			}
+		}
+	}()
+
+	for {
+		_, msg, err := ws.Read(ctx)
+		if err != nil {
+			ws.Close(websocket.StatusNormalClosure, "")
+			return
+		}
+
+		select {
+		case conn.out <- n.handleBody(client, msg, conn):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// callFilter answers the pending tx filter methods
+func (n *Node) callFilter(client string, req rpcRequest) (any, *rpcError) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if req.Method == "eth_newPendingTransactionFilter" {
+		id := hexutil.EncodeUint64(n.nextID.Add(1))
+		n.filters[id] = &pendingFilter{client: client}
+		return id, nil
+	}
+
+	var id string
+	if err := parseParams(req.Params, &id); err != nil {
+		return nil, err
+	}
+	filter, ok := n.filters[id]
+	if !ok || filter.client != client {
+		if req.Method == "eth_uninstallFilter" {
+			return false, nil
+		}
+		return nil, &rpcError{Code: -32000, Message: "filter not found"}
+	}
+
+	if req.Method == "eth_uninstallFilter" {
+		delete(n.filters, id)
+		return true, nil
+	}
+
+	hashes := filter.hashes
+	filter.hashes = nil
+	if hashes == nil {
+		hashes = []common.Hash{}
+	}
+	return hashes, nil
+}
+
+// addToFilters adds a hash to the pending tx filters of a client
+func (n *Node) addToFilters(client string, hash common.Hash) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	for _, filter := range n.filters {
+		if filter.client == client {
+			filter.hashes = append(filter.hashes, hash)
+		}
+	}
+}
+
+// notify sends a subscription notification to every matching subscription of a client
+func (n *Node) notify(client, kind string, result any) {
+	n.mu.Lock()
+	conns := make([]*wsConn, 0, len(n.subs[client]))
+	for conn := range n.subs[client] {
+		conns = append(conns, conn)
+	}
+	n.mu.Unlock()
+
+	for _, conn := range conns {
+		conn.mu.Lock()
+		ids := make([]string, 0, len(conn.subs))
+		for id, k := range conn.subs {
+			if k == kind {
+				ids = append(ids, id)
+			}
+		}
+		conn.mu.Unlock()
+
+		for _, id := range ids {
+			msg, _ := json.Marshal(map[string]any{
+				"jsonrpc": "2.0",
+				"method":  "eth_subscription",
+				"params":  map[string]any{"subscription": id, "result": result},
+			})
+
+			select {
+			case conn.out <- msg:
+			default:
+				n.logger.Warn("Dropping notification for slow subscriber", logger.Fields{"client": client, "subscription": id})
+			}
+		}
+	}
+}
@@ -0,0 +1,255 @@
+package mocknode
+
+import (
+	"cmp"
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"gopkg.in/yaml.v3"
+)
+
+// Action is a scripted change to the mock clients' mempools or chain
+type Action string
+
+const (
+	ActionSend    Action = "send"    // tx arrives in the mempools of the step's clients
+	ActionReplace Action = "replace" // like send, but a tx with the same sender and nonce must exist
+	ActionDrop    Action = "drop"    // txs are evicted from the mempools of the step's clients
+	ActionMine    Action = "mine"    // a block is produced, visible to every client
+	ActionReorg   Action = "reorg"   // the last depth blocks are replaced by depth+1 new ones, orphaned txs return to the mempools
+)
+
+const (
+	defaultChainID  = 1337
+	defaultGas      = 21000
+	defaultBaseFee  = "1gwei"
+	defaultBalance  = "100ether"
+	defaultBlobFee  = "1gwei"
+	defaultGasLimit = 30_000_000
+)
+
+// Scenario scripts the mempool activity served by the mock clients
+type Scenario struct {
+	ChainID    uint64   `yaml:"chain_id"`
+	BaseFee    string   `yaml:"base_fee"` // e.g. "1gwei", applied to every block
+	Balance    string   `yaml:"balance"`  // every account's balance before the mined txs, defaults to 100ether
+	Clients    []string `yaml:"clients"`
+	NoFilters  []string `yaml:"no_filters"`  // clients answering filter methods as unsupported, like nodes with filters disabled
+	NoSidecars []string `yaml:"no_sidecars"` // clients whose engine API holds none of their blob txs' blobs
+	Steps      []Step   `yaml:"steps"`
+}
+
+// Step is applied once the scenario clock reaches At
+type Step struct {
+	At      time.Duration `yaml:"at"`
+	Action  Action        `yaml:"action"`
+	Tx      *TxSpec       `yaml:"tx"`      // send and replace
+	IDs     []string      `yaml:"ids"`     // drop and mine targets. A mine without ids takes every executable tx, a reorg mines ids in its first block
+	Depth   int           `yaml:"depth"`   // reorg, defaults to 1
+	Clients []string      `yaml:"clients"` // defaults to every client
+}
+
+// TxSpec describes a tx to sign. Accounts are labels, each backed by a key derived from the label.
+type TxSpec struct {
+	ID         string              `yaml:"id"`
+	From       string              `yaml:"from"`
+	To         string              `yaml:"to"` // label or hex address, empty for contract creation
+	Nonce      uint64              `yaml:"nonce"`
+	Value      string              `yaml:"value"`
+	Gas        uint64              `yaml:"gas"`
+	GasPrice   string              `yaml:"gas_price"` // legacy tx when set
+	Tip        string              `yaml:"tip"`
+	FeeCap     string              `yaml:"fee_cap"`
+	Data       string              `yaml:"data"`
+	Blobs      int                 `yaml:"blobs"`        // blob tx carrying this many empty blobs when set
+	BlobFeeCap string              `yaml:"blob_fee_cap"` // defaults to 1gwei
+	Authorize  []AuthorizationSpec `yaml:"authorize"`    // set code tx carrying these authorizations when set
+	AccessList []AccessTupleSpec   `yaml:"access_list"`
+}
+
+// AccessTupleSpec is an access list entry
+type AccessTupleSpec struct {
+	Address     string   `yaml:"address"` // label or hex address
+	StorageKeys []string `yaml:"storage_keys"`
+}
+
+// AuthorizationSpec is an EIP-7702 authorization signed by an account label for the scenario's chain
+type AuthorizationSpec struct {
+	Account  string `yaml:"account"`
+	Delegate string `yaml:"delegate"` // label or hex address of the contract to delegate to
+	Nonce    uint64 `yaml:"nonce"`
+}
+
+// LoadScenario reads and validates a scenario file.
+func LoadScenario(path string) (*Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var s Scenario
+	if err := yaml.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("error parsing scenario: %w", err)
+	}
+
+	if err := s.validate(); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (s *Scenario) validate() error {
+	if len(s.Clients) == 0 {
+		return fmt.Errorf("scenario needs at least one client")
+	}
+	if s.ChainID == 0 {
+		s.ChainID = defaultChainID
+	}
+	if s.BaseFee == "" {
+		s.BaseFee = defaultBaseFee
+	}
+	if _, err := parseAmount(s.BaseFee); err != nil {
+		return fmt.Errorf("base_fee: %w", err)
+	}
+	if s.Balance == "" {
+		s.Balance = defaultBalance
+	}
+	if _, err := parseAmount(s.Balance); err != nil {
+		return fmt.Errorf("balance: %w", err)
+	}
+	for _, client := range s.NoFilters {
+		if !slices.Contains(s.Clients, client) {
+			return fmt.Errorf("no_filters: unknown client %q", client)
+		}
+	}
+	for _, client := range s.NoSidecars {
+		if !slices.Contains(s.Clients, client) {
+			return fmt.Errorf("no_sidecars: unknown client %q", client)
+		}
+	}
+
+	slices.SortStableFunc(s.Steps, func(a, b Step) int {
+		return cmp.Compare(a.At, b.At)
+	})
+
+	ids := make(map[string]bool)
+	for i := range s.Steps {
+		step := &s.Steps[i]
+
+		for _, client := range step.Clients {
+			if !slices.Contains(s.Clients, client) {
+				return fmt.Errorf("step %d: unknown client %q", i, client)
+			}
+		}
+		if len(step.Clients) == 0 {
+			step.Clients = s.Clients
+		}
+
+		switch step.Action {
+		case ActionSend, ActionReplace:
+			if step.Tx == nil || step.Tx.ID == "" || step.Tx.From == "" {
+				return fmt.Errorf("step %d: %s needs a tx with an id and a sender", i, step.Action)
+			}
+			if ids[step.Tx.ID] {
+				return fmt.Errorf("step %d: duplicate tx id %q", i, step.Tx.ID)
+			}
+			ids[step.Tx.ID] = true
+			if step.Tx.Blobs > 0 && (step.Tx.To == "" || step.Tx.GasPrice != "") {
+				return fmt.Errorf("step %d: blob tx %q needs a recipient and 1559 fees", i, step.Tx.ID)
+			}
+			if len(step.Tx.Authorize) > 0 && (step.Tx.To == "" || step.Tx.GasPrice != "" || step.Tx.Blobs > 0) {
+				return fmt.Errorf("step %d: set code tx %q needs a recipient and 1559 fees and no blobs", i, step.Tx.ID)
+			}
+			if len(step.Tx.AccessList) > 0 && step.Tx.GasPrice != "" {
+				return fmt.Errorf("step %d: tx %q needs 1559 fees for an access list", i, step.Tx.ID)
+			}
+			for _, auth := range step.Tx.Authorize {
+				if auth.Account == "" || auth.Delegate == "" {
+					return fmt.Errorf("step %d: set code tx %q authorizations need an account and a delegate", i, step.Tx.ID)
+				}
+			}
+		case ActionDrop:
+			if len(step.IDs) == 0 {
+				return fmt.Errorf("step %d: drop needs ids", i)
+			}
+			fallthrough
+		case ActionReorg:
+			if step.Depth < 0 {
+				return fmt.Errorf("step %d: reorg depth must not be negative", i)
+			}
+			if step.Depth == 0 {
+				step.Depth = 1
+			}
+			fallthrough
+		case ActionMine:
+			for _, id := range step.IDs {
+				if !ids[id] {
+					return fmt.Errorf("step %d: tx %q is not sent before it is used", i, id)
+				}
+			}
+		default:
+			return fmt.Errorf("step %d: unknown action %q", i, step.Action)
+		}
+	}
+
+	return nil
+}
+
+// AccountKey returns the deterministic key behind an account label.
+func AccountKey(label string) *ecdsa.PrivateKey {
+	key, _ := crypto.ToECDSA(crypto.Keccak256([]byte("txpool-viz mocknode " + label)))
+	return key
+}
+
+// AccountAddress returns the address behind an account label, or the label itself when it is a hex address.
+func AccountAddress(label string) common.Address {
+	if common.IsHexAddress(label) {
+		return common.HexToAddress(label)
+	}
+	return crypto.PubkeyToAddress(AccountKey(label).PublicKey)
+}
+
+// parseAmount parses "21", "1.5gwei", "2ether" or "0x10" into wei
+func parseAmount(s string) (*big.Int, error) {
+	s = strings.TrimSpace(strings.ToLower(s))
+	if s == "" {
+		return new(big.Int), nil
+	}
+	if strings.HasPrefix(s, "0x") {
+		return hexutil.DecodeBig(s)
+	}
+
+	units := []struct {
+		suffix string
+		exp    int64
+	}{{"ether", 18}, {"gwei", 9}, {"wei", 0}}
+
+	exp := int64(0)
+	for _, unit := range units {
+		if strings.HasSuffix(s, unit.suffix) {
+			s = strings.TrimSpace(strings.TrimSuffix(s, unit.suffix))
+			exp = unit.exp
+			break
+		}
+	}
+
+	value, ok := new(big.Float).SetPrec(256).SetString(s)
+	if !ok {
+		return nil, fmt.Errorf("invalid amount %q", s)
+	}
+	value.Mul(value, new(big.Float).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(exp), nil)))
+
+	wei, _ := value.Int(nil)
+	if wei.Sign() < 0 {
+		return nil, fmt.Errorf("negative amount %q", s)
+	}
+	return wei, nil
+}
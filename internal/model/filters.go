@@ -46,6 +46,64 @@ type GroupedTransactions struct {
 	} `json:"stats"`
 }
 
+// InclusionListQuery selects a page of inclusion reports, newest slot first
+type InclusionListQuery struct {
+	FromSlot *int
+	ToSlot   *int
+	Page     int
+	PageSize int
+}
+
 type CountArgs struct {
 	TxCount int64 `json:"tx_count" binding:"required"`
 }
+
+// BackfillRequest selects the slot range to rebuild inclusion reports for
+type BackfillRequest struct {
+	FromSlot    uint64 `json:"from_slot"`
+	ToSlot      uint64 `json:"to_slot" binding:"required"`
+	CaptureFile string `json:"capture_file,omitempty"` // Name of a capture in focil_backfill.capture_dir to read inclusion lists from instead of the beacon node
+}
+
+type BackfillStatus struct {
+	Running       bool            `json:"running"`
+	Request       BackfillRequest `json:"request"`
+	StartedAt     int64           `json:"started_at"`
+	FinishedAt    int64           `json:"finished_at,omitempty"`
+	SlotsScanned  int             `json:"slots_scanned"`
+	MissedSlots   int             `json:"missed_slots"`
+	ListsImported int             `json:"lists_imported"`
+	ReportsBuilt  int             `json:"reports_built"`
+	Errors        []string        `json:"errors,omitempty"`
+}
+
+// EndpointWorker is the set of goroutines started for an endpoint or beacon node. Running is false
+// once they stopped on their own, e.g. after the connection dropped.
+type EndpointWorker struct {
+	Name      string `json:"name"`
+	StartedAt int64  `json:"started_at"`
+	Running   bool   `json:"running"`
+}
+
+// SupervisorStatus lists the endpoint workers and whether the config file is watched
+type SupervisorStatus struct {
+	ConfigFile string           `json:"config_file"`
+	Watching   bool             `json:"watching"`
+	Endpoints  []EndpointWorker `json:"endpoints"`
+	Beacons    []EndpointWorker `json:"beacons"`
+}
+
+// EndpointChanges names the entries a reload added, removed or restarted with new settings
+type EndpointChanges struct {
+	Added    []string `json:"added,omitempty"`
+	Removed  []string `json:"removed,omitempty"`
+	Modified []string `json:"modified,omitempty"`
+}
+
+// ReloadResult describes what applying a config changed. Fields other than the endpoints and
+// beacon nodes only take effect after a restart.
+type ReloadResult struct {
+	Endpoints       EndpointChanges `json:"endpoints"`
+	Beacons         EndpointChanges `json:"beacons"`
+	RestartRequired bool            `json:"restart_required"`
+}
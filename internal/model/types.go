@@ -93,22 +93,52 @@ type TransactionMetadata struct {
 	BlockHash    string            `json:"block_hash"`
 	MineStatus   string            `json:"mine_status"`
 	GasUsed      uint64            `json:"gasUsed"`
+	BlobSidecar  SidecarStatus     `json:"blob_sidecar,omitempty"` // whether the client holds a blob tx's blobs
 }
 
+// SidecarStatus tells whether a client's engine API returned every blob of a pooled blob tx
+type SidecarStatus string
+
+const (
+	SidecarServed  SidecarStatus = "served"
+	SidecarMissing SidecarStatus = "missing"
+	SidecarUnknown SidecarStatus = "unknown" // no engine API to ask, or it failed
+)
+
 type Tx struct {
-	ChainID            string   `json:"chain_id"`
-	From               string   `json:"from"`
-	To                 string   `json:"to,omitempty"`
-	IsContractCreation bool     `json:"isContractCreation"`
-	Nonce              uint64   `json:"nonce"`
-	Value              string   `json:"value"`
-	Gas                uint64   `json:"gas"`
-	GasPrice           *big.Int `json:"gas_price,omitempty"`
-	MaxFeePerGas       string   `json:"max_fee_per_gas,omitempty"`
-	MaxPriorityFee     string   `json:"max_priority_fee,omitempty"`
-	MaxFeePerBlobGas   string   `json:"max_fee_per_blob_gas,omitempty"`
-	Data               string   `json:"data,omitempty"`
-	Type               uint8    `json:"type"`
+	ChainID            string          `json:"chain_id"`
+	From               string          `json:"from"`
+	To                 string          `json:"to,omitempty"`
+	IsContractCreation bool            `json:"isContractCreation"`
+	Nonce              uint64          `json:"nonce"`
+	Value              string          `json:"value"`
+	Gas                uint64          `json:"gas"`
+	GasPrice           *big.Int        `json:"gas_price,omitempty"`
+	MaxFeePerGas       string          `json:"max_fee_per_gas,omitempty"`
+	MaxPriorityFee     string          `json:"max_priority_fee,omitempty"`
+	MaxFeePerBlobGas   string          `json:"max_fee_per_blob_gas,omitempty"`
+	BlobHashes         []string        `json:"blob_hashes,omitempty"` // versioned hashes of a blob tx's blobs
+	BlobCount          int             `json:"blob_count,omitempty"`
+	Authorizations     []Authorization `json:"authorizations,omitempty"` // EIP-7702 delegations of a set code tx
+	AccessList         []AccessTuple   `json:"access_list,omitempty"`
+	InitCodeSize       int             `json:"init_code_size,omitempty"` // bytes of a contract creation's init code
+	Data               string          `json:"data,omitempty"`
+	Type               uint8           `json:"type"`
+}
+
+// AccessTuple is an access list entry, an address and the storage slots the tx declares it touches
+type AccessTuple struct {
+	Address     string   `json:"address"`
+	StorageKeys []string `json:"storage_keys"`
+}
+
+// Authorization is a set code tx's authorization tuple with the account it was signed by
+type Authorization struct {
+	ChainID   string `json:"chain_id"` // 0 authorizes on every chain
+	Delegate  string `json:"delegate"` // contract whose code the authority delegates to
+	Nonce     uint64 `json:"nonce"`
+	Authority string `json:"authority,omitempty"` // empty when the signature doesn't recover
+	Error     string `json:"error,omitempty"`
 }
 
 // StoredTransaction represents a transaction with its metadata
@@ -127,10 +157,28 @@ type TxBlock struct {
 }
 
 type ApiTxResponse struct {
-	Hash    string   `json:"hash"`
-	Clients []string `json:"clients"`
-	Diff    TxDiff   `json:"diff"`
-	Common  TxBlock  `json:"common"`
+	Hash    string       `json:"hash"`
+	Clients []string     `json:"clients"`
+	Diff    TxDiff       `json:"diff"`
+	Common  TxBlock      `json:"common"`
+	Call    *DecodedCall `json:"call,omitempty"` // calldata decoded against the known signatures, absent for contract creations
+}
+
+// DecodedCall is a tx's calldata matched by its 4-byte selector
+type DecodedCall struct {
+	Selector  string       `json:"selector"`
+	Signature string       `json:"signature,omitempty"` // empty when the selector is unknown
+	Method    string       `json:"method,omitempty"`
+	Source    string       `json:"source,omitempty"` // abi, when named arguments came from a user ABI, or signature
+	Args      []DecodedArg `json:"args,omitempty"`
+	Error     string       `json:"error,omitempty"` // why the arguments didn't decode against the signature
+}
+
+// DecodedArg is one argument of a decoded call. Integers are decimal strings and bytes hex.
+type DecodedArg struct {
+	Name  string      `json:"name,omitempty"`
+	Type  string      `json:"type"`
+	Value interface{} `json:"value"`
 }
 
 type TxSummary struct {
@@ -201,6 +249,449 @@ type InclusionSummary struct {
 }
 
 type InclusionListWithSlot struct {
-	Slot   int             `json:"slot"`
-	Report InclusionReport `json:"report"`
+	Slot          int                         `json:"slot"`
+	Report        InclusionReport             `json:"report"`
+	Verifications []InclusionListVerification `json:"verifications,omitempty"`
+	Propagation   []InclusionListPropagation  `json:"propagation,omitempty"`
+}
+
+// InclusionListPropagation records when each beacon node delivered the same inclusion list
+type InclusionListPropagation struct {
+	Root           string           `json:"root"`
+	ValidatorIndex string           `json:"validator_index"`
+	FirstNode      string           `json:"first_node"`
+	FirstSeen      int64            `json:"first_seen"` // unix ms
+	Delays         map[string]int64 `json:"delays"`     // ms after the first node, per beacon node
+}
+
+// InclusionOutcome is whether an inclusion list tx made it into the following block
+type InclusionOutcome string
+
+const (
+	OutcomeIncluded InclusionOutcome = "included"
+	OutcomeMissing  InclusionOutcome = "missing"
+	OutcomePending  InclusionOutcome = "pending" // no report for the following block yet
+)
+
+type InclusionListTx struct {
+	Hash     string           `json:"hash"`
+	Tx       Tx               `json:"tx"`
+	Type     string           `json:"type"`
+	ListedBy []string         `json:"listed_by"` // validator indices whose lists contained the tx
+	Outcome  InclusionOutcome `json:"outcome"`
+}
+
+// InclusionListDetail is a single slot's inclusion list with decoded transactions
+type InclusionListDetail struct {
+	Slot          int                         `json:"slot"`
+	Validators    []string                    `json:"validators"`
+	Transactions  []InclusionListTx           `json:"transactions"`
+	Report        *InclusionReport            `json:"report,omitempty"`
+	Verifications []InclusionListVerification `json:"verifications,omitempty"`
+	Propagation   []InclusionListPropagation  `json:"propagation,omitempty"`
+}
+
+// VerificationStatus is the outcome of checking an inclusion list's signature and committee membership
+type VerificationStatus string
+
+const (
+	VerificationValid      VerificationStatus = "valid"
+	VerificationInvalid    VerificationStatus = "invalid"
+	VerificationUnverified VerificationStatus = "unverified" // committee or key lookup failed
+)
+
+type InclusionListVerification struct {
+	Slot            string             `json:"slot"`
+	ValidatorIndex  string             `json:"validator_index"`
+	Status          VerificationStatus `json:"status"`
+	CommitteeMember bool               `json:"committee_member"`
+	SignatureValid  bool               `json:"signature_valid"`
+	Reason          string             `json:"reason,omitempty"`
+	VerifiedAt      int64              `json:"verified_at"`
+}
+
+// ClientSighting describes how a single client saw an inclusion list transaction
+type ClientSighting struct {
+	Status       TransactionStatus `json:"status"`
+	TimeReceived int64             `json:"time_received"`
+	TimePending  *int64            `json:"time_pending,omitempty"`
+}
+
+// InclusionTxMempoolView cross-references one inclusion list tx with the per-client mempool records
+type InclusionTxMempoolView struct {
+	Hash                 string                    `json:"hash"`
+	FirstSeen            *int64                    `json:"first_seen,omitempty"`
+	PendingBeforeListing *int64                    `json:"pending_before_listing,omitempty"` // seconds between first sighting and the IL arriving
+	SeenBy               map[string]ClientSighting `json:"seen_by"`
+	NotSeenBy            []string                  `json:"not_seen_by"`
+	Unknown              map[string]string         `json:"unknown,omitempty"` // clients whose record couldn't be read, with the error
+}
+
+type InclusionMempoolSummary struct {
+	Total      int `json:"total"`
+	SeenByAll  int `json:"seen_by_all"`
+	SeenByNone int `json:"seen_by_none"`
+	Partial    int `json:"partial"`
+	Unknown    int `json:"unknown"` // txs with at least one client whose record couldn't be read
+}
+
+type InclusionListMempoolView struct {
+	Slot         int                      `json:"slot"`
+	ListedAt     *int64                   `json:"listed_at,omitempty"`
+	Transactions []InclusionTxMempoolView `json:"transactions"`
+	Summary      InclusionMempoolSummary  `json:"summary"`
+}
+
+// PoolDiffGroup holds the txs seen by exactly the same subset of clients
+type PoolDiffGroup struct {
+	SeenBy    []string `json:"seen_by"`
+	MissingIn []string `json:"missing_in"`
+	Hashes    []string `json:"hashes"`
+}
+
+// PoolDiff is the set difference of the clients' mempools
+type PoolDiff struct {
+	Clients  []string        `json:"clients"`
+	Statuses []string        `json:"statuses"`
+	Sizes    map[string]int  `json:"sizes"`
+	Shared   int             `json:"shared"` // txs every client has
+	Groups   []PoolDiffGroup `json:"groups"`
+}
+
+// BlockRef identifies a block on either side of a reorg
+type BlockRef struct {
+	Number uint64 `json:"number"`
+	Hash   string `json:"hash"`
+}
+
+// ReorgEvent is a change of canonical chain seen by one endpoint
+type ReorgEvent struct {
+	Endpoint       string     `json:"endpoint"`
+	DetectedAt     int64      `json:"detected_at"`
+	Depth          int        `json:"depth"`           // blocks removed from the canonical chain
+	CommonAncestor uint64     `json:"common_ancestor"` // last block both chains share
+	OldHead        BlockRef   `json:"old_head"`
+	NewHead        BlockRef   `json:"new_head"`
+	Removed        []BlockRef `json:"removed"`
+	Added          []BlockRef `json:"added"`
+	AffectedTxs    []string   `json:"affected_txs"` // txs reverted from mined to pending
+}
+
+// Policy names an acceptance rule inferred from what a client held
+type Policy string
+
+const (
+	PolicyMinTip             Policy = "min_tip"
+	PolicyReplacementBump    Policy = "replacement_bump"
+	PolicyMaxQueuedPerSender Policy = "max_queued_per_sender"
+	PolicyBlobTxs            Policy = "blob_txs"
+	PolicyNonceGaps          Policy = "nonce_gaps"
+	PolicySetCodeTxs         Policy = "set_code_txs"
+)
+
+// PolicyEvidence is a tx an inference rests on. Txs other clients held that this one never did
+// count against acceptance, though they may just not have reached it.
+type PolicyEvidence struct {
+	Hash     string `json:"hash"`
+	Accepted bool   `json:"accepted"`
+	Note     string `json:"note"`
+}
+
+// PolicyInference is what the collected txs tell about one acceptance rule of a client
+type PolicyInference struct {
+	Policy   Policy           `json:"policy"`
+	Value    string           `json:"value"` // e.g. "1 gwei", "10%", empty when there is no evidence
+	Summary  string           `json:"summary"`
+	Evidence []PolicyEvidence `json:"evidence"`
+}
+
+// ClientPolicy is the acceptance behaviour inferred for one client
+type ClientPolicy struct {
+	Client   string            `json:"client"`
+	Txs      int               `json:"txs"` // decoded txs the inferences are based on
+	Policies []PolicyInference `json:"policies"`
+}
+
+// ClientPoolStats is one client's pool at a sample time. Types, gas and tips cover the txs still in the
+// pool, i.e. received, pending or queued.
+type ClientPoolStats struct {
+	ByStatus      map[string]int `json:"by_status"`
+	ByType        map[string]int `json:"by_type"`
+	GasDemand     uint64         `json:"gas_demand"` // sum of the gas limits
+	Blobs         int            `json:"blobs"`      // blobs of the blob txs held
+	MedianTipGwei float64        `json:"median_tip_gwei"`
+	P90TipGwei    float64        `json:"p90_tip_gwei"`
+}
+
+// PoolSample is every client's pool at one time
+type PoolSample struct {
+	Timestamp int64                      `json:"timestamp"`
+	Clients   map[string]ClientPoolStats `json:"clients"`
+}
+
+// TimeSeries is the pool samples of one resolution, oldest first
+type TimeSeries struct {
+	Resolution string       `json:"resolution"`
+	Step       int64        `json:"step"` // seconds between samples, 0 for raw samples taken every stats.interval
+	Samples    []PoolSample `json:"samples"`
+}
+
+// BlockFees is a block's fee market state, recorded as its head arrives. Fees are in wei.
+type BlockFees struct {
+	Number          uint64 `json:"number"`
+	Hash            string `json:"hash"`
+	Timestamp       int64  `json:"timestamp"`
+	Coinbase        string `json:"coinbase"`
+	GasUsed         uint64 `json:"gas_used"`
+	GasLimit        uint64 `json:"gas_limit"`
+	BaseFee         string `json:"base_fee"`
+	BlobGasUsed     uint64 `json:"blob_gas_used"`
+	NextBaseFee     string `json:"next_base_fee"`
+	NextBlobBaseFee string `json:"next_blob_base_fee,omitempty"` // empty before Cancun or when the node lacks eth_blobBaseFee
+}
+
+// FeeDistribution summarizes fees in gwei
+type FeeDistribution struct {
+	Count  int     `json:"count"`
+	Min    float64 `json:"min"`
+	P25    float64 `json:"p25"`
+	Median float64 `json:"median"`
+	P75    float64 `json:"p75"`
+	P90    float64 `json:"p90"`
+	Max    float64 `json:"max"`
+}
+
+// BlockFeeReport compares the txs a block included with those left in a client's pool
+type BlockFeeReport struct {
+	Client        string          `json:"client"`
+	Block         BlockFees       `json:"block"`
+	BlobBaseFee   string          `json:"blob_base_fee,omitempty"` // wei, from the parent's next_blob_base_fee
+	Included      FeeDistribution `json:"included"`                // effective tips of the block's txs the client saw
+	Pending       FeeDistribution `json:"pending"`                 // effective tips of the txs left pending that could pay the base fee
+	Underpriced   int             `json:"underpriced"`             // txs left pending with a fee cap under the base fee
+	TipCutoffGwei *float64        `json:"tip_cutoff_gwei"`         // lowest effective tip included
+	Missed        int             `json:"missed"`
+	MissedTxs     []string        `json:"missed_txs"` // executable txs left pending that paid more than the cutoff
+}
+
+// FeeBucket counts the txs whose fee falls in [MinGwei, MaxGwei), MaxGwei is unset for the last bucket
+type FeeBucket struct {
+	MinGwei float64  `json:"min_gwei"`
+	MaxGwei *float64 `json:"max_gwei,omitempty"`
+	Count   int      `json:"count"`
+}
+
+// BlobFeeReport compares the blob fee caps of a pool's blob txs with the blob base fee
+type BlobFeeReport struct {
+	Txs          int             `json:"txs"`
+	FeeCaps      FeeDistribution `json:"fee_caps"`
+	AboveBaseFee int             `json:"above_base_fee"`
+	BelowBaseFee int             `json:"below_base_fee"`
+}
+
+// MissedInclusions counts the txs of a pool recent blocks should have included
+type MissedInclusions struct {
+	Blocks      int      `json:"blocks"`      // recent blocks checked
+	Txs         int      `json:"txs"`         // distinct txs missed by at least one block
+	Occurrences int      `json:"occurrences"` // tx and block pairs
+	Hashes      []string `json:"hashes"`
+}
+
+// PoolFeeReport is the fee market view of one client's pool at the next block's base fees
+type PoolFeeReport struct {
+	Client       string           `json:"client"`
+	Head         *BlockFees       `json:"head,omitempty"`
+	BaseFee      string           `json:"base_fee,omitempty"`      // wei, the next block's
+	BlobBaseFee  string           `json:"blob_base_fee,omitempty"` // wei, the next block's
+	Txs          int              `json:"txs"`
+	TipHistogram []FeeBucket      `json:"tip_histogram"` // effective tips of the txs that can pay the base fee
+	Underpriced  int              `json:"underpriced"`
+	Blob         BlobFeeReport    `json:"blob"`
+	Missed       MissedInclusions `json:"missed"`
+}
+
+// PrivateFlowReport splits a block's txs by whether a public pool announced them first
+type PrivateFlowReport struct {
+	Number       uint64   `json:"number"`
+	Hash         string   `json:"hash"`
+	Timestamp    int64    `json:"timestamp"`
+	Coinbase     string   `json:"coinbase"`
+	Builder      string   `json:"builder,omitempty"` // the block's extra data, where builders put their name
+	Txs          int      `json:"txs"`
+	Public       int      `json:"public"`  // announced by an endpoint before the block's timestamp
+	Late         int      `json:"late"`    // only announced at or after the block's timestamp
+	Private      int      `json:"private"` // never announced by any endpoint
+	PrivateRatio float64  `json:"private_ratio"`
+	PrivateTxs   []string `json:"private_txs"`
+	LateTxs      []string `json:"late_txs"`
+}
+
+// BuilderPrivateFlow sums the private flow of the recorded blocks of one coinbase
+type BuilderPrivateFlow struct {
+	Coinbase     string  `json:"coinbase"`
+	Builder      string  `json:"builder,omitempty"` // extra data of its latest block
+	Blocks       int     `json:"blocks"`
+	Txs          int     `json:"txs"`
+	Private      int     `json:"private"`
+	Late         int     `json:"late"`
+	PrivateRatio float64 `json:"private_ratio"`
+}
+
+// StuckTx is a tx every client holds that recent blocks left out while it paid more than they included
+type StuckTx struct {
+	Hash           string   `json:"hash"`
+	From           string   `json:"from"`
+	To             string   `json:"to,omitempty"` // empty for contract creations
+	Nonce          uint64   `json:"nonce"`
+	Type           string   `json:"type"`
+	TipGwei        float64  `json:"tip_gwei"`        // effective tip at the latest block's base fee
+	MaxCutoffGwei  float64  `json:"max_cutoff_gwei"` // highest lowest-included tip of the blocks that left it out
+	FirstSeen      int64    `json:"first_seen"`      // first announced by any endpoint
+	PendingSeconds int64    `json:"pending_seconds"`
+	ExcludedBlocks int      `json:"excluded_blocks"`
+	FirstExcluded  uint64   `json:"first_excluded"` // block number
+	LastExcluded   uint64   `json:"last_excluded"`  // block number
+	SkippedBy      []string `json:"skipped_by"`     // coinbases of the blocks that left it out
+}
+
+// StuckTxGroup counts the stuck txs sharing a sender or recipient
+type StuckTxGroup struct {
+	Address        string   `json:"address"`
+	Txs            int      `json:"txs"`
+	ExcludedBlocks int      `json:"excluded_blocks"` // most blocks any of them was left out of
+	Hashes         []string `json:"hashes"`
+}
+
+// ProposerSkips counts the stuck txs a coinbase's blocks left out
+type ProposerSkips struct {
+	Coinbase string `json:"coinbase"`
+	Blocks   int    `json:"blocks"`
+	Txs      int    `json:"txs"`
+}
+
+// CensorshipReport lists the stuck txs, longest excluded first, and groups them by sender, recipient and proposer
+type CensorshipReport struct {
+	Blocks      int             `json:"blocks"`     // recent blocks checked
+	MinBlocks   int             `json:"min_blocks"` // blocks a tx had to be left out of
+	Txs         []StuckTx       `json:"txs"`
+	BySender    []StuckTxGroup  `json:"by_sender"`
+	ByRecipient []StuckTxGroup  `json:"by_recipient"` // recipients are often the contract the txs call
+	ByProposer  []ProposerSkips `json:"by_proposer"`
+}
+
+// CensorshipAlert is raised the first time a tx is found stuck
+type CensorshipAlert struct {
+	RaisedAt int64   `json:"raised_at"`
+	Tx       StuckTx `json:"tx"`
+}
+
+// AddressTx is a tx from or to an address with the record each client keeps of it
+type AddressTx struct {
+	Hash     string                         `json:"hash"`
+	Tx       Tx                             `json:"tx"`
+	Outgoing bool                           `json:"outgoing"`
+	Clients  map[string]TransactionMetadata `json:"clients"`
+}
+
+// NonceClientState is a tx one client holds at a nonce, there may be several while replacements are pending
+type NonceClientState struct {
+	Hash        string            `json:"hash"`
+	Status      TransactionStatus `json:"status"`
+	BlockNumber uint64            `json:"block_number,omitempty"`
+}
+
+// NonceSlot is every client's view of one nonce of the address
+type NonceSlot struct {
+	Nonce   uint64                        `json:"nonce"`
+	Clients map[string][]NonceClientState `json:"clients"`
+}
+
+// NonceGap is a run of nonces a client holds no tx for, keeping the txs above it from executing
+type NonceGap struct {
+	Client  string   `json:"client"`
+	From    uint64   `json:"from"` // first missing nonce
+	To      uint64   `json:"to"`   // last missing nonce
+	Blocked []string `json:"blocked"`
+}
+
+// BalanceCheck compares the address' balance with the worst-case cost of its pending txs in nonce order
+type BalanceCheck struct {
+	Balance        string   `json:"balance"`      // wei
+	PendingCost    string   `json:"pending_cost"` // wei, gas times fee cap plus value of each nonce the primary client holds
+	Sufficient     bool     `json:"sufficient"`
+	CoveredThrough *uint64  `json:"covered_through,omitempty"` // highest nonce the balance pays for
+	Underfunded    []string `json:"underfunded"`
+}
+
+// AddressView is one account's txs across clients, its nonce timeline and what keeps its txs from executing
+type AddressView struct {
+	Address      string        `json:"address"`
+	OnChainNonce *uint64       `json:"on_chain_nonce,omitempty"`
+	Txs          []AddressTx   `json:"txs"`
+	Timeline     []NonceSlot   `json:"timeline"` // nonces of the txs sent by the address
+	Gaps         []NonceGap    `json:"gaps"`
+	Balance      *BalanceCheck `json:"balance,omitempty"`
+	ChainError   string        `json:"chain_error,omitempty"` // why the on-chain nonce or balance are missing
+}
+
+// BlobPoolReport is a client's blob txs, the sidecars it serves and their fee caps against the blob base fee
+type BlobPoolReport struct {
+	Client  string        `json:"client"`
+	Txs     int           `json:"txs"`
+	Blobs   int           `json:"blobs"`
+	Bytes   uint64        `json:"bytes"`   // blob data held, 128KiB a blob
+	Served  int           `json:"served"`  // txs whose blobs the engine API returned
+	Missing int           `json:"missing"` // txs it lacks blobs of
+	Unknown int           `json:"unknown"` // txs of clients without an engine API
+	Fees    BlobFeeReport `json:"fees"`
+}
+
+// BlobPoolView compares the clients' blobpools at the next block's blob base fee
+type BlobPoolView struct {
+	BlobBaseFee string           `json:"blob_base_fee,omitempty"` // wei
+	Clients     []BlobPoolReport `json:"clients"`
+}
+
+// BlockBlobs is a recorded block's blob usage with the blob txs a client saw mined in it
+type BlockBlobs struct {
+	Client      string   `json:"client"`
+	Number      uint64   `json:"number"`
+	Hash        string   `json:"hash"`
+	Timestamp   int64    `json:"timestamp"`
+	BlobGasUsed uint64   `json:"blob_gas_used"`
+	Blobs       int      `json:"blobs"`
+	BlobBaseFee string   `json:"blob_base_fee,omitempty"` // wei, from the parent's next_blob_base_fee
+	Txs         []string `json:"txs"`
+}
+
+// SetCodeClientStats counts the set code txs a client recorded, by status, and their authorizations
+type SetCodeClientStats struct {
+	Client                string                    `json:"client"`
+	Txs                   int                       `json:"txs"`
+	Statuses              map[TransactionStatus]int `json:"statuses"`
+	Authorizations        int                       `json:"authorizations"`
+	InvalidAuthorizations int                       `json:"invalid_authorizations"` // signatures that don't recover
+	SelfSponsored         int                       `json:"self_sponsored"`         // authorizations signed by the tx's own sender
+}
+
+// SetCodeTx is a set code tx with each client's status for it
+type SetCodeTx struct {
+	Hash           string                       `json:"hash"`
+	From           string                       `json:"from"`
+	Nonce          uint64                       `json:"nonce"`
+	Authorizations []Authorization              `json:"authorizations"`
+	Clients        map[string]TransactionStatus `json:"clients"`
+	Unseen         []string                     `json:"unseen"` // clients with no record of the tx
+}
+
+// SetCodeReport compares how the clients treat set code txs, listing the txs they disagree on
+type SetCodeReport struct {
+	Clients   []SetCodeClientStats `json:"clients"`
+	Divergent []SetCodeTx          `json:"divergent"`
+}
+
+// DelegationView is every tracked set code tx authorizing a delegate contract
+type DelegationView struct {
+	Delegate string      `json:"delegate"`
+	Txs      []SetCodeTx `json:"txs"`
 }
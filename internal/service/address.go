@@ -0,0 +1,261 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+
+	"txpool-viz/internal/config"
+	"txpool-viz/internal/model"
+	"txpool-viz/utils"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// primaryEndpoint returns the first endpoint, false when there is none
+func (ts *TransactionServiceImpl) primaryEndpoint() (config.Endpoint, bool) {
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+
+	if len(ts.endpoints) == 0 {
+		return config.Endpoint{}, false
+	}
+	return ts.endpoints[0], true
+}
+
+// inPool reports whether a client still holds the tx
+func inPool(meta model.TransactionMetadata) bool {
+	return meta.Status == model.StatusPending || meta.Status == model.StatusQueued
+}
+
+// GetAddressView returns every tracked tx from or to an address with the nonce timeline of the txs it
+// sent, the nonce gaps keeping them queued and whether its balance covers them. The on-chain nonce and
+// balance are read from the primary endpoint.
+func (ts *TransactionServiceImpl) GetAddressView(ctx context.Context, address string) (model.AddressView, error) {
+	addr := common.HexToAddress(address)
+	view := model.AddressView{
+		Address:  addr.Hex(),
+		Txs:      []model.AddressTx{},
+		Timeline: []model.NonceSlot{},
+		Gaps:     []model.NonceGap{},
+	}
+
+	clients := ts.clientNames()
+	byHash := make(map[string]*model.AddressTx)
+	for _, client := range clients {
+		records, err := ts.redis.HGetAll(ctx, utils.RedisClientMetaKey(client)).Result()
+		if err != nil {
+			return view, fmt.Errorf("error reading %s mempool: %w", client, err)
+		}
+
+		for _, data := range records {
+			var tx model.StoredTransaction
+			if err := json.Unmarshal([]byte(data), &tx); err != nil {
+				continue
+			}
+			outgoing := strings.EqualFold(tx.Tx.From, view.Address)
+			if !outgoing && !strings.EqualFold(tx.Tx.To, view.Address) {
+				continue
+			}
+
+			addrTx, ok := byHash[tx.Hash]
+			if !ok {
+				addrTx = &model.AddressTx{
+					Hash:     tx.Hash,
+					Tx:       tx.Tx,
+					Outgoing: outgoing,
+					Clients:  make(map[string]model.TransactionMetadata),
+				}
+				byHash[tx.Hash] = addrTx
+			}
+			addrTx.Clients[client] = tx.Metadata
+		}
+	}
+
+	for _, tx := range byHash {
+		view.Txs = append(view.Txs, *tx)
+	}
+	sort.Slice(view.Txs, func(i, j int) bool {
+		ri, rj := firstReceived(view.Txs[i]), firstReceived(view.Txs[j])
+		if ri != rj {
+			return ri < rj
+		}
+		return view.Txs[i].Hash < view.Txs[j].Hash
+	})
+
+	var balance *big.Int
+	if endpoint, ok := ts.primaryEndpoint(); ok && endpoint.Client != nil {
+		nonce, err := endpoint.Client.NonceAt(ctx, addr, nil)
+		if err == nil {
+			view.OnChainNonce = &nonce
+			balance, err = endpoint.Client.BalanceAt(ctx, addr, nil)
+		}
+		if err != nil {
+			view.ChainError = err.Error()
+		}
+	}
+
+	view.Timeline = nonceTimeline(view.Txs)
+	for _, client := range clients {
+		view.Gaps = append(view.Gaps, nonceGaps(client, view.Txs, view.OnChainNonce)...)
+	}
+	if balance != nil && len(clients) > 0 {
+		view.Balance = balanceCheck(clients[0], view.Txs, *view.OnChainNonce, balance)
+	}
+
+	return view, nil
+}
+
+// firstReceived is when the first client saw the tx
+func firstReceived(tx model.AddressTx) int64 {
+	var first int64
+	for _, meta := range tx.Clients {
+		if first == 0 || meta.TimeReceived < first {
+			first = meta.TimeReceived
+		}
+	}
+	return first
+}
+
+// nonceTimeline lays out each client's txs by nonce for the txs the address sent
+func nonceTimeline(txs []model.AddressTx) []model.NonceSlot {
+	byNonce := make(map[uint64]*model.NonceSlot)
+	for _, tx := range txs {
+		if !tx.Outgoing {
+			continue
+		}
+		slot, ok := byNonce[tx.Tx.Nonce]
+		if !ok {
+			slot = &model.NonceSlot{Nonce: tx.Tx.Nonce, Clients: make(map[string][]model.NonceClientState)}
+			byNonce[tx.Tx.Nonce] = slot
+		}
+		for client, meta := range tx.Clients {
+			slot.Clients[client] = append(slot.Clients[client], model.NonceClientState{
+				Hash:        tx.Hash,
+				Status:      meta.Status,
+				BlockNumber: meta.BlockNumber,
+			})
+		}
+	}
+
+	timeline := make([]model.NonceSlot, 0, len(byNonce))
+	for _, slot := range byNonce {
+		for _, states := range slot.Clients {
+			sort.Slice(states, func(i, j int) bool { return states[i].Hash < states[j].Hash })
+		}
+		timeline = append(timeline, *slot)
+	}
+	sort.Slice(timeline, func(i, j int) bool { return timeline[i].Nonce < timeline[j].Nonce })
+	return timeline
+}
+
+// nonceGaps finds the nonces a client is missing between the account's next nonce and the highest it
+// holds. Without the on-chain nonce the next one after the client's mined txs is used, or its lowest held.
+func nonceGaps(client string, txs []model.AddressTx, onChain *uint64) []model.NonceGap {
+	held := make(map[uint64][]string)
+	var (
+		next, highest uint64
+		haveNext      bool
+	)
+	for _, tx := range txs {
+		meta, ok := tx.Clients[client]
+		if !ok || !tx.Outgoing {
+			continue
+		}
+		nonce := tx.Tx.Nonce
+		switch {
+		case inPool(meta):
+			held[nonce] = append(held[nonce], tx.Hash)
+			if nonce > highest {
+				highest = nonce
+			}
+		case meta.Status == model.StatusMined && (!haveNext || nonce+1 > next):
+			next, haveNext = nonce+1, true
+		}
+	}
+	if len(held) == 0 {
+		return nil
+	}
+
+	switch {
+	case onChain != nil:
+		next = *onChain
+	case !haveNext:
+		next = highest
+		for nonce := range held {
+			if nonce < next {
+				next = nonce
+			}
+		}
+	}
+
+	var gaps []model.NonceGap
+	for nonce := next; nonce < highest; nonce++ {
+		if _, ok := held[nonce]; ok {
+			continue
+		}
+		if n := len(gaps); n > 0 && gaps[n-1].To+1 == nonce {
+			gaps[n-1].To = nonce
+			continue
+		}
+		gaps = append(gaps, model.NonceGap{Client: client, From: nonce, To: nonce})
+	}
+	for i := range gaps {
+		gaps[i].Blocked = []string{}
+		for nonce, hashes := range held {
+			if nonce > gaps[i].To {
+				gaps[i].Blocked = append(gaps[i].Blocked, hashes...)
+			}
+		}
+		sort.Strings(gaps[i].Blocked)
+	}
+	return gaps
+}
+
+// balanceCheck adds up the worst-case cost of the txs the client holds from the on-chain nonce on, the
+// latest received one for each nonce, and finds the first nonce the balance can't pay for. Blob txs also
+// pay for their blob gas at their blob fee cap.
+func balanceCheck(client string, txs []model.AddressTx, onChain uint64, balance *big.Int) *model.BalanceCheck {
+	byNonce := make(map[uint64]model.AddressTx)
+	for _, tx := range txs {
+		meta, ok := tx.Clients[client]
+		if !ok || !tx.Outgoing || !inPool(meta) || tx.Tx.Nonce < onChain {
+			continue
+		}
+		if current, ok := byNonce[tx.Tx.Nonce]; !ok || meta.TimeReceived > current.Clients[client].TimeReceived {
+			byNonce[tx.Tx.Nonce] = tx
+		}
+	}
+	nonces := make([]uint64, 0, len(byNonce))
+	for nonce := range byNonce {
+		nonces = append(nonces, nonce)
+	}
+	sort.Slice(nonces, func(i, j int) bool { return nonces[i] < nonces[j] })
+
+	check := &model.BalanceCheck{Balance: balance.String(), Underfunded: []string{}}
+	cost := new(big.Int)
+	for _, nonce := range nonces {
+		tx := byNonce[nonce].Tx
+		txCost := new(big.Int).Mul(new(big.Int).SetUint64(tx.Gas), parseWei(tx.MaxFeePerGas))
+		txCost.Add(txCost, parseWei(tx.Value))
+		if tx.MaxFeePerBlobGas != "" {
+			blobGas := new(big.Int).SetUint64(uint64(tx.BlobCount) * params.BlobTxBlobGasPerBlob)
+			txCost.Add(txCost, blobGas.Mul(blobGas, parseWei(tx.MaxFeePerBlobGas)))
+		}
+		cost.Add(cost, txCost)
+
+		if cost.Cmp(balance) <= 0 {
+			covered := nonce
+			check.CoveredThrough = &covered
+		} else {
+			check.Underfunded = append(check.Underfunded, byNonce[nonce].Hash)
+		}
+	}
+	check.PendingCost = cost.String()
+	check.Sufficient = cost.Cmp(balance) <= 0
+	return check
+}
@@ -0,0 +1,122 @@
+package service
+
+import (
+	"context"
+	"math/big"
+
+	"txpool-viz/internal/model"
+
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// GetBlobPool returns every client's blob txs with the sidecars it serves and their blob fee caps
+// against the next block's blob base fee
+func (ts *TransactionServiceImpl) GetBlobPool(ctx context.Context) (model.BlobPoolView, error) {
+	view := model.BlobPoolView{Clients: []model.BlobPoolReport{}}
+
+	clients := ts.clientNames()
+	if len(clients) == 0 {
+		return view, nil
+	}
+
+	// The primary's latest block sets the blob base fee every client is compared against
+	var blobBaseFee *big.Int
+	latest, err := ts.latestBlockFees(ctx, clients[0], 1)
+	if err != nil {
+		return view, err
+	}
+	if len(latest) > 0 && latest[0].NextBlobBaseFee != "" {
+		view.BlobBaseFee = latest[0].NextBlobBaseFee
+		blobBaseFee = parseWei(latest[0].NextBlobBaseFee)
+	}
+
+	for _, client := range clients {
+		txs, err := ts.feeTxs(ctx, client)
+		if err != nil {
+			return view, err
+		}
+
+		report := model.BlobPoolReport{Client: client}
+		var feeCaps []float64
+		for i := range txs {
+			tx := &txs[i]
+			if tx.blobFeeCap == nil || !inPool(tx.Metadata) {
+				continue
+			}
+			report.Txs++
+			report.Blobs += tx.Tx.BlobCount
+			switch tx.Metadata.BlobSidecar {
+			case model.SidecarServed:
+				report.Served++
+			case model.SidecarMissing:
+				report.Missing++
+			case model.SidecarUnknown:
+				report.Unknown++
+			}
+
+			feeCaps = append(feeCaps, toGwei(tx.blobFeeCap))
+			if blobBaseFee != nil {
+				if tx.blobFeeCap.Cmp(blobBaseFee) >= 0 {
+					report.Fees.AboveBaseFee++
+				} else {
+					report.Fees.BelowBaseFee++
+				}
+			}
+		}
+		report.Bytes = uint64(report.Blobs) * params.BlobTxBlobGasPerBlob
+		report.Fees.Txs = report.Txs
+		report.Fees.FeeCaps = distribution(feeCaps)
+		view.Clients = append(view.Clients, report)
+	}
+
+	return view, nil
+}
+
+// GetBlockBlobs returns the blob usage of the latest limit recorded blocks, newest first, with the blob
+// txs the client saw mined in each
+func (ts *TransactionServiceImpl) GetBlockBlobs(ctx context.Context, client string, limit int) ([]model.BlockBlobs, error) {
+	client, err := ts.resolveClient(client)
+	if err != nil {
+		return nil, err
+	}
+
+	// One more block for the blob base fee of the oldest
+	blocks, err := ts.latestBlockFees(ctx, client, int64(limit)+1)
+	if err != nil {
+		return nil, err
+	}
+	txs, err := ts.feeTxs(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+
+	byBlock := make(map[string][]string)
+	for i := range txs {
+		tx := &txs[i]
+		if tx.blobFeeCap != nil && tx.Metadata.Status == model.StatusMined {
+			byBlock[tx.Metadata.BlockHash] = append(byBlock[tx.Metadata.BlockHash], tx.Hash)
+		}
+	}
+
+	reports := make([]model.BlockBlobs, 0, limit)
+	for i := 0; i < len(blocks) && i < limit; i++ {
+		block := blocks[i]
+		report := model.BlockBlobs{
+			Client:      client,
+			Number:      block.Number,
+			Hash:        block.Hash,
+			Timestamp:   block.Timestamp,
+			BlobGasUsed: block.BlobGasUsed,
+			Blobs:       int(block.BlobGasUsed / params.BlobTxBlobGasPerBlob),
+			Txs:         byBlock[block.Hash],
+		}
+		if report.Txs == nil {
+			report.Txs = []string{}
+		}
+		if i+1 < len(blocks) && blocks[i+1].Number+1 == block.Number {
+			report.BlobBaseFee = blocks[i+1].NextBlobBaseFee
+		}
+		reports = append(reports, report)
+	}
+	return reports, nil
+}
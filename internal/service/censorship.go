@@ -0,0 +1,360 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"txpool-viz/internal/logger"
+	"txpool-viz/internal/model"
+	"txpool-viz/utils"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// maxCensorshipAlerts is how many alerts the log keeps
+	maxCensorshipAlerts = 1000
+	// censorshipAlertedFor is how long a tx is remembered as alerted on
+	censorshipAlertedFor = 24 * time.Hour
+	// censorshipWindow is how many recent blocks the watcher checks
+	censorshipWindow = 64
+)
+
+// exclusion is a tx of the primary client left out of recorded blocks
+type exclusion struct {
+	tx     *feeTx
+	blocks []model.BlockFees // newest first
+	cutoff float64           // highest tip cutoff of those blocks, in gwei
+}
+
+// GetStuckTxs checks the latest blocks for txs each of them missed, as the fee reports define it through
+// the primary client's txs, and returns those left out of more than minBlocks that every client still holds
+func (ts *TransactionServiceImpl) GetStuckTxs(ctx context.Context, minBlocks, blocks int) (model.CensorshipReport, error) {
+	report := model.CensorshipReport{
+		MinBlocks:   minBlocks,
+		Txs:         []model.StuckTx{},
+		BySender:    []model.StuckTxGroup{},
+		ByRecipient: []model.StuckTxGroup{},
+		ByProposer:  []model.ProposerSkips{},
+	}
+
+	clients := ts.clientNames()
+	if len(clients) == 0 {
+		return report, nil
+	}
+
+	recent, err := ts.latestBlockFees(ctx, clients[0], int64(blocks)+1)
+	if err != nil {
+		return report, err
+	}
+	txs, err := ts.windowTxs(ctx, clients[0], recent)
+	if err != nil {
+		return report, err
+	}
+
+	excluded := make(map[string]*exclusion)
+	for i := 0; i < len(recent) && i < blocks; i++ {
+		var parent *model.BlockFees
+		if i+1 < len(recent) && recent[i+1].Number+1 == recent[i].Number {
+			parent = &recent[i+1]
+		}
+		block := blockFeeReport(clients[0], recent[i], parent, txs)
+		report.Blocks++
+
+		for _, hash := range block.MissedTxs {
+			ex, ok := excluded[hash]
+			if !ok {
+				ex = &exclusion{}
+				excluded[hash] = ex
+			}
+			ex.blocks = append(ex.blocks, recent[i])
+			if *block.TipCutoffGwei > ex.cutoff {
+				ex.cutoff = *block.TipCutoffGwei
+			}
+		}
+	}
+	for i := range txs {
+		if ex, ok := excluded[txs[i].Hash]; ok {
+			ex.tx = &txs[i]
+		}
+	}
+
+	var candidates []*exclusion
+	for _, ex := range excluded {
+		if len(ex.blocks) > minBlocks && ex.tx != nil {
+			candidates = append(candidates, ex)
+		}
+	}
+	candidates, err = ts.heldByEvery(ctx, clients[1:], candidates)
+	if err != nil {
+		return report, err
+	}
+	firstSeen, err := ts.firstSeen(ctx, candidates)
+	if err != nil {
+		return report, err
+	}
+
+	now := ts.clock.Now().Unix()
+	for i, ex := range candidates {
+		stuck := model.StuckTx{
+			Hash:           ex.tx.Hash,
+			From:           ex.tx.Tx.From,
+			To:             ex.tx.Tx.To,
+			Nonce:          ex.tx.Tx.Nonce,
+			Type:           model.TransactionType(ex.tx.Tx.Type).String(),
+			MaxCutoffGwei:  ex.cutoff,
+			FirstSeen:      firstSeen[i],
+			PendingSeconds: now - firstSeen[i],
+			ExcludedBlocks: len(ex.blocks),
+			FirstExcluded:  ex.blocks[len(ex.blocks)-1].Number,
+			LastExcluded:   ex.blocks[0].Number,
+			SkippedBy:      []string{},
+		}
+		if tip, ok := ex.tx.effectiveTip(parseWei(recent[0].BaseFee)); ok {
+			stuck.TipGwei = toGwei(tip)
+		}
+		seen := make(map[string]bool)
+		for j := len(ex.blocks) - 1; j >= 0; j-- {
+			if coinbase := ex.blocks[j].Coinbase; coinbase != "" && !seen[coinbase] {
+				seen[coinbase] = true
+				stuck.SkippedBy = append(stuck.SkippedBy, coinbase)
+			}
+		}
+		report.Txs = append(report.Txs, stuck)
+	}
+	sort.Slice(report.Txs, func(i, j int) bool {
+		if report.Txs[i].ExcludedBlocks != report.Txs[j].ExcludedBlocks {
+			return report.Txs[i].ExcludedBlocks > report.Txs[j].ExcludedBlocks
+		}
+		if report.Txs[i].FirstSeen != report.Txs[j].FirstSeen {
+			return report.Txs[i].FirstSeen < report.Txs[j].FirstSeen
+		}
+		return report.Txs[i].Hash < report.Txs[j].Hash
+	})
+
+	report.BySender = groupStuckTxs(report.Txs, func(tx model.StuckTx) string { return tx.From })
+	report.ByRecipient = groupStuckTxs(report.Txs, func(tx model.StuckTx) string { return tx.To })
+	report.ByProposer = proposerSkips(candidates)
+	return report, nil
+}
+
+// windowTxs reads the client's txs the blocks can report on: those it still holds, which are the only
+// ones that can be stuck, and those mined in the blocks, which set the cutoffs and the senders' nonces.
+// Txs long mined or dropped are never read.
+func (ts *TransactionServiceImpl) windowTxs(ctx context.Context, client string, blocks []model.BlockFees) ([]feeTx, error) {
+	pipe := ts.redis.Pipeline()
+	held := pipe.ZRange(ctx, utils.RedisTipIndexKey(client), 0, -1)
+	mined := make([]*redis.StringSliceCmd, len(blocks))
+	for i, block := range blocks {
+		mined[i] = pipe.SMembers(ctx, utils.RedisBlockTxsKey(client, block.Hash))
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("error reading %s pending index: %w", client, err)
+	}
+
+	seen := make(map[string]bool)
+	var hashes []string
+	add := func(members []string) {
+		for _, hash := range members {
+			if !seen[hash] {
+				seen[hash] = true
+				hashes = append(hashes, hash)
+			}
+		}
+	}
+	add(held.Val())
+	for _, cmd := range mined {
+		add(cmd.Val())
+	}
+	return ts.feeTxsByHash(ctx, client, hashes)
+}
+
+// heldByEvery keeps the candidates each of the other clients still holds pending or queued
+func (ts *TransactionServiceImpl) heldByEvery(ctx context.Context, clients []string, candidates []*exclusion) ([]*exclusion, error) {
+	for _, client := range clients {
+		if len(candidates) == 0 {
+			break
+		}
+
+		hashes := make([]string, len(candidates))
+		for i, ex := range candidates {
+			hashes[i] = ex.tx.Hash
+		}
+		records, err := ts.redis.HMGet(ctx, utils.RedisClientMetaKey(client), hashes...).Result()
+		if err != nil {
+			return nil, fmt.Errorf("error reading %s mempool: %w", client, err)
+		}
+
+		held := candidates[:0]
+		for i, record := range records {
+			data, ok := record.(string)
+			if !ok {
+				continue
+			}
+			var stored model.StoredTransaction
+			if err := json.Unmarshal([]byte(data), &stored); err != nil {
+				continue
+			}
+			if stored.Metadata.Status == model.StatusPending || stored.Metadata.Status == model.StatusQueued {
+				held = append(held, candidates[i])
+			}
+		}
+		candidates = held
+	}
+	return candidates, nil
+}
+
+// firstSeen returns when any endpoint first announced each candidate
+func (ts *TransactionServiceImpl) firstSeen(ctx context.Context, candidates []*exclusion) ([]int64, error) {
+	pipe := ts.redis.Pipeline()
+	scores := make([]*redis.FloatCmd, len(candidates))
+	for i, ex := range candidates {
+		scores[i] = pipe.ZScore(ctx, utils.RedisUniversalKey(), ex.tx.Hash)
+	}
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("error reading first sightings: %w", err)
+	}
+
+	seen := make([]int64, len(candidates))
+	for i, ex := range candidates {
+		seen[i] = ex.tx.Metadata.TimeReceived
+		if score, err := scores[i].Result(); err == nil {
+			seen[i] = int64(score)
+		}
+	}
+	return seen, nil
+}
+
+// groupStuckTxs groups the txs by an address, largest groups first
+func groupStuckTxs(txs []model.StuckTx, address func(model.StuckTx) string) []model.StuckTxGroup {
+	byAddress := make(map[string]*model.StuckTxGroup)
+	for _, tx := range txs {
+		addr := address(tx)
+		group, ok := byAddress[addr]
+		if !ok {
+			group = &model.StuckTxGroup{Address: addr, Hashes: []string{}}
+			byAddress[addr] = group
+		}
+		group.Txs++
+		group.Hashes = append(group.Hashes, tx.Hash)
+		if tx.ExcludedBlocks > group.ExcludedBlocks {
+			group.ExcludedBlocks = tx.ExcludedBlocks
+		}
+	}
+
+	groups := make([]model.StuckTxGroup, 0, len(byAddress))
+	for _, group := range byAddress {
+		groups = append(groups, *group)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].Txs != groups[j].Txs {
+			return groups[i].Txs > groups[j].Txs
+		}
+		return groups[i].Address < groups[j].Address
+	})
+	return groups
+}
+
+// proposerSkips counts by coinbase the blocks that left stuck txs out and the txs they left out
+func proposerSkips(candidates []*exclusion) []model.ProposerSkips {
+	blocks := make(map[string]map[string]bool)
+	txs := make(map[string]int)
+	for _, ex := range candidates {
+		skipped := make(map[string]bool)
+		for _, block := range ex.blocks {
+			if blocks[block.Coinbase] == nil {
+				blocks[block.Coinbase] = make(map[string]bool)
+			}
+			blocks[block.Coinbase][block.Hash] = true
+			skipped[block.Coinbase] = true
+		}
+		for coinbase := range skipped {
+			txs[coinbase]++
+		}
+	}
+
+	skips := make([]model.ProposerSkips, 0, len(blocks))
+	for coinbase, hashes := range blocks {
+		skips = append(skips, model.ProposerSkips{Coinbase: coinbase, Blocks: len(hashes), Txs: txs[coinbase]})
+	}
+	sort.Slice(skips, func(i, j int) bool {
+		if skips[i].Txs != skips[j].Txs {
+			return skips[i].Txs > skips[j].Txs
+		}
+		return skips[i].Coinbase < skips[j].Coinbase
+	})
+	return skips
+}
+
+// WatchCensorship looks for stuck txs every interval until ctx is cancelled, raising an alert the first
+// time each one is found. Ticks follow the wall clock, alerts are stamped with the service's clock.
+func (ts *TransactionServiceImpl) WatchCensorship(ctx context.Context, minBlocks int, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			report, err := ts.GetStuckTxs(ctx, minBlocks, censorshipWindow)
+			if err != nil {
+				if ctx.Err() == nil {
+					ts.logger.Error("Error looking for stuck txs", logger.Fields{"error": err.Error()})
+				}
+				continue
+			}
+			for _, tx := range report.Txs {
+				ts.raiseCensorshipAlert(ctx, ts.clock.Now().Unix(), tx)
+			}
+		}
+	}
+}
+
+// raiseCensorshipAlert logs and stores an alert for a stuck tx, unless one was already raised for it
+func (ts *TransactionServiceImpl) raiseCensorshipAlert(ctx context.Context, raisedAt int64, tx model.StuckTx) {
+	first, err := ts.redis.SetNX(ctx, utils.RedisCensorshipAlertedKey(tx.Hash), raisedAt, censorshipAlertedFor).Result()
+	if err != nil || !first {
+		return
+	}
+
+	ts.logger.Warn("Tx stuck while paying more than included txs", logger.Fields{
+		"txHash":         tx.Hash,
+		"from":           tx.From,
+		"excludedBlocks": tx.ExcludedBlocks,
+		"tipGwei":        tx.TipGwei,
+		"skippedBy":      tx.SkippedBy,
+	})
+
+	data, err := json.Marshal(model.CensorshipAlert{RaisedAt: raisedAt, Tx: tx})
+	if err != nil {
+		return
+	}
+	pipe := ts.redis.Pipeline()
+	pipe.LPush(ctx, utils.RedisCensorshipAlertsKey(), data)
+	pipe.LTrim(ctx, utils.RedisCensorshipAlertsKey(), 0, maxCensorshipAlerts-1)
+	if _, err := pipe.Exec(ctx); err != nil {
+		ts.logger.Error("Error storing censorship alert", logger.Fields{"txHash": tx.Hash, "error": err.Error()})
+	}
+}
+
+// GetCensorshipAlerts returns the latest limit alerts, newest first
+func (ts *TransactionServiceImpl) GetCensorshipAlerts(ctx context.Context, limit int) ([]model.CensorshipAlert, error) {
+	raw, err := ts.redis.LRange(ctx, utils.RedisCensorshipAlertsKey(), 0, int64(limit)-1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("error reading censorship alerts: %w", err)
+	}
+
+	alerts := make([]model.CensorshipAlert, 0, len(raw))
+	for _, data := range raw {
+		var alert model.CensorshipAlert
+		if err := json.Unmarshal([]byte(data), &alert); err == nil {
+			alerts = append(alerts, alert)
+		}
+	}
+	return alerts, nil
+}
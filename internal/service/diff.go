@@ -30,6 +30,17 @@ func toMapTx(t model.Tx) map[string]interface{} {
 	}
 	if t.MaxFeePerBlobGas != "" {
 		m["maxFeePerBlobGas"] = t.MaxFeePerBlobGas
+		m["blobHashes"] = t.BlobHashes
+		m["blobCount"] = t.BlobCount
+	}
+	if t.IsContractCreation {
+		m["initCodeSize"] = t.InitCodeSize
+	}
+	if len(t.AccessList) > 0 {
+		m["accessList"] = t.AccessList
+	}
+	if len(t.Authorizations) > 0 {
+		m["authorizations"] = t.Authorizations
 	}
 	return m
 }
@@ -52,6 +63,9 @@ func toMapMeta(md model.TransactionMetadata) map[string]interface{} {
 		m["timeMined"] = *md.TimeMined
 	}
 	m["timeDropped"] = md.TimeDropped
+	if md.BlobSidecar != "" {
+		m["blobSidecar"] = string(md.BlobSidecar)
+	}
 	return m
 }
 
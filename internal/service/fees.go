@@ -0,0 +1,439 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"sort"
+	"strconv"
+
+	"txpool-viz/internal/model"
+	"txpool-viz/utils"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	// ErrUnknownClient is returned for a client that isn't one of the endpoints
+	ErrUnknownClient = errors.New("unknown client")
+
+	// ErrBlockNotFound is returned for a block whose fees weren't recorded
+	ErrBlockNotFound = errors.New("block fees not recorded")
+)
+
+// feeHistogramEdges are the lower bounds, in gwei, of the pool tip histogram buckets
+var feeHistogramEdges = []float64{0, 0.1, 0.5, 1, 2, 5, 10, 20, 50, 100}
+
+// maxMissedHashes is how many missed txs a pool report lists
+const maxMissedHashes = 20
+
+// feeTx is a decoded tx of a client with its fees in wei
+type feeTx struct {
+	model.StoredTransaction
+	tip        *big.Int
+	feeCap     *big.Int
+	blobFeeCap *big.Int // nil unless a blob tx
+}
+
+// effectiveTip is what the tx pays the proposer per gas at baseFee, false when its fee cap is under it
+func (t *feeTx) effectiveTip(baseFee *big.Int) (*big.Int, bool) {
+	if t.feeCap.Cmp(baseFee) < 0 {
+		return nil, false
+	}
+	headroom := new(big.Int).Sub(t.feeCap, baseFee)
+	if headroom.Cmp(t.tip) < 0 {
+		return headroom, true
+	}
+	return t.tip, true
+}
+
+// pendingAt reports whether the tx sat in the pool when the block was built
+func (t *feeTx) pendingAt(block model.BlockFees) bool {
+	meta := t.Metadata
+	if meta.TimeReceived >= block.Timestamp {
+		return false
+	}
+	if meta.Status == model.StatusMined && meta.BlockNumber <= block.Number {
+		return false
+	}
+	return meta.Status != model.StatusDropped || meta.TimeDropped > block.Timestamp
+}
+
+// resolveClient defaults to the primary client and checks the client is an endpoint
+func (ts *TransactionServiceImpl) resolveClient(client string) (string, error) {
+	clients := ts.clientNames()
+	if client == "" && len(clients) > 0 {
+		return clients[0], nil
+	}
+	if !slices.Contains(clients, client) {
+		return "", fmt.Errorf("%w %q", ErrUnknownClient, client)
+	}
+	return client, nil
+}
+
+// feeTxs reads the client's decoded txs
+func (ts *TransactionServiceImpl) feeTxs(ctx context.Context, client string) ([]feeTx, error) {
+	records, err := ts.redis.HGetAll(ctx, utils.RedisClientMetaKey(client)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s mempool: %w", client, err)
+	}
+
+	txs := make([]feeTx, 0, len(records))
+	for _, val := range records {
+		if tx, ok := decodeFeeTx(val); ok {
+			txs = append(txs, tx)
+		}
+	}
+	return txs, nil
+}
+
+// feeTxsByHash reads the client's decoded txs among hashes, skipping those it doesn't have
+func (ts *TransactionServiceImpl) feeTxsByHash(ctx context.Context, client string, hashes []string) ([]feeTx, error) {
+	if len(hashes) == 0 {
+		return nil, nil
+	}
+	records, err := ts.redis.HMGet(ctx, utils.RedisClientMetaKey(client), hashes...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s mempool: %w", client, err)
+	}
+
+	txs := make([]feeTx, 0, len(records))
+	for _, record := range records {
+		val, ok := record.(string)
+		if !ok {
+			continue
+		}
+		if tx, ok := decodeFeeTx(val); ok {
+			txs = append(txs, tx)
+		}
+	}
+	return txs, nil
+}
+
+// decodeFeeTx decodes a stored tx, false when its body or fees are missing
+func decodeFeeTx(val string) (feeTx, bool) {
+	var tx feeTx
+	if err := json.Unmarshal([]byte(val), &tx.StoredTransaction); err != nil || tx.Tx.From == "" {
+		return tx, false
+	}
+
+	var ok bool
+	if tx.tip, ok = new(big.Int).SetString(tx.Tx.MaxPriorityFee, 10); !ok {
+		return tx, false
+	}
+	if tx.feeCap, ok = new(big.Int).SetString(tx.Tx.MaxFeePerGas, 10); !ok {
+		return tx, false
+	}
+	if tx.Tx.MaxFeePerBlobGas != "" {
+		tx.blobFeeCap, _ = new(big.Int).SetString(tx.Tx.MaxFeePerBlobGas, 10)
+	}
+	return tx, true
+}
+
+// latestBlockFees returns up to n blocks recorded from the client's heads, newest first
+func (ts *TransactionServiceImpl) latestBlockFees(ctx context.Context, client string, n int64) ([]model.BlockFees, error) {
+	raw, err := ts.redis.ZRevRange(ctx, utils.RedisBlockFeesKey(client), 0, n-1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("error reading block fees: %w", err)
+	}
+	return decodeBlockFees(raw), nil
+}
+
+// blockFeesAt returns the client's recorded block at a height, nil when there is none
+func (ts *TransactionServiceImpl) blockFeesAt(ctx context.Context, client string, number uint64) (*model.BlockFees, error) {
+	score := strconv.FormatUint(number, 10)
+	raw, err := ts.redis.ZRangeByScore(ctx, utils.RedisBlockFeesKey(client), &redis.ZRangeBy{Min: score, Max: score}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("error reading block fees: %w", err)
+	}
+	blocks := decodeBlockFees(raw)
+	if len(blocks) == 0 {
+		return nil, nil
+	}
+	return &blocks[0], nil
+}
+
+func decodeBlockFees(raw []string) []model.BlockFees {
+	blocks := make([]model.BlockFees, 0, len(raw))
+	for _, data := range raw {
+		var block model.BlockFees
+		if err := json.Unmarshal([]byte(data), &block); err == nil {
+			blocks = append(blocks, block)
+		}
+	}
+	return blocks
+}
+
+// GetBlockFeeReports returns the fee reports of the latest limit recorded blocks, newest first
+func (ts *TransactionServiceImpl) GetBlockFeeReports(ctx context.Context, client string, limit int) ([]model.BlockFeeReport, error) {
+	client, err := ts.resolveClient(client)
+	if err != nil {
+		return nil, err
+	}
+
+	// One more block for the blob base fee of the oldest
+	blocks, err := ts.latestBlockFees(ctx, client, int64(limit)+1)
+	if err != nil {
+		return nil, err
+	}
+	txs, err := ts.feeTxs(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+
+	reports := make([]model.BlockFeeReport, 0, limit)
+	for i := 0; i < len(blocks) && i < limit; i++ {
+		var parent *model.BlockFees
+		if i+1 < len(blocks) && blocks[i+1].Number+1 == blocks[i].Number {
+			parent = &blocks[i+1]
+		}
+		reports = append(reports, blockFeeReport(client, blocks[i], parent, txs))
+	}
+	return reports, nil
+}
+
+// GetBlockFeeReport returns the fee report of a recorded block
+func (ts *TransactionServiceImpl) GetBlockFeeReport(ctx context.Context, client string, number uint64) (model.BlockFeeReport, error) {
+	client, err := ts.resolveClient(client)
+	if err != nil {
+		return model.BlockFeeReport{}, err
+	}
+
+	block, err := ts.blockFeesAt(ctx, client, number)
+	if err != nil {
+		return model.BlockFeeReport{}, err
+	}
+	if block == nil {
+		return model.BlockFeeReport{}, fmt.Errorf("%w: %d", ErrBlockNotFound, number)
+	}
+
+	var parent *model.BlockFees
+	if number > 0 {
+		if parent, err = ts.blockFeesAt(ctx, client, number-1); err != nil {
+			return model.BlockFeeReport{}, err
+		}
+	}
+
+	txs, err := ts.feeTxs(ctx, client)
+	if err != nil {
+		return model.BlockFeeReport{}, err
+	}
+	return blockFeeReport(client, *block, parent, txs), nil
+}
+
+// GetPoolFeeReport returns the fee market view of a client's pool at the next block's base fees, with the
+// txs the latest blocks should have included
+func (ts *TransactionServiceImpl) GetPoolFeeReport(ctx context.Context, client string, blocks int) (model.PoolFeeReport, error) {
+	client, err := ts.resolveClient(client)
+	if err != nil {
+		return model.PoolFeeReport{}, err
+	}
+
+	recent, err := ts.latestBlockFees(ctx, client, int64(blocks)+1)
+	if err != nil {
+		return model.PoolFeeReport{}, err
+	}
+	txs, err := ts.feeTxs(ctx, client)
+	if err != nil {
+		return model.PoolFeeReport{}, err
+	}
+
+	report := model.PoolFeeReport{Client: client, TipHistogram: feeHistogram(), Missed: model.MissedInclusions{Hashes: []string{}}}
+
+	baseFee, blobBaseFee := new(big.Int), (*big.Int)(nil)
+	if len(recent) > 0 {
+		report.Head = &recent[0]
+		report.BaseFee = recent[0].NextBaseFee
+		report.BlobBaseFee = recent[0].NextBlobBaseFee
+		baseFee = parseWei(recent[0].NextBaseFee)
+		if recent[0].NextBlobBaseFee != "" {
+			blobBaseFee = parseWei(recent[0].NextBlobBaseFee)
+		}
+	}
+
+	var blobFeeCaps []float64
+	for i := range txs {
+		tx := &txs[i]
+		if tx.Metadata.Status != model.StatusPending && tx.Metadata.Status != model.StatusQueued {
+			continue
+		}
+		report.Txs++
+
+		if tip, ok := tx.effectiveTip(baseFee); ok {
+			addToHistogram(report.TipHistogram, toGwei(tip))
+		} else {
+			report.Underpriced++
+		}
+
+		if tx.blobFeeCap == nil {
+			continue
+		}
+		report.Blob.Txs++
+		blobFeeCaps = append(blobFeeCaps, toGwei(tx.blobFeeCap))
+		if blobBaseFee != nil {
+			if tx.blobFeeCap.Cmp(blobBaseFee) >= 0 {
+				report.Blob.AboveBaseFee++
+			} else {
+				report.Blob.BelowBaseFee++
+			}
+		}
+	}
+	report.Blob.FeeCaps = distribution(blobFeeCaps)
+
+	missed := make(map[string]bool)
+	for i := 0; i < len(recent) && i < blocks; i++ {
+		var parent *model.BlockFees
+		if i+1 < len(recent) && recent[i+1].Number+1 == recent[i].Number {
+			parent = &recent[i+1]
+		}
+		block := blockFeeReport(client, recent[i], parent, txs)
+
+		report.Missed.Blocks++
+		report.Missed.Occurrences += block.Missed
+		for _, hash := range block.MissedTxs {
+			if !missed[hash] && len(report.Missed.Hashes) < maxMissedHashes {
+				report.Missed.Hashes = append(report.Missed.Hashes, hash)
+			}
+			missed[hash] = true
+		}
+	}
+	report.Missed.Txs = len(missed)
+
+	return report, nil
+}
+
+// blockFeeReport compares the txs of the client that the block included with those it left pending.
+// A pending tx was missed when it paid more than the lowest included tip, could pay the base fees, and
+// was its sender's next nonce as far as the client's txs tell.
+func blockFeeReport(client string, block model.BlockFees, parent *model.BlockFees, txs []feeTx) model.BlockFeeReport {
+	report := model.BlockFeeReport{Client: client, Block: block, MissedTxs: []string{}}
+
+	baseFee := parseWei(block.BaseFee)
+	var blobBaseFee *big.Int
+	if parent != nil && parent.NextBlobBaseFee != "" {
+		report.BlobBaseFee = parent.NextBlobBaseFee
+		blobBaseFee = parseWei(parent.NextBlobBaseFee)
+	}
+
+	var (
+		included, pendingTips []float64
+		cutoff                *big.Int
+		pending               []*feeTx
+		includedNonce         = make(map[string]uint64) // highest included nonce by sender
+	)
+	for i := range txs {
+		tx := &txs[i]
+		switch {
+		case tx.Metadata.Status == model.StatusMined && tx.Metadata.BlockHash == block.Hash:
+			tip, _ := tx.effectiveTip(baseFee)
+			if tip == nil {
+				tip = new(big.Int)
+			}
+			included = append(included, toGwei(tip))
+			if cutoff == nil || tip.Cmp(cutoff) < 0 {
+				cutoff = tip
+			}
+			if nonce, ok := includedNonce[tx.Tx.From]; !ok || tx.Tx.Nonce > nonce {
+				includedNonce[tx.Tx.From] = tx.Tx.Nonce
+			}
+		case tx.pendingAt(block):
+			if tip, ok := tx.effectiveTip(baseFee); ok {
+				pendingTips = append(pendingTips, toGwei(tip))
+				pending = append(pending, tx)
+			} else {
+				report.Underpriced++
+			}
+		}
+	}
+	report.Included = distribution(included)
+	report.Pending = distribution(pendingTips)
+	if cutoff == nil {
+		return report
+	}
+	gwei := toGwei(cutoff)
+	report.TipCutoffGwei = &gwei
+
+	// Only each sender's lowest pending nonce above what the block included could have gone in
+	next := make(map[string]*feeTx)
+	for _, tx := range pending {
+		if nonce, ok := includedNonce[tx.Tx.From]; ok && tx.Tx.Nonce <= nonce {
+			continue
+		}
+		if lowest, ok := next[tx.Tx.From]; !ok || tx.Tx.Nonce < lowest.Tx.Nonce {
+			next[tx.Tx.From] = tx
+		}
+	}
+	for _, tx := range next {
+		tip, _ := tx.effectiveTip(baseFee)
+		if tip.Cmp(cutoff) <= 0 {
+			continue
+		}
+		if tx.blobFeeCap != nil && blobBaseFee != nil && tx.blobFeeCap.Cmp(blobBaseFee) < 0 {
+			continue
+		}
+		report.MissedTxs = append(report.MissedTxs, tx.Hash)
+	}
+	sort.Strings(report.MissedTxs)
+	report.Missed = len(report.MissedTxs)
+
+	return report
+}
+
+func feeHistogram() []model.FeeBucket {
+	buckets := make([]model.FeeBucket, len(feeHistogramEdges))
+	for i, edge := range feeHistogramEdges {
+		buckets[i].MinGwei = edge
+		if i+1 < len(feeHistogramEdges) {
+			upper := feeHistogramEdges[i+1]
+			buckets[i].MaxGwei = &upper
+		}
+	}
+	return buckets
+}
+
+func addToHistogram(buckets []model.FeeBucket, gwei float64) {
+	for i := len(buckets) - 1; i >= 0; i-- {
+		if gwei >= buckets[i].MinGwei {
+			buckets[i].Count++
+			return
+		}
+	}
+}
+
+// distribution summarizes values with nearest-rank percentiles
+func distribution(values []float64) model.FeeDistribution {
+	if len(values) == 0 {
+		return model.FeeDistribution{}
+	}
+	sorted := slices.Clone(values)
+	sort.Float64s(sorted)
+
+	rank := func(p int) float64 {
+		return sorted[max((p*len(sorted)+99)/100-1, 0)]
+	}
+	return model.FeeDistribution{
+		Count:  len(sorted),
+		Min:    sorted[0],
+		P25:    rank(25),
+		Median: rank(50),
+		P75:    rank(75),
+		P90:    rank(90),
+		Max:    sorted[len(sorted)-1],
+	}
+}
+
+func parseWei(s string) *big.Int {
+	wei, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		return new(big.Int)
+	}
+	return wei
+}
+
+func toGwei(wei *big.Int) float64 {
+	gwei, _ := new(big.Rat).SetFrac(wei, big.NewInt(1_000_000_000)).Float64()
+	return gwei
+}
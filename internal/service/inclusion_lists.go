@@ -3,35 +3,57 @@ package service
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"sort"
 	"strconv"
+	"sync"
+	"txpool-viz/internal/config"
 	"txpool-viz/internal/logger"
 	"txpool-viz/internal/model"
+	"txpool-viz/internal/storage"
 	"txpool-viz/utils"
 
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/redis/go-redis/v9"
 )
 
+// ErrInclusionListNotFound is returned when no inclusion list has been stored for a slot
+var ErrInclusionListNotFound = errors.New("inclusion list not found")
+
 type InclusionListService struct {
-	redis  *redis.Client
-	logger logger.Logger
+	redis   *redis.Client
+	logger  logger.Logger
 	enabled bool
+
+	mu        sync.RWMutex
+	endpoints []config.Endpoint
 }
 
-func NewInclusionListService(r *redis.Client, l logger.Logger, focilEnabled bool) *InclusionListService {
+func NewInclusionListService(r *redis.Client, l logger.Logger, focilEnabled bool, cfgEndpoints []config.Endpoint) *InclusionListService {
 	return &InclusionListService{
-		redis:  r,
-		logger: l,
-		enabled: focilEnabled,
+		redis:     r,
+		logger:    l,
+		enabled:   focilEnabled,
+		endpoints: cfgEndpoints,
 	}
 }
 
-func (il *InclusionListService) GetInclusionLists(ctx context.Context) ([]model.InclusionListWithSlot, error) {
+// SetEndpoints replaces the endpoints whose mempools are compared, e.g. after a config reload
+func (il *InclusionListService) SetEndpoints(endpoints []config.Endpoint) {
+	il.mu.Lock()
+	defer il.mu.Unlock()
+	il.endpoints = endpoints
+}
+
+// GetInclusionLists returns a page of inclusion reports, newest first, along with the number
+// of reports matching the slot range.
+func (il *InclusionListService) GetInclusionLists(ctx context.Context, query model.InclusionListQuery) ([]model.InclusionListWithSlot, int, error) {
 	inclusionReportKey := utils.RedisInclusionListReportKey()
 
 	results, err := il.redis.HGetAll(ctx, inclusionReportKey).Result()
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
 	var sortedReports []model.InclusionListWithSlot
@@ -43,6 +65,10 @@ func (il *InclusionListService) GetInclusionLists(ctx context.Context) ([]model.
 			continue
 		}
 
+		if (query.FromSlot != nil && slot < *query.FromSlot) || (query.ToSlot != nil && slot > *query.ToSlot) {
+			continue
+		}
+
 		var report model.InclusionReport
 		if err := json.Unmarshal([]byte(reportJSON), &report); err != nil {
 			il.logger.Error("Invalid entry", err.Error())
@@ -59,10 +85,316 @@ func (il *InclusionListService) GetInclusionLists(ctx context.Context) ([]model.
 		return sortedReports[i].Slot > sortedReports[j].Slot
 	})
 
-	return sortedReports, nil
+	total := len(sortedReports)
+	if query.PageSize > 0 {
+		start := min(max(query.Page-1, 0)*query.PageSize, total)
+		end := min(start+query.PageSize, total)
+		sortedReports = sortedReports[start:end]
+	}
+
+	// Reports are keyed by block number and built from the previous slot's lists
+	for i := range sortedReports {
+		ilSlot := sortedReports[i].Slot - 1
+		sortedReports[i].Verifications = il.getVerifications(ctx, ilSlot)
+		sortedReports[i].Propagation = il.getPropagation(ctx, ilSlot)
+	}
+
+	return sortedReports, total, nil
+}
+
+// GetInclusionListDetail returns the decoded transactions of a slot's inclusion list, the validators
+// that contributed lists and whether each tx made it into the following block.
+func (il *InclusionListService) GetInclusionListDetail(ctx context.Context, slot int) (model.InclusionListDetail, error) {
+	slotStr := strconv.Itoa(slot)
+
+	// Every tx any validator listed, not only the largest list
+	ilTxs, err := storage.InclusionListTransactions(ctx, il.redis, slotStr)
+	if err == redis.Nil {
+		return model.InclusionListDetail{}, ErrInclusionListNotFound
+	} else if err != nil {
+		return model.InclusionListDetail{}, err
+	}
+
+	detail := model.InclusionListDetail{
+		Slot:          slot,
+		Validators:    []string{},
+		Transactions:  make([]model.InclusionListTx, 0, len(ilTxs)),
+		Verifications: il.getVerifications(ctx, slot),
+		Propagation:   il.getPropagation(ctx, slot),
+	}
+
+	// tx hash -> validators that listed it
+	listedBy := make(map[string][]string)
+	validatorLists, err := il.redis.HGetAll(ctx, utils.RedisInclusionListValidatorsKey(slotStr)).Result()
+	if err != nil {
+		il.logger.Error("Redis error", "error", err.Error())
+	}
+	for validatorIndex, hashesJSON := range validatorLists {
+		detail.Validators = append(detail.Validators, validatorIndex)
+
+		var hashes []string
+		if err := json.Unmarshal([]byte(hashesJSON), &hashes); err != nil {
+			il.logger.Error("Invalid entry", err.Error())
+			continue
+		}
+		for _, hash := range hashes {
+			listedBy[hash] = append(listedBy[hash], validatorIndex)
+		}
+	}
+	sortValidatorIndices(detail.Validators)
+	for _, validators := range listedBy {
+		sortValidatorIndices(validators)
+	}
+
+	// The report for the following block tells which txs were included
+	outcomes := make(map[string]model.InclusionOutcome)
+	reportJSON, err := il.redis.HGet(ctx, utils.RedisInclusionListReportKey(), strconv.Itoa(slot+1)).Result()
+	if err == nil {
+		var report model.InclusionReport
+		if err := json.Unmarshal([]byte(reportJSON), &report); err == nil {
+			detail.Report = &report
+			for _, hash := range report.Included {
+				outcomes[hash.Hex()] = model.OutcomeIncluded
+			}
+			for _, hash := range report.Missing {
+				outcomes[hash.Hex()] = model.OutcomeMissing
+			}
+		}
+	}
+
+	for _, tx := range ilTxs {
+		if tx == nil {
+			continue
+		}
+
+		hash := tx.Hash().Hex()
+		ilTx := model.InclusionListTx{
+			Hash:     hash,
+			Type:     utils.GetTransactionType(tx).String(),
+			ListedBy: listedBy[hash],
+			Outcome:  model.OutcomePending,
+		}
+
+		if sender, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx); err == nil {
+			ilTx.Tx = storage.StructureTx(tx, sender)
+		} else {
+			il.logger.Warn("Failed to derive sender", "txHash", hash, "error", err.Error())
+		}
+
+		if outcome, ok := outcomes[hash]; ok {
+			ilTx.Outcome = outcome
+		}
+
+		detail.Transactions = append(detail.Transactions, ilTx)
+	}
+
+	return detail, nil
+}
+
+// lessValidatorIndex orders decimal validator indices numerically, anything else after them as text
+func lessValidatorIndex(a, b string) bool {
+	x, errA := strconv.ParseUint(a, 10, 64)
+	y, errB := strconv.ParseUint(b, 10, 64)
+	switch {
+	case errA == nil && errB == nil:
+		return x < y
+	case errA == nil || errB == nil:
+		return errA == nil
+	default:
+		return a < b
+	}
+}
+
+// sortValidatorIndices sorts validator indices numerically
+func sortValidatorIndices(indices []string) {
+	sort.Slice(indices, func(i, j int) bool { return lessValidatorIndex(indices[i], indices[j]) })
+}
+
+// getVerifications returns the stored signature checks for a slot's inclusion lists
+func (il *InclusionListService) getVerifications(ctx context.Context, ilSlot int) []model.InclusionListVerification {
+	key := utils.RedisInclusionVerificationKey(strconv.Itoa(ilSlot))
+
+	results, err := il.redis.HGetAll(ctx, key).Result()
+	if err != nil {
+		il.logger.Error("Redis error", "error", err.Error())
+		return nil
+	}
+
+	verifications := make([]model.InclusionListVerification, 0, len(results))
+	for _, resultJSON := range results {
+		var verification model.InclusionListVerification
+		if err := json.Unmarshal([]byte(resultJSON), &verification); err != nil {
+			il.logger.Error("Invalid entry", err.Error())
+			continue
+		}
+		verifications = append(verifications, verification)
+	}
+
+	sort.Slice(verifications, func(i, j int) bool {
+		return lessValidatorIndex(verifications[i].ValidatorIndex, verifications[j].ValidatorIndex)
+	})
+
+	return verifications
+}
+
+// getPropagation returns per beacon node arrival delays for every distinct list of a slot
+func (il *InclusionListService) getPropagation(ctx context.Context, ilSlot int) []model.InclusionListPropagation {
+	roots, err := il.redis.HGetAll(ctx, utils.RedisInclusionListRootsKey(strconv.Itoa(ilSlot))).Result()
+	if err != nil {
+		il.logger.Error("Redis error", "error", err.Error())
+		return nil
+	}
+
+	propagation := make([]model.InclusionListPropagation, 0, len(roots))
+	for root, validatorIndex := range roots {
+		arrivals, err := il.redis.HGetAll(ctx, utils.RedisInclusionListArrivalsKey(root)).Result()
+		if err != nil || len(arrivals) == 0 {
+			continue
+		}
+
+		entry := model.InclusionListPropagation{
+			Root:           root,
+			ValidatorIndex: validatorIndex,
+			Delays:         make(map[string]int64, len(arrivals)),
+		}
+
+		arrivalTimes := make(map[string]int64, len(arrivals))
+		for node, arrival := range arrivals {
+			ms, err := strconv.ParseInt(arrival, 10, 64)
+			if err != nil {
+				continue
+			}
+			arrivalTimes[node] = ms
+			if entry.FirstNode == "" || ms < entry.FirstSeen {
+				entry.FirstNode = node
+				entry.FirstSeen = ms
+			}
+		}
+
+		for node, ms := range arrivalTimes {
+			entry.Delays[node] = ms - entry.FirstSeen
+		}
+
+		propagation = append(propagation, entry)
+	}
+
+	sort.Slice(propagation, func(i, j int) bool {
+		return lessValidatorIndex(propagation[i].ValidatorIndex, propagation[j].ValidatorIndex)
+	})
+
+	return propagation
+}
+
+// GetInclusionListMempoolView annotates every tx of a slot's inclusion list with the
+// clients that had it in their pool, the clients that never saw it and how long it
+// sat pending before the list arrived.
+func (il *InclusionListService) GetInclusionListMempoolView(ctx context.Context, slot int) (model.InclusionListMempoolView, error) {
+	slotStr := strconv.Itoa(slot)
+
+	ilTxs, err := storage.InclusionListTransactions(ctx, il.redis, slotStr)
+	if err == redis.Nil {
+		return model.InclusionListMempoolView{}, ErrInclusionListNotFound
+	} else if err != nil {
+		return model.InclusionListMempoolView{}, err
+	}
+
+	view := model.InclusionListMempoolView{
+		Slot:         slot,
+		Transactions: make([]model.InclusionTxMempoolView, 0, len(ilTxs)),
+	}
+
+	if listedAt, err := il.redis.HGet(ctx, utils.RedisInclusionListSeenKey(), slotStr).Int64(); err == nil {
+		view.ListedAt = &listedAt
+	}
+
+	for _, tx := range ilTxs {
+		if tx == nil {
+			continue
+		}
+
+		txView := il.crossReferenceTx(ctx, tx.Hash().Hex(), view.ListedAt)
+
+		view.Summary.Total++
+		switch {
+		case len(txView.Unknown) > 0:
+			view.Summary.Unknown++
+		case len(txView.NotSeenBy) == 0:
+			view.Summary.SeenByAll++
+		case len(txView.SeenBy) == 0:
+			view.Summary.SeenByNone++
+		default:
+			view.Summary.Partial++
+		}
+
+		view.Transactions = append(view.Transactions, txView)
+	}
+
+	return view, nil
+}
+
+// crossReferenceTx looks up a tx hash in every configured client's mempool records
+func (il *InclusionListService) crossReferenceTx(ctx context.Context, txHash string, listedAt *int64) model.InclusionTxMempoolView {
+	txView := model.InclusionTxMempoolView{
+		Hash:      txHash,
+		SeenBy:    make(map[string]model.ClientSighting),
+		NotSeenBy: []string{},
+	}
+
+	if score, err := il.redis.ZScore(ctx, utils.RedisUniversalKey(), txHash).Result(); err == nil {
+		firstSeen := int64(score)
+		txView.FirstSeen = &firstSeen
+
+		// Negative values mean the tx reached our nodes only after it was listed
+		if listedAt != nil {
+			pending := *listedAt - firstSeen
+			txView.PendingBeforeListing = &pending
+		}
+	}
+
+	il.mu.RLock()
+	endpoints := il.endpoints
+	il.mu.RUnlock()
+
+	for _, endpoint := range endpoints {
+		val, err := il.redis.HGet(ctx, utils.RedisClientMetaKey(endpoint.Name), txHash).Result()
+		if err == redis.Nil {
+			txView.NotSeenBy = append(txView.NotSeenBy, endpoint.Name)
+			continue
+		} else if err != nil {
+			il.markUnknown(&txView, endpoint.Name, fmt.Errorf("error reading mempool record: %w", err))
+			continue
+		}
+
+		var storedTx model.StoredTransaction
+		if err := json.Unmarshal([]byte(val), &storedTx); err != nil {
+			il.markUnknown(&txView, endpoint.Name, fmt.Errorf("invalid mempool record: %w", err))
+			continue
+		}
+
+		txView.SeenBy[endpoint.Name] = model.ClientSighting{
+			Status:       storedTx.Metadata.Status,
+			TimeReceived: storedTx.Metadata.TimeReceived,
+			TimePending:  storedTx.Metadata.TimePending,
+		}
+	}
+
+	return txView
+}
+
+// markUnknown records that a client's sighting of the tx couldn't be determined
+func (il *InclusionListService) markUnknown(txView *model.InclusionTxMempoolView, client string, err error) {
+	il.logger.Error("Couldn't cross-reference inclusion list tx", logger.Fields{
+		"txHash":   txView.Hash,
+		"endpoint": client,
+		"error":    err.Error(),
+	})
+	if txView.Unknown == nil {
+		txView.Unknown = make(map[string]string)
+	}
+	txView.Unknown[client] = err.Error()
 }
 
 // IsFocilEnabled checks if the Focil feature is enabled
 func (il *InclusionListService) IsFocilEnabled() bool {
 	return il.enabled
-}
\ No newline at end of file
+}
@@ -0,0 +1,461 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+
+	"txpool-viz/internal/model"
+	"txpool-viz/utils"
+)
+
+// maxPolicyEvidence is how many txs are linked on either side of an inference
+const maxPolicyEvidence = 5
+
+// policyTx is a decoded tx with the record of each client that held it
+type policyTx struct {
+	hash      string
+	tx        model.Tx
+	tip       *big.Int
+	feeCap    *big.Int
+	holders   map[string]model.StoredTransaction
+	firstSeen int64
+	mined     *int64 // earliest block time any client reported
+}
+
+// heldBy lists the clients holding the tx, sorted
+func (t *policyTx) heldBy() string {
+	clients := make([]string, 0, len(t.holders))
+	for client := range t.holders {
+		clients = append(clients, client)
+	}
+	sort.Strings(clients)
+	return strings.Join(clients, ", ")
+}
+
+// GetClientPolicies infers each client's acceptance policies from the txs it held compared to the txs
+// the other clients held
+func (ts *TransactionServiceImpl) GetClientPolicies(ctx context.Context) ([]model.ClientPolicy, error) {
+	clients := ts.clientNames()
+
+	records := make(map[string]map[string]model.StoredTransaction, len(clients))
+	for _, client := range clients {
+		raw, err := ts.redis.HGetAll(ctx, utils.RedisClientMetaKey(client)).Result()
+		if err != nil {
+			return nil, fmt.Errorf("error reading %s mempool: %w", client, err)
+		}
+
+		records[client] = make(map[string]model.StoredTransaction, len(raw))
+		for hash, val := range raw {
+			var storedTx model.StoredTransaction
+			if err := json.Unmarshal([]byte(val), &storedTx); err != nil {
+				continue
+			}
+			records[client][hash] = storedTx
+		}
+	}
+
+	return inferPolicies(clients, records), nil
+}
+
+// inferPolicies infers the policies of each client from the records by client and tx hash
+func inferPolicies(clients []string, records map[string]map[string]model.StoredTransaction) []model.ClientPolicy {
+	all := make(map[string]*policyTx)
+	for _, client := range clients {
+		for hash, storedTx := range records[client] {
+			tx, ok := all[hash]
+			if !ok {
+				tx = &policyTx{hash: hash, holders: make(map[string]model.StoredTransaction), firstSeen: storedTx.Metadata.TimeReceived}
+				all[hash] = tx
+			}
+			tx.holders[client] = storedTx
+			tx.firstSeen = min(tx.firstSeen, storedTx.Metadata.TimeReceived)
+			if tx.tx.From == "" {
+				tx.tx = storedTx.Tx
+			}
+			if mined := storedTx.Metadata.TimeMined; storedTx.Metadata.Status == model.StatusMined && mined != nil && (tx.mined == nil || *mined < *tx.mined) {
+				tx.mined = mined
+			}
+		}
+	}
+
+	// Only txs a client fetched can be reasoned about
+	var txs []*policyTx
+	for _, tx := range all {
+		if tx.tx.From == "" {
+			continue
+		}
+		tx.tip, _ = new(big.Int).SetString(tx.tx.MaxPriorityFee, 10)
+		tx.feeCap, _ = new(big.Int).SetString(tx.tx.MaxFeePerGas, 10)
+		if tx.tip == nil || tx.feeCap == nil {
+			continue
+		}
+		txs = append(txs, tx)
+	}
+	sort.Slice(txs, func(i, j int) bool {
+		if txs[i].firstSeen != txs[j].firstSeen {
+			return txs[i].firstSeen < txs[j].firstSeen
+		}
+		return txs[i].hash < txs[j].hash
+	})
+
+	senders := make(map[string][]*policyTx)
+	for _, tx := range txs {
+		senders[tx.tx.From] = append(senders[tx.tx.From], tx)
+	}
+
+	policies := make([]model.ClientPolicy, 0, len(clients))
+	for _, client := range clients {
+		a := &policyAnalysis{client: client, txs: txs, senders: senders}
+
+		policy := model.ClientPolicy{Client: client}
+		for _, tx := range txs {
+			if a.holds(tx) {
+				policy.Txs++
+			}
+		}
+		policy.Policies = []model.PolicyInference{
+			a.minTip(),
+			a.replacementBump(),
+			a.maxQueuedPerSender(),
+			a.typeAcceptance(model.PolicyBlobTxs, model.BlobTxType, "blob"),
+			a.nonceGaps(),
+			a.typeAcceptance(model.PolicySetCodeTxs, model.SetCodeTxType, "set code"),
+		}
+		policies = append(policies, policy)
+	}
+
+	return policies
+}
+
+// policyAnalysis infers the policies of one client
+type policyAnalysis struct {
+	client  string
+	txs     []*policyTx
+	senders map[string][]*policyTx // by sender, in the order they were first seen
+}
+
+func (a *policyAnalysis) holds(tx *policyTx) bool {
+	_, ok := tx.holders[a.client]
+	return ok
+}
+
+// received is when the client first saw the tx
+func (a *policyAnalysis) received(tx *policyTx) int64 {
+	return tx.holders[a.client].Metadata.TimeReceived
+}
+
+// missingNonces counts the lower nonces of the tx's sender that, at time at, the client didn't hold and
+// weren't mined yet, i.e. the nonce gap the tx was ahead of. Only nonces some client saw are known.
+func (a *policyAnalysis) missingNonces(tx *policyTx, at int64) int {
+	ready := make(map[uint64]bool)
+	for _, other := range a.senders[tx.tx.From] {
+		if other.tx.Nonce >= tx.tx.Nonce {
+			continue
+		}
+		if _, ok := ready[other.tx.Nonce]; !ok {
+			ready[other.tx.Nonce] = false
+		}
+		if (a.holds(other) && a.received(other) <= at) || (other.mined != nil && *other.mined <= at) {
+			ready[other.tx.Nonce] = true
+		}
+	}
+
+	missing := 0
+	for _, ok := range ready {
+		if !ok {
+			missing++
+		}
+	}
+	return missing
+}
+
+func (a *policyAnalysis) minTip() model.PolicyInference {
+	inference := model.PolicyInference{Policy: model.PolicyMinTip, Evidence: []model.PolicyEvidence{}}
+
+	var lowest *policyTx
+	for _, tx := range a.txs {
+		if a.holds(tx) && (lowest == nil || tx.tip.Cmp(lowest.tip) < 0) {
+			lowest = tx
+		}
+	}
+	if lowest == nil {
+		inference.Summary = "No txs held"
+		return inference
+	}
+
+	inference.Value = formatGwei(lowest.tip)
+	inference.Summary = fmt.Sprintf("Held txs with tips down to %s", inference.Value)
+	inference.Evidence = append(inference.Evidence, model.PolicyEvidence{
+		Hash:     lowest.hash,
+		Accepted: true,
+		Note:     fmt.Sprintf("held with a tip of %s", inference.Value),
+	})
+
+	// Cheaper txs other clients held bound the minimum from below
+	var below []*policyTx
+	for _, tx := range a.txs {
+		if !a.holds(tx) && tx.tip.Cmp(lowest.tip) < 0 {
+			below = append(below, tx)
+		}
+	}
+	sort.SliceStable(below, func(i, j int) bool { return below[i].tip.Cmp(below[j].tip) > 0 })
+	if len(below) > 0 {
+		inference.Summary += fmt.Sprintf(", never held %d cheaper txs other clients held, tips up to %s", len(below), formatGwei(below[0].tip))
+	}
+	for _, tx := range below[:min(len(below), maxPolicyEvidence)] {
+		inference.Evidence = append(inference.Evidence, model.PolicyEvidence{
+			Hash: tx.hash,
+			Note: fmt.Sprintf("tip of %s, held by %s", formatGwei(tx.tip), tx.heldBy()),
+		})
+	}
+	return inference
+}
+
+// replacement is a tx sent again with the same sender and nonce
+type replacement struct {
+	original *policyTx
+	next     *policyTx
+	bump     float64 // percent, the lower of the tip and fee cap bumps
+}
+
+func (a *policyAnalysis) replacementBump() model.PolicyInference {
+	inference := model.PolicyInference{Policy: model.PolicyReplacementBump, Evidence: []model.PolicyEvidence{}}
+
+	var accepted, rejected []replacement
+	for _, txs := range a.senders {
+		byNonce := make(map[uint64][]*policyTx)
+		for _, tx := range txs {
+			byNonce[tx.tx.Nonce] = append(byNonce[tx.tx.Nonce], tx)
+		}
+
+		for _, versions := range byNonce {
+			for i := 1; i < len(versions); i++ {
+				original, next := versions[i-1], versions[i]
+				if !a.holds(original) {
+					continue
+				}
+				r := replacement{original: original, next: next, bump: min(percentBump(original.tip, next.tip), percentBump(original.feeCap, next.feeCap))}
+				switch {
+				case !a.holds(next):
+					rejected = append(rejected, r)
+				case a.received(next) >= a.received(original):
+					accepted = append(accepted, r)
+				}
+			}
+		}
+	}
+
+	sort.Slice(accepted, func(i, j int) bool { return accepted[i].bump < accepted[j].bump })
+	sort.Slice(rejected, func(i, j int) bool { return rejected[i].bump > rejected[j].bump })
+
+	var summary []string
+	if len(accepted) > 0 {
+		inference.Value = formatPercent(accepted[0].bump)
+		summary = append(summary, fmt.Sprintf("Replaced %d txs, with bumps down to %s", len(accepted), inference.Value))
+	}
+	if len(rejected) > 0 {
+		summary = append(summary, fmt.Sprintf("never held %d replacements other clients held, bumps up to %s", len(rejected), formatPercent(rejected[0].bump)))
+	}
+	if len(summary) == 0 {
+		inference.Summary = "No replacements of held txs seen"
+		return inference
+	}
+	inference.Summary = strings.Join(summary, ", ")
+	if len(accepted) == 0 {
+		inference.Summary = strings.ToUpper(inference.Summary[:1]) + inference.Summary[1:]
+	}
+
+	for _, r := range accepted[:min(len(accepted), maxPolicyEvidence)] {
+		inference.Evidence = append(inference.Evidence, model.PolicyEvidence{
+			Hash:     r.next.hash,
+			Accepted: true,
+			Note:     fmt.Sprintf("replaced %s with a %s bump", r.original.hash, formatPercent(r.bump)),
+		})
+	}
+	for _, r := range rejected[:min(len(rejected), maxPolicyEvidence)] {
+		inference.Evidence = append(inference.Evidence, model.PolicyEvidence{
+			Hash: r.next.hash,
+			Note: fmt.Sprintf("%s bump over %s, held by %s", formatPercent(r.bump), r.original.hash, r.next.heldBy()),
+		})
+	}
+	return inference
+}
+
+func (a *policyAnalysis) maxQueuedPerSender() model.PolicyInference {
+	inference := model.PolicyInference{Policy: model.PolicyMaxQueuedPerSender, Evidence: []model.PolicyEvidence{}}
+
+	var (
+		most             string
+		queued, rejected []*policyTx
+	)
+	for sender, txs := range a.senders {
+		var held, missed []*policyTx
+		for _, tx := range txs {
+			if a.holds(tx) {
+				if tx.holders[a.client].Metadata.Status == model.StatusQueued || a.missingNonces(tx, a.received(tx)) > 0 {
+					held = append(held, tx)
+				}
+			} else if a.missingNonces(tx, tx.firstSeen) > 0 {
+				missed = append(missed, tx)
+			}
+		}
+		if len(held) > len(queued) || (len(held) == len(queued) && len(held) > 0 && sender < most) {
+			most, queued, rejected = sender, held, missed
+		}
+	}
+
+	if len(queued) == 0 {
+		inference.Summary = "Held no txs ahead of a nonce gap"
+		return inference
+	}
+
+	inference.Value = fmt.Sprint(len(queued))
+	inference.Summary = fmt.Sprintf("Held up to %d queued txs of one sender, %s", len(queued), most)
+	if len(rejected) > 0 {
+		inference.Summary += fmt.Sprintf(", never held %d more of its queued txs other clients held", len(rejected))
+	}
+	for _, tx := range queued[:min(len(queued), maxPolicyEvidence)] {
+		inference.Evidence = append(inference.Evidence, model.PolicyEvidence{
+			Hash:     tx.hash,
+			Accepted: true,
+			Note:     fmt.Sprintf("queued with nonce %d", tx.tx.Nonce),
+		})
+	}
+	for _, tx := range rejected[:min(len(rejected), maxPolicyEvidence)] {
+		inference.Evidence = append(inference.Evidence, model.PolicyEvidence{
+			Hash: tx.hash,
+			Note: fmt.Sprintf("nonce %d, held by %s", tx.tx.Nonce, tx.heldBy()),
+		})
+	}
+	return inference
+}
+
+// gappedTx is a tx sent ahead of missing nonces
+type gappedTx struct {
+	tx  *policyTx
+	gap int
+}
+
+func (a *policyAnalysis) nonceGaps() model.PolicyInference {
+	inference := model.PolicyInference{Policy: model.PolicyNonceGaps, Evidence: []model.PolicyEvidence{}}
+
+	var held, missed []gappedTx
+	for _, tx := range a.txs {
+		if a.holds(tx) {
+			if gap := a.missingNonces(tx, a.received(tx)); gap > 0 {
+				held = append(held, gappedTx{tx, gap})
+			}
+		} else if gap := a.missingNonces(tx, tx.firstSeen); gap > 0 {
+			missed = append(missed, gappedTx{tx, gap})
+		}
+	}
+	sort.SliceStable(held, func(i, j int) bool { return held[i].gap > held[j].gap })
+	sort.SliceStable(missed, func(i, j int) bool { return missed[i].gap < missed[j].gap })
+
+	switch {
+	case len(held) > 0:
+		inference.Value = fmt.Sprintf("up to %d", held[0].gap)
+		inference.Summary = fmt.Sprintf("Held %d txs ahead of missing nonces, gaps up to %d", len(held), held[0].gap)
+		if len(missed) > 0 {
+			inference.Summary += fmt.Sprintf(", never held %d gapped txs other clients held", len(missed))
+		}
+	case len(missed) > 0:
+		inference.Value = "none"
+		inference.Summary = fmt.Sprintf("Held no txs ahead of missing nonces, never held %d gapped txs other clients held", len(missed))
+	default:
+		inference.Summary = "No txs ahead of missing nonces seen"
+		return inference
+	}
+
+	for _, g := range held[:min(len(held), maxPolicyEvidence)] {
+		inference.Evidence = append(inference.Evidence, model.PolicyEvidence{
+			Hash:     g.tx.hash,
+			Accepted: true,
+			Note:     fmt.Sprintf("nonce %d held ahead of %d missing", g.tx.tx.Nonce, g.gap),
+		})
+	}
+	for _, g := range missed[:min(len(missed), maxPolicyEvidence)] {
+		inference.Evidence = append(inference.Evidence, model.PolicyEvidence{
+			Hash: g.tx.hash,
+			Note: fmt.Sprintf("nonce %d ahead of %d missing, held by %s", g.tx.tx.Nonce, g.gap, g.tx.heldBy()),
+		})
+	}
+	return inference
+}
+
+// typeAcceptance tells whether the client holds txs of a type other clients hold
+func (a *policyAnalysis) typeAcceptance(policy model.Policy, txType model.TransactionType, name string) model.PolicyInference {
+	inference := model.PolicyInference{Policy: policy, Evidence: []model.PolicyEvidence{}}
+
+	var held, missed []*policyTx
+	dropped := 0
+	for _, tx := range a.txs {
+		if model.TransactionType(tx.tx.Type) != txType {
+			continue
+		}
+		if !a.holds(tx) {
+			missed = append(missed, tx)
+			continue
+		}
+		held = append(held, tx)
+		if tx.holders[a.client].Metadata.Status == model.StatusDropped {
+			dropped++
+		}
+	}
+
+	switch {
+	case len(held) > 0:
+		inference.Value = "accepted"
+		inference.Summary = fmt.Sprintf("Held %d %s txs, %d of them dropped", len(held), name, dropped)
+		if len(missed) > 0 {
+			inference.Summary += fmt.Sprintf(", never held %d other clients held", len(missed))
+		}
+	case len(missed) > 0:
+		inference.Value = "rejected"
+		inference.Summary = fmt.Sprintf("Never held any of the %d %s txs other clients held", len(missed), name)
+	default:
+		inference.Summary = fmt.Sprintf("No %s txs seen", name)
+		return inference
+	}
+
+	for _, tx := range held[:min(len(held), maxPolicyEvidence)] {
+		inference.Evidence = append(inference.Evidence, model.PolicyEvidence{
+			Hash:     tx.hash,
+			Accepted: true,
+			Note:     string(tx.holders[a.client].Metadata.Status),
+		})
+	}
+	for _, tx := range missed[:min(len(missed), maxPolicyEvidence)] {
+		inference.Evidence = append(inference.Evidence, model.PolicyEvidence{
+			Hash: tx.hash,
+			Note: fmt.Sprintf("held by %s", tx.heldBy()),
+		})
+	}
+	return inference
+}
+
+// percentBump is how much higher next is than prev, in percent
+func percentBump(prev, next *big.Int) float64 {
+	if prev.Sign() == 0 {
+		if next.Sign() == 0 {
+			return 0
+		}
+		return 100
+	}
+	bump, _ := new(big.Rat).SetFrac(new(big.Int).Mul(new(big.Int).Sub(next, prev), big.NewInt(100)), prev).Float64()
+	return bump
+}
+
+func formatPercent(p float64) string {
+	return strings.TrimSuffix(strings.TrimRight(fmt.Sprintf("%.2f", p), "0"), ".") + "%"
+}
+
+// formatGwei formats a wei amount in gwei
+func formatGwei(wei *big.Int) string {
+	gwei := new(big.Rat).SetFrac(wei, big.NewInt(1_000_000_000)).FloatString(9)
+	return strings.TrimSuffix(strings.TrimRight(gwei, "0"), ".") + " gwei"
+}
@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"txpool-viz/internal/model"
+	"txpool-viz/utils"
+)
+
+// GetPrivateFlow returns the private flow of the latest limit analysed blocks, newest first
+func (ts *TransactionServiceImpl) GetPrivateFlow(ctx context.Context, limit int) ([]model.PrivateFlowReport, error) {
+	raw, err := ts.redis.ZRevRange(ctx, utils.RedisPrivateFlowKey(), 0, int64(limit)-1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("error reading private flow: %w", err)
+	}
+	return decodePrivateFlow(raw), nil
+}
+
+// GetBuilderPrivateFlow sums the private flow of the analysed blocks by coinbase, most private txs first
+func (ts *TransactionServiceImpl) GetBuilderPrivateFlow(ctx context.Context) ([]model.BuilderPrivateFlow, error) {
+	raw, err := ts.redis.ZRange(ctx, utils.RedisPrivateFlowKey(), 0, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("error reading private flow: %w", err)
+	}
+
+	byCoinbase := make(map[string]*model.BuilderPrivateFlow)
+	// Oldest first, so the builder name ends up being the latest
+	for _, block := range decodePrivateFlow(raw) {
+		builder, ok := byCoinbase[block.Coinbase]
+		if !ok {
+			builder = &model.BuilderPrivateFlow{Coinbase: block.Coinbase}
+			byCoinbase[block.Coinbase] = builder
+		}
+		if block.Builder != "" {
+			builder.Builder = block.Builder
+		}
+		builder.Blocks++
+		builder.Txs += block.Txs
+		builder.Private += block.Private
+		builder.Late += block.Late
+	}
+
+	builders := make([]model.BuilderPrivateFlow, 0, len(byCoinbase))
+	for _, builder := range byCoinbase {
+		if builder.Txs > 0 {
+			builder.PrivateRatio = float64(builder.Private) / float64(builder.Txs)
+		}
+		builders = append(builders, *builder)
+	}
+	sort.Slice(builders, func(i, j int) bool {
+		if builders[i].Private != builders[j].Private {
+			return builders[i].Private > builders[j].Private
+		}
+		return builders[i].Coinbase < builders[j].Coinbase
+	})
+	return builders, nil
+}
+
+func decodePrivateFlow(raw []string) []model.PrivateFlowReport {
+	reports := make([]model.PrivateFlowReport, 0, len(raw))
+	for _, data := range raw {
+		var report model.PrivateFlowReport
+		if err := json.Unmarshal([]byte(data), &report); err == nil {
+			reports = append(reports, report)
+		}
+	}
+	return reports
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 
+	"txpool-viz/internal/capture"
 	"txpool-viz/internal/config"
 	"txpool-viz/internal/logger"
 
@@ -12,12 +13,35 @@ import (
 )
 
 type Service struct {
-	Redis  *redis.Client
-	DB     string
-	Logger logger.Logger
+	Redis    *redis.Client
+	DB       string
+	Logger   logger.Logger
+	Recorder *capture.Recorder // nil unless a capture is being recorded
 }
 
+// NewService connects to the stores for a fresh instance, wiping the state of any previous run
 func NewService(cfg *config.Config) (*Service, error) {
+	// Initialize Postgres connection
+	conn := os.Getenv("POSTGRES_URL")
+	if conn == "" {
+		return nil, fmt.Errorf("POSTGRES_URL environment variable is not set")
+	}
+
+	srvc, err := OpenService(cfg)
+	if err != nil {
+		return nil, err
+	}
+	srvc.DB = conn
+
+	// Wipe redis keys for a fresh instance
+	srvc.Redis.FlushAll(context.Background())
+
+	return srvc, nil
+}
+
+// OpenService connects to the redis instance of a running txpool-viz without touching its state,
+// for commands that inspect what it has collected
+func OpenService(cfg *config.Config) (*Service, error) {
 	// Initialize redis client
 	redisUrl := os.Getenv("REDIS_URL")
 	if redisUrl == "" {
@@ -31,15 +55,6 @@ func NewService(cfg *config.Config) (*Service, error) {
 
 	redisClient := redis.NewClient(redisOptions)
 
-	// Wipe redis keys for a fresh instance
-	redisClient.FlushAll(context.Background())
-
-	// Initialize Postgres connection
-	conn := os.Getenv("POSTGRES_URL")
-	if conn == "" {
-		return nil, fmt.Errorf("POSTGRES_URL environment variable is not set")
-	}
-
 	devEnvironment := os.Getenv("ENV") != "prod"
 	if cfg.LogLevel == "" {
 		cfg.LogLevel = "info" // Default log level if not set
@@ -55,7 +70,7 @@ func NewService(cfg *config.Config) (*Service, error) {
 
 	return &Service{
 		Redis:  redisClient,
-		DB:     conn,
+		DB:     os.Getenv("POSTGRES_URL"),
 		Logger: logger,
 	}, nil
 }
@@ -0,0 +1,161 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"txpool-viz/internal/model"
+	"txpool-viz/utils"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// GetSetCodeReport counts each client's set code txs and authorizations and lists the txs the clients
+// disagree on, by status or by not having recorded them at all
+func (ts *TransactionServiceImpl) GetSetCodeReport(ctx context.Context) (model.SetCodeReport, error) {
+	report := model.SetCodeReport{Clients: []model.SetCodeClientStats{}, Divergent: []model.SetCodeTx{}}
+
+	clients := ts.clientNames()
+	byHash := make(map[string]*model.SetCodeTx)
+	for _, client := range clients {
+		records, err := ts.redis.HGetAll(ctx, utils.RedisClientMetaKey(client)).Result()
+		if err != nil {
+			return report, fmt.Errorf("error reading %s mempool: %w", client, err)
+		}
+
+		stats := model.SetCodeClientStats{Client: client, Statuses: make(map[model.TransactionStatus]int)}
+		for _, data := range records {
+			var stored model.StoredTransaction
+			if err := json.Unmarshal([]byte(data), &stored); err != nil || model.TransactionType(stored.Tx.Type) != model.SetCodeTxType {
+				continue
+			}
+			stats.Txs++
+			stats.Statuses[stored.Metadata.Status]++
+			for _, auth := range stored.Tx.Authorizations {
+				stats.Authorizations++
+				switch {
+				case auth.Authority == "":
+					stats.InvalidAuthorizations++
+				case strings.EqualFold(auth.Authority, stored.Tx.From):
+					stats.SelfSponsored++
+				}
+			}
+
+			tx, ok := byHash[stored.Hash]
+			if !ok {
+				tx = newSetCodeTx(stored)
+				byHash[stored.Hash] = tx
+			}
+			tx.Clients[client] = stored.Metadata.Status
+		}
+		report.Clients = append(report.Clients, stats)
+	}
+
+	for _, tx := range byHash {
+		tx.Unseen = unseenBy(clients, tx)
+		if len(tx.Unseen) > 0 || !sameStatus(tx.Clients) {
+			report.Divergent = append(report.Divergent, *tx)
+		}
+	}
+	sort.Slice(report.Divergent, func(i, j int) bool { return report.Divergent[i].Hash < report.Divergent[j].Hash })
+	return report, nil
+}
+
+// GetDelegations returns every set code tx any client recorded with an authorization to the delegate
+func (ts *TransactionServiceImpl) GetDelegations(ctx context.Context, delegate string) (model.DelegationView, error) {
+	view := model.DelegationView{Delegate: common.HexToAddress(delegate).Hex(), Txs: []model.SetCodeTx{}}
+
+	clients := ts.clientNames()
+	var hashes []string
+	seen := make(map[string]bool)
+	for _, client := range clients {
+		members, err := ts.redis.SMembers(ctx, utils.RedisDelegateIndexKey(client, view.Delegate)).Result()
+		if err != nil {
+			return view, fmt.Errorf("error reading %s delegations: %w", client, err)
+		}
+		for _, hash := range members {
+			if !seen[hash] {
+				seen[hash] = true
+				hashes = append(hashes, hash)
+			}
+		}
+	}
+	if len(hashes) == 0 {
+		return view, nil
+	}
+	sort.Strings(hashes)
+
+	byHash := make(map[string]*model.SetCodeTx)
+	for _, client := range clients {
+		records, err := ts.redis.HMGet(ctx, utils.RedisClientMetaKey(client), hashes...).Result()
+		if err != nil {
+			return view, fmt.Errorf("error reading %s mempool: %w", client, err)
+		}
+		for _, record := range records {
+			data, ok := record.(string)
+			if !ok {
+				continue
+			}
+			var stored model.StoredTransaction
+			if err := json.Unmarshal([]byte(data), &stored); err != nil {
+				continue
+			}
+			tx, ok := byHash[stored.Hash]
+			if !ok {
+				tx = newSetCodeTx(stored)
+				byHash[stored.Hash] = tx
+			}
+			tx.Clients[client] = stored.Metadata.Status
+		}
+	}
+
+	for _, hash := range hashes {
+		if tx, ok := byHash[hash]; ok {
+			tx.Unseen = unseenBy(clients, tx)
+			view.Txs = append(view.Txs, *tx)
+		}
+	}
+	return view, nil
+}
+
+// newSetCodeTx starts a tx's cross-client record from one client's copy
+func newSetCodeTx(stored model.StoredTransaction) *model.SetCodeTx {
+	tx := &model.SetCodeTx{
+		Hash:           stored.Hash,
+		From:           stored.Tx.From,
+		Nonce:          stored.Tx.Nonce,
+		Authorizations: stored.Tx.Authorizations,
+		Clients:        make(map[string]model.TransactionStatus),
+	}
+	if tx.Authorizations == nil {
+		tx.Authorizations = []model.Authorization{}
+	}
+	return tx
+}
+
+// unseenBy lists the clients without a record of the tx
+func unseenBy(clients []string, tx *model.SetCodeTx) []string {
+	unseen := []string{}
+	for _, client := range clients {
+		if _, ok := tx.Clients[client]; !ok {
+			unseen = append(unseen, client)
+		}
+	}
+	return unseen
+}
+
+// sameStatus reports whether every client gives the tx the same status
+func sameStatus(statuses map[string]model.TransactionStatus) bool {
+	var first model.TransactionStatus
+	for _, status := range statuses {
+		if first == "" {
+			first = status
+		} else if status != first {
+			return false
+		}
+	}
+	return true
+}
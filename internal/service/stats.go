@@ -0,0 +1,62 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"txpool-viz/internal/model"
+	"txpool-viz/internal/stats"
+	"txpool-viz/utils"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrUnknownResolution is returned for a time series resolution that isn't kept
+var ErrUnknownResolution = errors.New("unknown resolution")
+
+// GetTimeSeries returns the pool samples of a resolution between the unix times from and to, 0 leaving
+// that end open. Only client's stats are kept when it is set.
+func (ts *TransactionServiceImpl) GetTimeSeries(ctx context.Context, resolution string, from, to int64, client string) (model.TimeSeries, error) {
+	res, ok := stats.FindResolution(resolution)
+	if !ok {
+		return model.TimeSeries{}, fmt.Errorf("%w %q", ErrUnknownResolution, resolution)
+	}
+
+	lower, upper := "-inf", "+inf"
+	if from > 0 {
+		lower = strconv.FormatInt(from, 10)
+	}
+	if to > 0 {
+		upper = strconv.FormatInt(to, 10)
+	}
+
+	raw, err := ts.redis.ZRangeByScore(ctx, utils.RedisStatsKey(res.Name), &redis.ZRangeBy{Min: lower, Max: upper}).Result()
+	if err != nil {
+		return model.TimeSeries{}, fmt.Errorf("error reading %s time series: %w", res.Name, err)
+	}
+
+	series := model.TimeSeries{
+		Resolution: res.Name,
+		Step:       int64(res.Step / time.Second),
+		Samples:    make([]model.PoolSample, 0, len(raw)),
+	}
+	for _, data := range raw {
+		var sample model.PoolSample
+		if err := json.Unmarshal([]byte(data), &sample); err != nil {
+			continue
+		}
+		if client != "" {
+			clientStats, ok := sample.Clients[client]
+			sample.Clients = map[string]model.ClientPoolStats{}
+			if ok {
+				sample.Clients[client] = clientStats
+			}
+		}
+		series.Samples = append(series.Samples, sample)
+	}
+	return series, nil
+}
@@ -3,6 +3,12 @@ package service
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"txpool-viz/internal/calldata"
+	"txpool-viz/internal/clock"
 	"txpool-viz/internal/config"
 	"txpool-viz/internal/logger"
 	"txpool-viz/internal/model"
@@ -13,20 +19,51 @@ import (
 )
 
 type TransactionServiceImpl struct {
-	redis     *redis.Client
-	logger    logger.Logger
+	redis  *redis.Client
+	logger logger.Logger
+	clock  clock.Clock // what pending times are measured against, the capture time in a replay
+
+	mu        sync.RWMutex
 	endpoints []config.Endpoint
+	calls     *calldata.Registry
 }
 
 // NewTransactionService creates a new transaction service
-func NewTransactionService(ctx context.Context, r *redis.Client, l logger.Logger, cfgEndpoints []config.Endpoint) *TransactionServiceImpl {
+func NewTransactionService(ctx context.Context, r *redis.Client, l logger.Logger, clk clock.Clock, cfgEndpoints []config.Endpoint) *TransactionServiceImpl {
 	return &TransactionServiceImpl{
 		redis:     r,
 		logger:    l,
+		clock:     clk,
 		endpoints: cfgEndpoints,
 	}
 }
 
+// SetEndpoints replaces the endpoints whose records are read, e.g. after a config reload
+func (ts *TransactionServiceImpl) SetEndpoints(endpoints []config.Endpoint) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	ts.endpoints = endpoints
+}
+
+// SetCallRegistry sets the signatures tx details decode calldata against, nil leaves it undecoded
+func (ts *TransactionServiceImpl) SetCallRegistry(calls *calldata.Registry) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	ts.calls = calls
+}
+
+// clientNames returns the names of the current endpoints, the first being the primary client
+func (ts *TransactionServiceImpl) clientNames() []string {
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+
+	clients := make([]string, len(ts.endpoints))
+	for i, ep := range ts.endpoints {
+		clients[i] = ep.Name
+	}
+	return clients
+}
+
 func (ts *TransactionServiceImpl) GetLatestNTransactions(ctx context.Context, n int64) ([]model.ApiTxResponse, error) {
 	start := -n
 	stop := int64(-1)
@@ -70,7 +107,7 @@ func (ts *TransactionServiceImpl) GetLatestTxSummaries(ctx context.Context, n in
 		return nil, err
 	}
 
-	primary := ts.endpoints[0].Name
+	primary := ts.clientNames()[0]
 	metaKey := utils.RedisClientMetaKey(primary)
 
 	var out []model.TxSummary
@@ -111,13 +148,65 @@ func (ts *TransactionServiceImpl) GetLatestTxSummaries(ctx context.Context, n in
 }
 
 func (ts *TransactionServiceImpl) GetTxDetails(ctx context.Context, txHash string) (model.ApiTxResponse, error) {
-	raw := make(map[string]model.StoredTransaction, len(ts.endpoints))
+	raw, err := ts.GetClientRecords(ctx, txHash)
+	if err != nil {
+		return model.ApiTxResponse{}, err
+	}
 
-	for _, endpoint := range ts.endpoints {
-		metaKey := utils.RedisClientMetaKey(endpoint.Name)
+	// flatten each into maps
+	txMaps := make(map[string]map[string]interface{}, len(raw))
+	metaMaps := make(map[string]map[string]interface{}, len(raw))
+	for client, stx := range raw {
+		txMaps[client] = toMapTx(stx.Tx)
+		metaMaps[client] = toMapMeta(stx.Metadata)
+	}
+
+	clients := ts.clientNames()
+	first := clients[0]
+	txRes := Compute(txMaps, first)
+	metaRes := Compute(metaMaps, first)
+
+	resp := model.ApiTxResponse{
+		Hash:    txHash,
+		Clients: clients,
+		Common: model.TxBlock{
+			Tx:       txRes.Common,
+			Metadata: metaRes.Common,
+		},
+		Diff: model.TxDiff{
+			Tx:       txRes.Diff,
+			Metadata: metaRes.Diff,
+		},
+	}
+
+	ts.mu.RLock()
+	calls := ts.calls
+	ts.mu.RUnlock()
+	stx, ok := raw[first]
+	if !ok {
+		for _, client := range clients {
+			if stx, ok = raw[client]; ok {
+				break
+			}
+		}
+	}
+	if ok && calls != nil && !stx.Tx.IsContractCreation {
+		resp.Call = calls.Decode(stx.Tx.Data)
+	}
+
+	return resp, nil
+}
+
+// GetClientRecords returns the record of a tx kept for each client that saw it
+func (ts *TransactionServiceImpl) GetClientRecords(ctx context.Context, txHash string) (map[string]model.StoredTransaction, error) {
+	clients := ts.clientNames()
+	raw := make(map[string]model.StoredTransaction, len(clients))
+
+	for _, client := range clients {
+		metaKey := utils.RedisClientMetaKey(client)
 
 		// retrieve per client record
-		val, err := ts.redis.HGet(context.Background(), metaKey, txHash).Result()
+		val, err := ts.redis.HGet(ctx, metaKey, txHash).Result()
 		if err != nil {
 			if err == redis.Nil {
 				ts.logger.Debug("No record for %s in %s\n", txHash, metaKey)
@@ -132,42 +221,106 @@ func (ts *TransactionServiceImpl) GetTxDetails(ctx context.Context, txHash strin
 		err = json.Unmarshal([]byte(val), &storedTx)
 		if err != nil {
 			ts.logger.Error("Failed to unmarshal transaction: %v\n", err)
-			return model.ApiTxResponse{}, err
+			return nil, err
 		}
 
-		raw[endpoint.Name] = storedTx
+		raw[client] = storedTx
 	}
 
-	// flatten each into maps
-	txMaps := make(map[string]map[string]interface{}, len(raw))
-	metaMaps := make(map[string]map[string]interface{}, len(raw))
-	for client, stx := range raw {
-		txMaps[client] = toMapTx(stx.Tx)
-		metaMaps[client] = toMapMeta(stx.Metadata)
-	}
+	return raw, nil
+}
 
-	first := ts.endpoints[0].Name
-	txRes := Compute(txMaps, first)
-	metaRes := Compute(metaMaps, first)
+// GetLatestTxHashes returns the hashes of the n most recently received txs, newest last
+func (ts *TransactionServiceImpl) GetLatestTxHashes(ctx context.Context, n int64) ([]string, error) {
+	return ts.redis.ZRange(ctx, utils.RedisUniversalKey(), -n, -1).Result()
+}
 
-	// assemble clients
-	clients := make([]string, len(ts.endpoints))
-	for i, ep := range ts.endpoints {
-		clients[i] = ep.Name
+// GetReorgs returns the newest reorg events, only those of endpoint when it is set
+func (ts *TransactionServiceImpl) GetReorgs(ctx context.Context, endpoint string, limit int) ([]model.ReorgEvent, error) {
+	raw, err := ts.redis.LRange(ctx, utils.RedisReorgLogKey(), 0, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("error reading reorg log: %w", err)
 	}
 
-	resp := model.ApiTxResponse{
-		Hash:    txHash,
+	events := []model.ReorgEvent{}
+	for _, data := range raw {
+		var event model.ReorgEvent
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			continue
+		}
+		if endpoint != "" && event.Endpoint != endpoint {
+			continue
+		}
+		events = append(events, event)
+		if len(events) == limit {
+			break
+		}
+	}
+	return events, nil
+}
+
+// GetPoolDiff groups the txs with one of the given statuses by the clients holding them.
+// Txs held by every client are only counted.
+func (ts *TransactionServiceImpl) GetPoolDiff(ctx context.Context, statuses []model.TransactionStatus) (model.PoolDiff, error) {
+	clients := ts.clientNames()
+
+	diff := model.PoolDiff{
 		Clients: clients,
-		Common: model.TxBlock{
-			Tx:       txRes.Common,
-			Metadata: metaRes.Common,
-		},
-		Diff: model.TxDiff{
-			Tx:       txRes.Diff,
-			Metadata: metaRes.Diff,
-		},
+		Sizes:   make(map[string]int, len(clients)),
+		Groups:  []model.PoolDiffGroup{},
+	}
+	for _, status := range statuses {
+		diff.Statuses = append(diff.Statuses, string(status))
 	}
 
-	return resp, nil
+	holders := make(map[string][]string)
+	for _, client := range clients {
+		records, err := ts.redis.HGetAll(ctx, utils.RedisClientMetaKey(client)).Result()
+		if err != nil {
+			return model.PoolDiff{}, fmt.Errorf("error reading %s mempool: %w", client, err)
+		}
+
+		for hash, val := range records {
+			var storedTx model.StoredTransaction
+			if err := json.Unmarshal([]byte(val), &storedTx); err != nil {
+				continue
+			}
+			if len(statuses) > 0 && !slices.Contains(statuses, storedTx.Metadata.Status) {
+				continue
+			}
+			holders[hash] = append(holders[hash], client)
+			diff.Sizes[client]++
+		}
+	}
+
+	groups := make(map[string]*model.PoolDiffGroup)
+	for hash, seenBy := range holders {
+		if len(seenBy) == len(clients) {
+			diff.Shared++
+			continue
+		}
+
+		key := strings.Join(seenBy, ",")
+		group, ok := groups[key]
+		if !ok {
+			group = &model.PoolDiffGroup{SeenBy: seenBy}
+			for _, client := range clients {
+				if !slices.Contains(seenBy, client) {
+					group.MissingIn = append(group.MissingIn, client)
+				}
+			}
+			groups[key] = group
+		}
+		group.Hashes = append(group.Hashes, hash)
+	}
+
+	for _, group := range groups {
+		sort.Strings(group.Hashes)
+		diff.Groups = append(diff.Groups, *group)
+	}
+	sort.Slice(diff.Groups, func(i, j int) bool {
+		return len(diff.Groups[i].Hashes) > len(diff.Groups[j].Hashes)
+	})
+
+	return diff, nil
 }
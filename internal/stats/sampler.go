@@ -0,0 +1,234 @@
+package stats
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"txpool-viz/internal/config"
+	"txpool-viz/internal/logger"
+	"txpool-viz/internal/model"
+	"txpool-viz/internal/storage"
+	"txpool-viz/utils"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Resolution is a time series kept at one granularity. Coarser ones keep the average of the samples
+// of each step.
+type Resolution struct {
+	Name      string
+	Step      time.Duration // 0 keeps every sample
+	Retention time.Duration
+}
+
+// Resolutions are the granularities the samples are stored at, finest first
+var Resolutions = []Resolution{
+	{Name: "raw", Retention: time.Hour},
+	{Name: "1m", Step: time.Minute, Retention: 24 * time.Hour},
+	{Name: "5m", Step: 5 * time.Minute, Retention: 7 * 24 * time.Hour},
+	{Name: "1h", Step: time.Hour, Retention: 30 * 24 * time.Hour},
+}
+
+// FindResolution returns the resolution with the given name
+func FindResolution(name string) (Resolution, bool) {
+	for _, res := range Resolutions {
+		if res.Name == name {
+			return res, true
+		}
+	}
+	return Resolution{}, false
+}
+
+// bucket is the score of a sample taken at unix time ts
+func (r Resolution) bucket(ts int64) int64 {
+	step := int64(r.Step / time.Second)
+	if step == 0 {
+		return ts
+	}
+	return ts - ts%step
+}
+
+// Sampler periodically records each client's pool size by status and type, its gas demand and tips
+type Sampler struct {
+	redis    *redis.Client
+	logger   logger.Logger
+	interval time.Duration
+
+	mu      sync.Mutex
+	clients []string
+}
+
+// NewSampler creates a sampler of the configured endpoints' pools
+func NewSampler(r *redis.Client, l logger.Logger, cfg *config.Config) *Sampler {
+	interval := config.DefaultStatsInterval
+	if cfg.Stats.Interval != "" {
+		d, err := time.ParseDuration(cfg.Stats.Interval)
+		if err != nil || d <= 0 {
+			l.Warn("Invalid stats interval, using the default", logger.Fields{"interval": cfg.Stats.Interval})
+		} else {
+			interval = d
+		}
+	}
+
+	s := &Sampler{redis: r, logger: l, interval: interval}
+	s.SetEndpoints(cfg.Endpoints)
+	return s
+}
+
+// SetEndpoints replaces the endpoints whose pools are sampled, e.g. after a config reload
+func (s *Sampler) SetEndpoints(endpoints []config.Endpoint) {
+	clients := make([]string, len(endpoints))
+	for i, endpoint := range endpoints {
+		clients[i] = endpoint.Name
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clients = clients
+}
+
+// Run samples the pools every interval until ctx is cancelled
+func (s *Sampler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			sample, err := s.sample(ctx, now.Unix())
+			if err != nil {
+				if ctx.Err() == nil {
+					s.logger.Error("Error sampling pools", logger.Fields{"error": err.Error()})
+				}
+				continue
+			}
+			if err := s.store(ctx, sample); err != nil && ctx.Err() == nil {
+				s.logger.Error("Error storing pool sample", logger.Fields{"error": err.Error()})
+			}
+		}
+	}
+}
+
+// sample reads every client's pool from the counts storage keeps as txs change
+func (s *Sampler) sample(ctx context.Context, ts int64) (model.PoolSample, error) {
+	s.mu.Lock()
+	clients := s.clients
+	s.mu.Unlock()
+
+	sample := model.PoolSample{Timestamp: ts, Clients: make(map[string]model.ClientPoolStats, len(clients))}
+	for _, client := range clients {
+		stats, err := storage.PoolStats(ctx, s.redis, client)
+		if err != nil {
+			return model.PoolSample{}, err
+		}
+		sample.Clients[client] = stats
+	}
+
+	return sample, nil
+}
+
+// store adds the sample to the finest resolution and recomputes its step in every coarser one from the
+// samples of the resolution before it. Samples past each resolution's retention are dropped.
+func (s *Sampler) store(ctx context.Context, sample model.PoolSample) error {
+	for i, res := range Resolutions {
+		bucket := res.bucket(sample.Timestamp)
+
+		stepSample := sample
+		if i > 0 {
+			finer := Resolutions[i-1]
+			raw, err := s.redis.ZRangeByScore(ctx, utils.RedisStatsKey(finer.Name), &redis.ZRangeBy{
+				Min: strconv.FormatInt(bucket, 10),
+				Max: "(" + strconv.FormatInt(bucket+int64(res.Step/time.Second), 10),
+			}).Result()
+			if err != nil {
+				return fmt.Errorf("error reading %s samples: %w", finer.Name, err)
+			}
+			stepSample = average(bucket, raw)
+		}
+
+		data, err := json.Marshal(stepSample)
+		if err != nil {
+			return err
+		}
+
+		key := utils.RedisStatsKey(res.Name)
+		score := strconv.FormatInt(bucket, 10)
+		pipe := s.redis.TxPipeline()
+		pipe.ZRemRangeByScore(ctx, key, score, score)
+		pipe.ZAdd(ctx, key, redis.Z{Score: float64(bucket), Member: data})
+		pipe.ZRemRangeByScore(ctx, key, "-inf", "("+strconv.FormatInt(sample.Timestamp-int64(res.Retention/time.Second), 10))
+		if _, err := pipe.Exec(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// average returns the sample of a step at bucket, each client's stats averaged over the samples
+// that include the client
+func average(bucket int64, raw []string) model.PoolSample {
+	type sums struct {
+		samples           int
+		byStatus, byType  map[string]int
+		gas               uint64
+		blobs             int
+		medianTip, p90Tip float64
+	}
+	totals := make(map[string]*sums)
+
+	for _, data := range raw {
+		var sample model.PoolSample
+		if err := json.Unmarshal([]byte(data), &sample); err != nil {
+			continue
+		}
+		for client, stats := range sample.Clients {
+			t, ok := totals[client]
+			if !ok {
+				t = &sums{byStatus: make(map[string]int), byType: make(map[string]int)}
+				totals[client] = t
+			}
+			t.samples++
+			for status, count := range stats.ByStatus {
+				t.byStatus[status] += count
+			}
+			for txType, count := range stats.ByType {
+				t.byType[txType] += count
+			}
+			t.gas += stats.GasDemand
+			t.blobs += stats.Blobs
+			t.medianTip += stats.MedianTipGwei
+			t.p90Tip += stats.P90TipGwei
+		}
+	}
+
+	out := model.PoolSample{Timestamp: bucket, Clients: make(map[string]model.ClientPoolStats, len(totals))}
+	for client, t := range totals {
+		n := t.samples
+		stats := model.ClientPoolStats{
+			ByStatus:      make(map[string]int, len(t.byStatus)),
+			ByType:        make(map[string]int, len(t.byType)),
+			GasDemand:     (t.gas + uint64(n)/2) / uint64(n),
+			Blobs:         (t.blobs + n/2) / n,
+			MedianTipGwei: t.medianTip / float64(n),
+			P90TipGwei:    t.p90Tip / float64(n),
+		}
+		for status, count := range t.byStatus {
+			if avg := (count + n/2) / n; avg > 0 {
+				stats.ByStatus[status] = avg
+			}
+		}
+		for txType, count := range t.byType {
+			if avg := (count + n/2) / n; avg > 0 {
+				stats.ByType[txType] = avg
+			}
+		}
+		out.Clients[client] = stats
+	}
+	return out
+}
@@ -0,0 +1,46 @@
+package storage
+
+import (
+	"context"
+	"sort"
+
+	"txpool-viz/utils"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/redis/go-redis/v9"
+)
+
+// InclusionListTransactions returns the txs any validator listed for a slot, ordered by hash. redis.Nil
+// is returned when no list was stored for the slot.
+func InclusionListTransactions(ctx context.Context, rdb *redis.Client, slot string) ([]*types.Transaction, error) {
+	listed, err := rdb.HGetAll(ctx, utils.RedisInclusionListListedKey(slot)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(listed) == 0 {
+		return nil, redis.Nil
+	}
+
+	hashes := make([]string, 0, len(listed))
+	for hash := range listed {
+		hashes = append(hashes, hash)
+	}
+	sort.Strings(hashes)
+
+	txs := make([]*types.Transaction, 0, len(hashes))
+	for _, hash := range hashes {
+		raw, err := hexutil.Decode(listed[hash])
+		if err != nil {
+			return nil, err
+		}
+
+		tx := new(types.Transaction)
+		if err := tx.UnmarshalBinary(raw); err != nil {
+			return nil, err
+		}
+		txs = append(txs, tx)
+	}
+	return txs, nil
+}
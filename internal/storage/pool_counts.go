@@ -0,0 +1,175 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"txpool-viz/internal/model"
+	"txpool-viz/utils"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Fields of a client's counts hash. Txs are counted by status, held txs by type, gas and blobs too.
+const (
+	countStatusPrefix = "status:"
+	countTypePrefix   = "type:"
+	countGas          = "gas"
+	countBlobs        = "blobs"
+)
+
+// inPool are the statuses of txs a client still holds
+var inPool = map[model.TransactionStatus]bool{
+	model.StatusReceived: true,
+	model.StatusPending:  true,
+	model.StatusQueued:   true,
+}
+
+// held reports whether a client still holds a tx whose body was fetched. Txs not fetched yet have no
+// body to count.
+func held(tx *model.StoredTransaction) bool {
+	return inPool[tx.Metadata.Status] && tx.Tx.From != ""
+}
+
+// poolCounts returns what a stored tx adds to its client's counts
+func poolCounts(tx *model.StoredTransaction) map[string]int64 {
+	counts := map[string]int64{countStatusPrefix + string(tx.Metadata.Status): 1}
+	if held(tx) {
+		counts[countTypePrefix+model.TransactionType(tx.Tx.Type).String()] = 1
+		counts[countGas] = int64(tx.Tx.Gas)
+		counts[countBlobs] = int64(tx.Tx.BlobCount)
+	}
+	return counts
+}
+
+// saveCounted replaces a tx record, if it still holds what it was read as, along with the count
+// deltas and the tip index change the update makes. KEYS are the meta hash, the counts hash and the tip
+// index. ARGV are the tx hash, the record read, the updated record, its tip score or "" to leave the
+// index, then field and delta pairs. It returns 0 when the record changed since it was read.
+var saveCounted = redis.NewScript(`
+if redis.call('HGET', KEYS[1], ARGV[1]) ~= ARGV[2] then
+	return 0
+end
+redis.call('HSET', KEYS[1], ARGV[1], ARGV[3])
+if ARGV[4] == '' then
+	redis.call('ZREM', KEYS[3], ARGV[1])
+else
+	redis.call('ZADD', KEYS[3], ARGV[4], ARGV[1])
+end
+for i = 5, #ARGV, 2 do
+	redis.call('HINCRBY', KEYS[2], ARGV[i], ARGV[i + 1])
+end
+return 1
+`)
+
+// poolDeltas returns the count changes of a tx updated from before to after
+func poolDeltas(before, after *model.StoredTransaction) map[string]int64 {
+	deltas := poolCounts(after)
+	for field, count := range poolCounts(before) {
+		deltas[field] -= count
+	}
+	for field, delta := range deltas {
+		if delta == 0 {
+			delete(deltas, field)
+		}
+	}
+	return deltas
+}
+
+// tipScore returns a tx's score in the tip index, false when it isn't held with a tip
+func tipScore(tx *model.StoredTransaction) (float64, bool) {
+	tip, ok := new(big.Int).SetString(tx.Tx.MaxPriorityFee, 10)
+	if !held(tx) || !ok {
+		return 0, false
+	}
+	score, _ := new(big.Float).SetInt(tip).Float64()
+	return score, true
+}
+
+// saveUpdate stores after over the record read as raw, counting the change from before. It reports
+// false, storing nothing, when the record changed since it was read.
+func (s *ClientStorage) saveUpdate(ctx context.Context, txHash, raw string, before, after *model.StoredTransaction) (bool, error) {
+	updated, err := json.Marshal(after)
+	if err != nil {
+		return false, fmt.Errorf("error marshaling updated metadata: %w", err)
+	}
+
+	tip := ""
+	if score, ok := tipScore(after); ok {
+		tip = strconv.FormatFloat(score, 'f', -1, 64)
+	}
+	args := []any{txHash, raw, updated, tip}
+	for field, delta := range poolDeltas(before, after) {
+		args = append(args, field, delta)
+	}
+
+	keys := []string{s.MetaKey, utils.RedisPoolCountsKey(s.client), utils.RedisTipIndexKey(s.client)}
+	saved, err := saveCounted.Run(ctx, s.rdb, keys, args...).Int()
+	if err != nil {
+		return false, err
+	}
+	return saved == 1, nil
+}
+
+// PoolStats reads a client's pool from its counts and tip index, without reading the txs
+func PoolStats(ctx context.Context, rdb *redis.Client, client string) (model.ClientPoolStats, error) {
+	counts, err := rdb.HGetAll(ctx, utils.RedisPoolCountsKey(client)).Result()
+	if err != nil {
+		return model.ClientPoolStats{}, fmt.Errorf("error reading %s pool counts: %w", client, err)
+	}
+
+	stats := model.ClientPoolStats{ByStatus: make(map[string]int), ByType: make(map[string]int)}
+	for field, value := range counts {
+		count, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || count <= 0 {
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(field, countStatusPrefix):
+			stats.ByStatus[strings.TrimPrefix(field, countStatusPrefix)] = int(count)
+		case strings.HasPrefix(field, countTypePrefix):
+			stats.ByType[strings.TrimPrefix(field, countTypePrefix)] = int(count)
+		case field == countGas:
+			stats.GasDemand = uint64(count)
+		case field == countBlobs:
+			stats.Blobs = int(count)
+		}
+	}
+
+	tipKey := utils.RedisTipIndexKey(client)
+	tips, err := rdb.ZCard(ctx, tipKey).Result()
+	if err != nil {
+		return model.ClientPoolStats{}, fmt.Errorf("error reading %s tips: %w", client, err)
+	}
+	if tips == 0 {
+		return stats, nil
+	}
+
+	median := rdb.ZRangeWithScores(ctx, tipKey, percentileRank(tips, 50), percentileRank(tips, 50))
+	p90 := rdb.ZRangeWithScores(ctx, tipKey, percentileRank(tips, 90), percentileRank(tips, 90))
+	if err := median.Err(); err != nil {
+		return model.ClientPoolStats{}, fmt.Errorf("error reading %s tips: %w", client, err)
+	}
+	if err := p90.Err(); err != nil {
+		return model.ClientPoolStats{}, fmt.Errorf("error reading %s tips: %w", client, err)
+	}
+	if len(median.Val()) > 0 {
+		stats.MedianTipGwei = median.Val()[0].Score / 1e9
+	}
+	if len(p90.Val()) > 0 {
+		stats.P90TipGwei = p90.Val()[0].Score / 1e9
+	}
+
+	return stats, nil
+}
+
+// percentileRank returns the zero based index of the nearest-rank percentile p of n sorted values
+func percentileRank(n int64, p int64) int64 {
+	rank := (p*n + 99) / 100
+	return max(rank-1, 0)
+}
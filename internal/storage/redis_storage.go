@@ -8,6 +8,7 @@ import (
 	"math/big"
 	"regexp"
 	"strings"
+	"time"
 	"txpool-viz/internal/logger"
 	"txpool-viz/internal/model"
 	"txpool-viz/utils"
@@ -26,19 +27,19 @@ type ClientStorage struct {
 	client string
 
 	// Redis key references
-	MetaKey   string
-	TxKey     string
-	StreamKey string
+	MetaKey     string
+	TxKey       string
+	ScheduleKey string
 }
 
 // NewStorage creates a new storage instance
 func NewClientStorage(client string, rdb *redis.Client, l logger.Logger) *ClientStorage {
 	return &ClientStorage{
-		rdb:       rdb,
-		logger:    l,
-		client:    client,
-		MetaKey:   utils.RedisClientMetaKey(client),
-		StreamKey: utils.RedisStreamKey(client),
+		rdb:         rdb,
+		logger:      l,
+		client:      client,
+		MetaKey:     utils.RedisClientMetaKey(client),
+		ScheduleKey: utils.RedisScheduleKey(client),
 	}
 }
 
@@ -59,49 +60,75 @@ func (s *ClientStorage) StoreTransaction(ctx context.Context, txHash string, loc
 		return fmt.Errorf("error marshaling metadata: %w", err)
 	}
 
-	// 3. Store metadata entry in Redis
-	err = s.rdb.HSet(ctx, s.MetaKey, txHash, txJsonMetaData).Err()
+	// 3. Store metadata entry in Redis. A tx seen again keeps its record, its next check updates it.
+	created, err := s.rdb.HSetNX(ctx, s.MetaKey, txHash, txJsonMetaData).Result()
 	if err != nil {
 		return fmt.Errorf("error creating metadata entry txHash:%s, error: %s", txHash, err.Error())
 	}
 
+	// A received tx has no body yet, so it only adds to the counts and isn't in the tip index
+	if created {
+		pipe := s.rdb.Pipeline()
+		for field, delta := range poolDeltas(&model.StoredTransaction{}, txMetaData) {
+			pipe.HIncrBy(ctx, utils.RedisPoolCountsKey(s.client), field, delta)
+		}
+		if _, err := pipe.Exec(ctx); err != nil {
+			return fmt.Errorf("error counting txHash:%s, error: %s", txHash, err.Error())
+		}
+	}
+
 	return nil
 }
 
+// updateStoredTx applies updateFn to a tx record and stores it with its pool counts in one step. When
+// another update stores the record first, updateFn is applied again to the new record.
 func (s *ClientStorage) updateStoredTx(ctx context.Context, txHash string, updateFn func(*model.StoredTransaction) error) error {
-	val, err := s.rdb.HGet(ctx, s.MetaKey, txHash).Result()
-	if err == redis.Nil {
-		s.logger.Debug("Transaction metadata not found", "txHash", txHash)
-		return nil
-	} else if err != nil {
-		return fmt.Errorf("failed to get transaction metadata from %s: %w", s.MetaKey, err)
-	}
-
-	var storedTx model.StoredTransaction
-	if err := json.Unmarshal([]byte(val), &storedTx); err != nil {
-		return fmt.Errorf("failed to unmarshal transaction metadata: %w", err)
-	}
+	for range maxUpdateAttempts {
+		val, err := s.rdb.HGet(ctx, s.MetaKey, txHash).Result()
+		if err == redis.Nil {
+			s.logger.Debug("Transaction metadata not found", "txHash", txHash)
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("failed to get transaction metadata from %s: %w", s.MetaKey, err)
+		}
 
-	if err := updateFn(&storedTx); err != nil {
-		return err
-	}
+		var storedTx model.StoredTransaction
+		if err := json.Unmarshal([]byte(val), &storedTx); err != nil {
+			return fmt.Errorf("failed to unmarshal transaction metadata: %w", err)
+		}
+		before := storedTx
 
-	updated, err := json.Marshal(storedTx)
-	if err != nil {
-		return fmt.Errorf("error marshaling updated metadata: %w", err)
-	}
+		if err := updateFn(&storedTx); err != nil {
+			return err
+		}
 
-	if err := s.rdb.HSet(ctx, s.MetaKey, txHash, updated).Err(); err != nil {
-		return fmt.Errorf("error saving updated metadata to Redis: %w", err)
+		saved, err := s.saveUpdate(ctx, txHash, val, &before, &storedTx)
+		if err != nil {
+			return fmt.Errorf("error saving updated metadata to Redis: %w", err)
+		}
+		if saved {
+			s.addToIndexes(ctx, &storedTx)
+			return nil
+		}
 	}
-
-	s.addToIndexes(ctx, &storedTx)
-
-	return nil
+	return fmt.Errorf("transaction %s kept changing, gave up after %d attempts", txHash, maxUpdateAttempts)
 }
 
+const (
+	// maxUpdateAttempts is how many times an update is applied to a record other updates keep changing
+	maxUpdateAttempts = 10
+	// maxOrphanedBlocks is how many reorged out block hashes are remembered per client
+	maxOrphanedBlocks = 1024
+	// blockTxsTTL bounds the life of a block's tx set. The chain tracker deletes it once the block is too
+	// deep to be reorged, this covers blocks it never saw.
+	blockTxsTTL = 24 * time.Hour
+)
+
 func (s *ClientStorage) UpdateMinedTransaction(ctx context.Context, txHash string, tx *types.Transaction, blockTimestamp int64, receiptStatus uint64, blockNumber *big.Int, blockHash *common.Hash, gasUsed *uint64) error {
-	return s.updateStoredTx(ctx, txHash, func(storedTx *model.StoredTransaction) error {
+	var previousBlock string
+	err := s.updateStoredTx(ctx, txHash, func(storedTx *model.StoredTransaction) error {
+		previousBlock = storedTx.Metadata.BlockHash
+
 		storedTx.Metadata.Status = model.StatusMined
 		storedTx.Metadata.TimeMined = &blockTimestamp
 
@@ -117,7 +144,7 @@ func (s *ClientStorage) UpdateMinedTransaction(ctx context.Context, txHash strin
 			if err != nil {
 				return fmt.Errorf("failed to derive sender: %w", err)
 			}
-			storedTx.Tx = structureTx(tx, sender)
+			storedTx.Tx = StructureTx(tx, sender)
 		}
 
 		if blockNumber != nil {
@@ -132,9 +159,94 @@ func (s *ClientStorage) UpdateMinedTransaction(ctx context.Context, txHash strin
 
 		return nil
 	})
+	if err != nil || blockHash == nil {
+		return err
+	}
+
+	// Index the tx by block so a reorg finds the txs it affects
+	pipe := s.rdb.Pipeline()
+	if previousBlock != "" && previousBlock != blockHash.Hex() {
+		pipe.SRem(ctx, utils.RedisBlockTxsKey(s.client, previousBlock), txHash)
+	}
+	pipe.SAdd(ctx, utils.RedisBlockTxsKey(s.client, blockHash.Hex()), txHash)
+	pipe.Expire(ctx, utils.RedisBlockTxsKey(s.client, blockHash.Hex()), blockTxsTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("error indexing mined transaction by block: %w", err)
+	}
+	return nil
 }
 
-func (s *ClientStorage) UpdatePendingTransaction(ctx context.Context, txHash string, tx *types.Transaction, timestamp int64) error {
+// RevertMinedTransactions puts the txs stored as mined in a block a reorg removed back to pending and
+// schedules them for an immediate check, which finds them in their new block if they were mined again.
+// The block is remembered as orphaned. It returns the reverted tx hashes.
+func (s *ClientStorage) RevertMinedTransactions(ctx context.Context, blockHash common.Hash, detectedAt int64) ([]string, error) {
+	blockKey := utils.RedisBlockTxsKey(s.client, blockHash.Hex())
+
+	orphaned := s.rdb.Pipeline()
+	orphaned.ZAdd(ctx, utils.RedisOrphanedBlocksKey(s.client), redis.Z{Score: float64(detectedAt), Member: blockHash.Hex()})
+	orphaned.ZRemRangeByRank(ctx, utils.RedisOrphanedBlocksKey(s.client), 0, -maxOrphanedBlocks-1)
+	if _, err := orphaned.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("error recording orphaned block: %w", err)
+	}
+
+	hashes, err := s.rdb.SMembers(ctx, blockKey).Result()
+	if err != nil {
+		return nil, fmt.Errorf("error reading txs of block %s: %w", blockHash.Hex(), err)
+	}
+
+	var reverted []string
+	for _, txHash := range hashes {
+		changed := false
+		err := s.updateStoredTx(ctx, txHash, func(storedTx *model.StoredTransaction) error {
+			changed = false
+			if storedTx.Metadata.Status != model.StatusMined || storedTx.Metadata.BlockHash != blockHash.Hex() {
+				return nil
+			}
+			storedTx.Metadata.Status = model.StatusPending
+			storedTx.Metadata.TimeMined = nil
+			storedTx.Metadata.MineStatus = ""
+			storedTx.Metadata.BlockNumber = 0
+			storedTx.Metadata.BlockHash = ""
+			storedTx.Metadata.GasUsed = 0
+			changed = true
+			return nil
+		})
+		if err != nil {
+			s.logger.Error("Error reverting mined transaction", logger.Fields{"txHash": txHash, "error": err.Error()})
+			continue
+		}
+		if changed {
+			reverted = append(reverted, txHash)
+		}
+	}
+
+	pipe := s.rdb.Pipeline()
+	if len(reverted) > 0 {
+		due := float64(time.Unix(detectedAt, 0).UnixMilli())
+		members := make([]redis.Z, len(reverted))
+		for i, txHash := range reverted {
+			members[i] = redis.Z{Score: due, Member: txHash}
+		}
+		pipe.ZAdd(ctx, s.ScheduleKey, members...)
+	}
+	pipe.Del(ctx, blockKey)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return reverted, fmt.Errorf("error rescheduling reverted transactions: %w", err)
+	}
+
+	return reverted, nil
+}
+
+// IsOrphanedBlock reports whether a reorg removed the block, so receipts still pointing at it aren't stored
+func (s *ClientStorage) IsOrphanedBlock(ctx context.Context, blockHash common.Hash) (bool, error) {
+	err := s.rdb.ZScore(ctx, utils.RedisOrphanedBlocksKey(s.client), blockHash.Hex()).Err()
+	if err == redis.Nil {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (s *ClientStorage) UpdatePendingTransaction(ctx context.Context, txHash string, tx *types.Transaction, sidecar model.SidecarStatus, timestamp int64) error {
 	return s.updateStoredTx(ctx, txHash, func(storedTx *model.StoredTransaction) error {
 		storedTx.Metadata.Status = model.StatusPending
 
@@ -146,7 +258,8 @@ func (s *ClientStorage) UpdatePendingTransaction(ctx context.Context, txHash str
 			if err != nil {
 				return fmt.Errorf("failed to derive sender: %w", err)
 			}
-			storedTx.Tx = structureTx(tx, sender)
+			storedTx.Tx = StructureTx(tx, sender)
+			storedTx.Metadata.BlobSidecar = sidecar
 		}
 		return nil
 	})
@@ -160,7 +273,7 @@ func (s *ClientStorage) UpdateDroppedTransaction(ctx context.Context, txHash str
 	})
 }
 
-func (s *ClientStorage) UpdateQueuedTransaction(ctx context.Context, txHash string, tx *types.Transaction, timestamp int64) error {
+func (s *ClientStorage) UpdateQueuedTransaction(ctx context.Context, txHash string, tx *types.Transaction, sidecar model.SidecarStatus, timestamp int64) error {
 	return s.updateStoredTx(ctx, txHash, func(storedTx *model.StoredTransaction) error {
 		storedTx.Metadata.Status = model.StatusQueued
 
@@ -169,40 +282,68 @@ func (s *ClientStorage) UpdateQueuedTransaction(ctx context.Context, txHash stri
 			if err != nil {
 				return fmt.Errorf("failed to derive sender: %w", err)
 			}
-			storedTx.Tx = structureTx(tx, sender)
+			storedTx.Tx = StructureTx(tx, sender)
+			storedTx.Metadata.BlobSidecar = sidecar
 		}
 
 		return nil
 	})
 }
 
-// extracts the core fields from types.Transaction into model.Tx
-func structureTx(tx *types.Transaction, sender common.Address) model.Tx {
+// StructureTx extracts the core fields from types.Transaction into model.Tx
+func StructureTx(tx *types.Transaction, sender common.Address) model.Tx {
 	isContractCreation := tx.To() == nil
 
 	txData := model.Tx{
-		ChainID:             tx.ChainId().String(),
-		From:                sender.Hex(),
-		Nonce:               tx.Nonce(),
-		Value:               tx.Value().String(),
-		Gas:                 tx.Gas(),
-		GasPrice:            tx.GasPrice(),
-		MaxFeePerGas:        tx.GasFeeCap().String(),
-		MaxPriorityFee:      tx.GasTipCap().String(),
-		MaxFeePerBlobGas:    "",
-		Data:                hex.EncodeToString(tx.Data()),
-		Type:                tx.Type(),
-		IsContractCreation:  isContractCreation,
+		ChainID:            tx.ChainId().String(),
+		From:               sender.Hex(),
+		Nonce:              tx.Nonce(),
+		Value:              tx.Value().String(),
+		Gas:                tx.Gas(),
+		GasPrice:           tx.GasPrice(),
+		MaxFeePerGas:       tx.GasFeeCap().String(),
+		MaxPriorityFee:     tx.GasTipCap().String(),
+		Data:               hex.EncodeToString(tx.Data()),
+		Type:               tx.Type(),
+		IsContractCreation: isContractCreation,
 	}
 
 	if tx.To() != nil {
 		txData.To = tx.To().Hex()
+	} else {
+		txData.InitCodeSize = len(tx.Data())
+	}
+	for _, tuple := range tx.AccessList() {
+		entry := model.AccessTuple{Address: tuple.Address.Hex(), StorageKeys: make([]string, len(tuple.StorageKeys))}
+		for i, key := range tuple.StorageKeys {
+			entry.StorageKeys[i] = key.Hex()
+		}
+		txData.AccessList = append(txData.AccessList, entry)
+	}
+	if tx.Type() == types.BlobTxType {
+		txData.MaxFeePerBlobGas = tx.BlobGasFeeCap().String()
+		for _, hash := range tx.BlobHashes() {
+			txData.BlobHashes = append(txData.BlobHashes, hash.Hex())
+		}
+		txData.BlobCount = len(tx.BlobHashes())
+	}
+	for _, auth := range tx.SetCodeAuthorizations() {
+		authorization := model.Authorization{
+			ChainID:  auth.ChainID.String(),
+			Delegate: auth.Address.Hex(),
+			Nonce:    auth.Nonce,
+		}
+		if authority, err := auth.Authority(); err != nil {
+			authorization.Error = err.Error()
+		} else {
+			authorization.Authority = authority.Hex()
+		}
+		txData.Authorizations = append(txData.Authorizations, authorization)
 	}
 
 	return txData
 }
 
-
 // addToIndexes adds the transaction to various indexes for efficient filtering
 func (s *ClientStorage) addToIndexes(ctx context.Context, tx *model.StoredTransaction) {
 	pipe := s.rdb.Pipeline()
@@ -229,6 +370,11 @@ func (s *ClientStorage) addToIndexes(ctx context.Context, tx *model.StoredTransa
 		Member: txKey,
 	})
 
+	// Index set code txs by the delegates they authorize
+	for _, auth := range tx.Tx.Authorizations {
+		pipe.SAdd(ctx, utils.RedisDelegateIndexKey(client, auth.Delegate), txKey)
+	}
+
 	if _, err := pipe.Exec(ctx); err != nil {
 		s.logger.Error(fmt.Sprintf("Error adding transaction to indexes: %s", err))
 	}
@@ -0,0 +1,523 @@
+package supervisor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"txpool-viz/internal/capture"
+	"txpool-viz/internal/chain"
+	"txpool-viz/internal/clock"
+	"txpool-viz/internal/config"
+	"txpool-viz/internal/focil"
+	"txpool-viz/internal/logger"
+	"txpool-viz/internal/model"
+	"txpool-viz/internal/service"
+	"txpool-viz/internal/transactions"
+)
+
+var (
+	// ErrEndpointNotFound is returned when removing an endpoint or beacon node that is not configured
+	ErrEndpointNotFound = errors.New("endpoint not found")
+
+	// ErrInvalidConfig wraps the problems of a config that was rejected without changing anything
+	ErrInvalidConfig = errors.New("invalid config")
+)
+
+// DefaultWatchInterval is how often the config file is checked when reload.interval is unset
+const DefaultWatchInterval = 2 * time.Second
+
+// Supervisor runs the stream and processor goroutines of each endpoint and beacon node, and starts
+// or stops them as the config changes at runtime. Other endpoints and the stored data are left untouched.
+type Supervisor struct {
+	ctx   context.Context
+	srvc  *service.Service
+	focil *focil.FocilService // nil when FOCIL is disabled
+
+	mu        sync.Mutex
+	cfg       *config.Config
+	endpoints map[string]*worker
+	beacons   map[string]*worker
+	listeners []func(*config.Config)
+	watching  bool
+
+	wg sync.WaitGroup
+}
+
+// worker is the goroutines of one endpoint or beacon node
+type worker struct {
+	cancel    context.CancelFunc
+	done      chan struct{}
+	startedAt time.Time
+}
+
+func (w *worker) running() bool {
+	if w == nil {
+		return false
+	}
+	select {
+	case <-w.done:
+		return false
+	default:
+		return true
+	}
+}
+
+// New constructs a Supervisor for the connected cfg. Its goroutines run under ctx.
+func New(ctx context.Context, cfg *config.Config, srvc *service.Service, fs *focil.FocilService) *Supervisor {
+	return &Supervisor{
+		ctx:       ctx,
+		srvc:      srvc,
+		focil:     fs,
+		cfg:       cfg,
+		endpoints: make(map[string]*worker),
+		beacons:   make(map[string]*worker),
+	}
+}
+
+// OnReload registers fn to be called with the new config whenever the endpoints or beacon nodes change
+func (s *Supervisor) OnReload(fn func(*config.Config)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.listeners = append(s.listeners, fn)
+}
+
+// Start launches the goroutines of every configured endpoint and beacon node
+func (s *Supervisor) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, endpoint := range s.cfg.Endpoints {
+		s.startEndpoint(endpoint)
+	}
+	for _, beacon := range s.cfg.BeaconUrls {
+		s.startBeacon(beacon)
+	}
+}
+
+// Wait blocks until every goroutine has stopped, once the context is cancelled
+func (s *Supervisor) Wait() {
+	s.wg.Wait()
+}
+
+// Config returns the config currently in effect. It is replaced, never modified, by a reload.
+func (s *Supervisor) Config() *config.Config {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.cfg
+}
+
+// Status lists the endpoint and beacon node workers in config order
+func (s *Supervisor) Status() model.SupervisorStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status := model.SupervisorStatus{
+		ConfigFile: s.cfg.File,
+		Watching:   s.watching,
+		Endpoints:  []model.EndpointWorker{},
+		Beacons:    []model.EndpointWorker{},
+	}
+	for _, endpoint := range s.cfg.Endpoints {
+		if w, ok := s.endpoints[endpoint.Name]; ok {
+			status.Endpoints = append(status.Endpoints, workerStatus(endpoint.Name, w))
+		}
+	}
+	for _, beacon := range s.cfg.BeaconUrls {
+		if w, ok := s.beacons[beacon.Name]; ok {
+			status.Beacons = append(status.Beacons, workerStatus(beacon.Name, w))
+		}
+	}
+	return status
+}
+
+func workerStatus(name string, w *worker) model.EndpointWorker {
+	return model.EndpointWorker{Name: name, StartedAt: w.startedAt.Unix(), Running: w.running()}
+}
+
+// Apply switches to the endpoints and beacon nodes of next. Added entries are started, removed ones
+// stopped and changed ones restarted, as are unchanged entries whose goroutines stopped on their own.
+// Nothing changes when next is invalid or a new endpoint can't be dialed.
+func (s *Supervisor) Apply(next *config.Config) (model.ReloadResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.apply(next)
+}
+
+// ReloadFile applies the config file again, along with the environment and command line overrides
+func (s *Supervisor) ReloadFile() (model.ReloadResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	next, err := s.cfg.Reresolve()
+	if err != nil {
+		return model.ReloadResult{}, fmt.Errorf("%w: %w", ErrInvalidConfig, err)
+	}
+	return s.apply(next)
+}
+
+// PutEndpoint adds the endpoint, or replaces the endpoint of the same name
+func (s *Supervisor) PutEndpoint(endpoint config.Endpoint) (model.ReloadResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	next := *s.cfg
+	next.Endpoints = slices.Clone(s.cfg.Endpoints)
+	i := slices.IndexFunc(next.Endpoints, func(e config.Endpoint) bool { return e.Name == endpoint.Name })
+	if i < 0 {
+		next.Endpoints = append(next.Endpoints, endpoint)
+	} else {
+		next.Endpoints[i] = endpoint
+	}
+	return s.apply(&next)
+}
+
+// RemoveEndpoint stops and removes the endpoint. Its stored txs are kept.
+func (s *Supervisor) RemoveEndpoint(name string) (model.ReloadResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	next := *s.cfg
+	next.Endpoints = slices.DeleteFunc(slices.Clone(s.cfg.Endpoints), func(e config.Endpoint) bool { return e.Name == name })
+	if len(next.Endpoints) == len(s.cfg.Endpoints) {
+		return model.ReloadResult{}, fmt.Errorf("%w: %s", ErrEndpointNotFound, name)
+	}
+	return s.apply(&next)
+}
+
+// PutBeacon adds the beacon node, or replaces the beacon node of the same name
+func (s *Supervisor) PutBeacon(beacon config.BeaconEndpoint) (model.ReloadResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	next := *s.cfg
+	next.BeaconUrls = slices.Clone(s.cfg.BeaconUrls)
+	i := slices.IndexFunc(next.BeaconUrls, func(b config.BeaconEndpoint) bool { return b.Name == beacon.Name })
+	if i < 0 {
+		next.BeaconUrls = append(next.BeaconUrls, beacon)
+	} else {
+		next.BeaconUrls[i] = beacon
+	}
+	return s.apply(&next)
+}
+
+// RemoveBeacon stops and removes the beacon node. Its stored inclusion lists are kept.
+func (s *Supervisor) RemoveBeacon(name string) (model.ReloadResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	next := *s.cfg
+	next.BeaconUrls = slices.DeleteFunc(slices.Clone(s.cfg.BeaconUrls), func(b config.BeaconEndpoint) bool { return b.Name == name })
+	if len(next.BeaconUrls) == len(s.cfg.BeaconUrls) {
+		return model.ReloadResult{}, fmt.Errorf("%w: %s", ErrEndpointNotFound, name)
+	}
+	return s.apply(&next)
+}
+
+func (s *Supervisor) apply(next *config.Config) (model.ReloadResult, error) {
+	// Only some fields of next are applied, the config running afterwards is the one that must be valid
+	current := s.cfg
+	if errs := reloaded(current, next).Validate(); len(errs) > 0 {
+		return model.ReloadResult{}, fmt.Errorf("%w: %w", ErrInvalidConfig, errors.Join(errs...))
+	}
+
+	result := model.ReloadResult{RestartRequired: restartRequired(current, next)}
+
+	previous := make(map[string]config.Endpoint, len(current.Endpoints))
+	for _, endpoint := range current.Endpoints {
+		previous[endpoint.Name] = endpoint
+	}
+
+	// Dial every new client before stopping anything, so a bad endpoint leaves everything running
+	endpoints := make([]config.Endpoint, len(next.Endpoints))
+	var started []config.Endpoint
+	for i, endpoint := range next.Endpoints {
+		old, ok := previous[endpoint.Name]
+		switch {
+		case !ok:
+			result.Endpoints.Added = append(result.Endpoints.Added, endpoint.Name)
+		case endpointChanged(old, endpoint):
+			result.Endpoints.Modified = append(result.Endpoints.Modified, endpoint.Name)
+		case !s.endpoints[endpoint.Name].running():
+			// Unchanged but its stream has ended, restart it on the same client
+			result.Endpoints.Modified = append(result.Endpoints.Modified, endpoint.Name)
+			endpoint.Client, endpoint.Engine = old.Client, old.Engine
+			endpoints[i] = endpoint
+			started = append(started, endpoint)
+			continue
+		default:
+			endpoint.Client, endpoint.Engine = old.Client, old.Engine
+			endpoints[i] = endpoint
+			continue
+		}
+
+		if err := s.connect(&endpoint); err != nil {
+			closeNewClients(started, previous)
+			return model.ReloadResult{}, err
+		}
+		endpoints[i] = endpoint
+		started = append(started, endpoint)
+	}
+
+	var retired []config.Endpoint
+	for _, old := range current.Endpoints {
+		i := slices.IndexFunc(endpoints, func(e config.Endpoint) bool { return e.Name == old.Name })
+		if i < 0 {
+			result.Endpoints.Removed = append(result.Endpoints.Removed, old.Name)
+			retired = append(retired, old)
+		} else if endpoints[i].Client != old.Client {
+			retired = append(retired, old)
+		}
+	}
+
+	// Stop the old goroutines before starting their replacements so no endpoint is streamed twice
+	for _, old := range retired {
+		s.stop(s.endpoints, old.Name)
+	}
+	for _, endpoint := range started {
+		s.stop(s.endpoints, endpoint.Name)
+		s.startEndpoint(endpoint)
+	}
+
+	beacons := slices.Clone(next.BeaconUrls)
+	result.Beacons = s.applyBeacons(current.BeaconUrls, beacons)
+
+	updated := reloaded(current, next)
+	updated.Endpoints = endpoints
+	updated.BeaconUrls = beacons
+	s.cfg = updated
+
+	for _, fn := range s.listeners {
+		fn(s.cfg)
+	}
+
+	// Close the replaced clients only once nothing refers to them anymore
+	for _, old := range retired {
+		old.Close()
+	}
+
+	if changed(result) {
+		s.srvc.Logger.Info("Endpoints reloaded", logger.Fields{
+			"endpoints": result.Endpoints,
+			"beacons":   result.Beacons,
+		})
+	}
+	return result, nil
+}
+
+// applyBeacons restarts the beacon node streams that changed
+func (s *Supervisor) applyBeacons(current, next []config.BeaconEndpoint) model.EndpointChanges {
+	var changes model.EndpointChanges
+
+	previous := make(map[string]config.BeaconEndpoint, len(current))
+	for _, beacon := range current {
+		previous[beacon.Name] = beacon
+	}
+
+	for _, beacon := range next {
+		old, ok := previous[beacon.Name]
+		delete(previous, beacon.Name)
+
+		switch {
+		case !ok:
+			changes.Added = append(changes.Added, beacon.Name)
+		case !reflect.DeepEqual(normalizeBeacon(old), normalizeBeacon(beacon)):
+			changes.Modified = append(changes.Modified, beacon.Name)
+		case s.focil != nil && !s.beacons[beacon.Name].running():
+			changes.Modified = append(changes.Modified, beacon.Name)
+		default:
+			continue
+		}
+
+		s.stop(s.beacons, beacon.Name)
+		s.startBeacon(beacon)
+	}
+
+	for name := range previous {
+		changes.Removed = append(changes.Removed, name)
+		s.stop(s.beacons, name)
+	}
+	sort.Strings(changes.Removed)
+
+	return changes
+}
+
+// connect dials the endpoint's client, through the recorder when a capture is being recorded
+func (s *Supervisor) connect(endpoint *config.Endpoint) error {
+	if s.srvc.Recorder != nil {
+		return capture.RecordEndpoint(s.ctx, endpoint, s.srvc.Recorder)
+	}
+	return endpoint.Connect()
+}
+
+func (s *Supervisor) startEndpoint(endpoint config.Endpoint) {
+	polling := s.cfg.Polling
+	s.endpoints[endpoint.Name] = s.run(func(ctx context.Context) {
+		// Heads drive reorg tracking, and the inclusion reports when FOCIL is enabled
+		tracker := chain.NewTracker(endpoint, s.srvc, clock.Wall)
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			chain.FollowHeads(ctx, endpoint, s.srvc.Logger, s.srvc.Recorder, func(ctx context.Context, head *chain.Head) {
+				tracker.Observe(ctx, head)
+				if s.focil != nil {
+					s.focil.HandleHead(ctx, endpoint, head.Header, &wg)
+				}
+			})
+		}()
+
+		transactions.RunEndpoint(ctx, endpoint, polling, s.srvc)
+		wg.Wait()
+	})
+}
+
+func (s *Supervisor) startBeacon(beacon config.BeaconEndpoint) {
+	// Beacon nodes are only streamed for their inclusion lists
+	if s.focil == nil {
+		return
+	}
+	s.beacons[beacon.Name] = s.run(func(ctx context.Context) {
+		s.focil.StreamBeacon(ctx, beacon)
+	})
+}
+
+func (s *Supervisor) run(fn func(ctx context.Context)) *worker {
+	ctx, cancel := context.WithCancel(s.ctx)
+	w := &worker{cancel: cancel, done: make(chan struct{}), startedAt: time.Now()}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer close(w.done)
+		fn(ctx)
+	}()
+	return w
+}
+
+// stop cancels the named worker and waits for its goroutines to return
+func (s *Supervisor) stop(workers map[string]*worker, name string) {
+	w, ok := workers[name]
+	if !ok {
+		return
+	}
+	w.cancel()
+	<-w.done
+	delete(workers, name)
+}
+
+// Watch applies the config file each time it is saved, until the context is cancelled
+func (s *Supervisor) Watch(interval time.Duration) {
+	s.mu.Lock()
+	path := s.cfg.File
+	s.watching = true
+	s.mu.Unlock()
+
+	l := s.srvc.Logger
+	l.Info("Watching config file for endpoint changes", logger.Fields{"file": path})
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		last := stat(path)
+		for {
+			select {
+			case <-s.ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			current := stat(path)
+			if current == last {
+				continue
+			}
+			last = current
+
+			if _, err := s.ReloadFile(); err != nil {
+				l.Error("Config file change not applied", logger.Fields{"file": path, "error": err.Error()})
+			}
+		}
+	}()
+}
+
+// fileStat is what tells a saved file apart
+type fileStat struct {
+	modTime time.Time
+	size    int64
+}
+
+func stat(path string) fileStat {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileStat{}
+	}
+	return fileStat{modTime: info.ModTime(), size: info.Size()}
+}
+
+// endpointChanged reports whether the endpoint settings differ, ignoring the clients
+func endpointChanged(a, b config.Endpoint) bool {
+	a.Client, b.Client = nil, nil
+	a.Engine, b.Engine = nil, nil
+	if len(a.AuthHeaders) == 0 {
+		a.AuthHeaders = nil
+	}
+	if len(b.AuthHeaders) == 0 {
+		b.AuthHeaders = nil
+	}
+	return !reflect.DeepEqual(a, b)
+}
+
+func normalizeBeacon(b config.BeaconEndpoint) config.BeaconEndpoint {
+	if len(b.AuthHeaders) == 0 {
+		b.AuthHeaders = nil
+	}
+	return b
+}
+
+// reloaded returns the current config with the fields a reload applies taken from next
+func reloaded(current, next *config.Config) *config.Config {
+	updated := *current
+	updated.Endpoints = next.Endpoints
+	updated.BeaconUrls = next.BeaconUrls
+	updated.Decoding = next.Decoding
+	return &updated
+}
+
+// restartRequired reports whether next changes anything besides the endpoints, beacon nodes and decoding
+func restartRequired(current, next *config.Config) bool {
+	a, b := *current, *next
+	a.Endpoints, b.Endpoints = nil, nil
+	a.BeaconUrls, b.BeaconUrls = nil, nil
+	a.Decoding, b.Decoding = config.Decoding{}, config.Decoding{}
+	a.EnvOverrides, b.EnvOverrides = nil, nil
+	a.FlagOverrides, b.FlagOverrides = nil, nil
+	return !reflect.DeepEqual(a, b)
+}
+
+// closeNewClients closes the clients dialed for a reload that was abandoned
+func closeNewClients(started []config.Endpoint, previous map[string]config.Endpoint) {
+	for _, endpoint := range started {
+		if endpoint.Client != nil && endpoint.Client != previous[endpoint.Name].Client {
+			endpoint.Close()
+		}
+	}
+}
+
+func changed(result model.ReloadResult) bool {
+	for _, changes := range []model.EndpointChanges{result.Endpoints, result.Beacons} {
+		if len(changes.Added)+len(changes.Removed)+len(changes.Modified) > 0 {
+			return true
+		}
+	}
+	return false
+}
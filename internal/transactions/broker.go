@@ -5,9 +5,12 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net/http"
 	"sync"
 	"time"
 
+	"txpool-viz/internal/capture"
+	"txpool-viz/internal/clock"
 	"txpool-viz/internal/config"
 	"txpool-viz/internal/logger"
 	"txpool-viz/internal/model"
@@ -19,19 +22,26 @@ import (
 	"github.com/redis/go-redis/v9"
 )
 
-func Stream(ctx context.Context, cfg *config.Config, srvc *service.Service, wg *sync.WaitGroup) {
-	ProcessTransactions(ctx, cfg, srvc)
-
-	for _, endpoint := range cfg.Endpoints {
-		wg.Add(1)
-		go func(endpoint config.Endpoint) {
-			defer wg.Done()
-			streamEndpoint(ctx, endpoint, srvc.Logger, srvc.Redis)
-		}(endpoint)
+// RunEndpoint ingests an endpoint's pending txs and processes its queue until ctx is cancelled.
+// Endpoints without a subscription are polled over HTTP.
+func RunEndpoint(ctx context.Context, endpoint config.Endpoint, polling config.Polling, srvc *service.Service) {
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		processEndpointQueue(ctx, &endpoint, srvc, polling, clock.Wall)
+	}()
+
+	if endpoint.Pending() == config.PendingSubscription {
+		streamEndpoint(ctx, endpoint, srvc.Logger, srvc.Redis, srvc.Recorder)
+	} else {
+		pollEndpoint(ctx, endpoint, pendingInterval(polling, srvc.Logger), srvc.Logger, srvc.Redis, srvc.Recorder)
 	}
+	wg.Wait()
 }
 
-func streamEndpoint(ctx context.Context, endpoint config.Endpoint, l logger.Logger, r *redis.Client) {
+func streamEndpoint(ctx context.Context, endpoint config.Endpoint, l logger.Logger, r *redis.Client, rec *capture.Recorder) {
 	// Make the websocket connection
 	conn, err := dialWebSocket(ctx, endpoint, l)
 
@@ -40,14 +50,12 @@ func streamEndpoint(ctx context.Context, endpoint config.Endpoint, l logger.Logg
 			"endpoint": endpoint.Name,
 			"url":      endpoint.Websocket,
 		})
+		return
 	}
 
 	// Defer websocket close
 	defer conn.Close(websocket.StatusNormalClosure, "stream shutdown")
 
-	// retrieve the universal redis sorted set key
-	redisUniversalSortedSetKey := utils.RedisUniversalKey()
-
 	// Create new per-client redis storage instance
 	storage := storage.NewClientStorage(endpoint.Name, r, l)
 
@@ -58,8 +66,8 @@ func streamEndpoint(ctx context.Context, endpoint config.Endpoint, l logger.Logg
 			l.Info("Shutting down streamEndpoint", logger.Fields{"endpoint": endpoint.Name})
 			return
 		default:
-			time := time.Now().Unix()
 			_, msg, err := conn.Read(ctx)
+			receivedAt := time.Now()
 
 			if err != nil {
 				if websocket.CloseStatus(err) == websocket.StatusNormalClosure || errors.Is(err, context.Canceled) {
@@ -69,32 +77,56 @@ func streamEndpoint(ctx context.Context, endpoint config.Endpoint, l logger.Logg
 				return
 			}
 
-			var event model.SubscriptionResponse
-			if err := json.Unmarshal(msg, &event); err != nil {
-				l.Error("JSON parse error")
-				continue
-			}
+			rec.RecordStream(endpoint.Name, msg, receivedAt)
+			handleStreamMessage(ctx, endpoint.Name, msg, receivedAt.Unix(), storage, l, r)
+		}
+	}
 
-			txHash := event.Params.TxHash
+}
 
-			r.ZAddNX(ctx, redisUniversalSortedSetKey, redis.Z{
-				Score:  float64(time),
-				Member: txHash,
-			})
+// ReplayStreamMessage feeds a captured websocket message through the same path as a live stream.
+func ReplayStreamMessage(ctx context.Context, srvc *service.Service, endpointName string, msg []byte, receivedAt time.Time) {
+	storage := storage.NewClientStorage(endpointName, srvc.Redis, srvc.Logger)
+	handleStreamMessage(ctx, endpointName, msg, receivedAt.Unix(), storage, srvc.Logger, srvc.Redis)
+}
 
-			if err := storage.StoreTransaction(ctx, txHash, time); err != nil {
-				l.Error("Error storing tx to cache", logger.Fields{"txHash": txHash})
-			}
+// handleStreamMessage stores a newPendingTransactions notification and queues the tx for processing
+func handleStreamMessage(ctx context.Context, endpointName string, msg []byte, receivedAt int64, storage *storage.ClientStorage, l logger.Logger, r *redis.Client) {
+	var event model.SubscriptionResponse
+	if err := json.Unmarshal(msg, &event); err != nil {
+		l.Error("JSON parse error")
+		return
+	}
 
-			streamKey := utils.RedisStreamKey(endpoint.Name)
-			r.RPush(ctx, streamKey, fmt.Sprintf("%s:%s", endpoint.Name, event.Params.TxHash))
-		}
+	storeTxHash(ctx, endpointName, event.Params.TxHash, receivedAt, storage, l, r)
+}
+
+// storeTxHash records a newly seen pending tx and queues it for processing
+func storeTxHash(ctx context.Context, endpointName, txHash string, receivedAt int64, storage *storage.ClientStorage, l logger.Logger, r *redis.Client) {
+	r.ZAddNX(ctx, utils.RedisUniversalKey(), redis.Z{
+		Score:  float64(receivedAt),
+		Member: txHash,
+	})
+
+	if err := storage.StoreTransaction(ctx, txHash, receivedAt); err != nil {
+		l.Error("Error storing tx to cache", logger.Fields{"txHash": txHash})
 	}
 
+	if err := scheduleCheck(ctx, r, endpointName, txHash, receivedAt); err != nil {
+		l.Error("Error scheduling tx check", logger.Fields{"txHash": txHash, "error": err.Error()})
+	}
 }
 
 func dialWebSocket(ctx context.Context, endpoint config.Endpoint, l logger.Logger) (*websocket.Conn, error) {
-	conn, resp, err := websocket.Dial(ctx, endpoint.Websocket, nil)
+	creds, err := endpoint.Credentials()
+	if err != nil {
+		return nil, err
+	}
+
+	conn, resp, err := websocket.Dial(ctx, endpoint.Websocket, &websocket.DialOptions{
+		HTTPHeader: creds.Header(),
+		HTTPClient: &http.Client{Transport: creds.Transport()},
+	})
 
 	if err != nil {
 		return nil, fmt.Errorf("error connecting to websocket: %s", err)
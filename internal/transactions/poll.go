@@ -0,0 +1,179 @@
+package transactions
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"txpool-viz/internal/capture"
+	"txpool-viz/internal/config"
+	"txpool-viz/internal/logger"
+	"txpool-viz/internal/model"
+	"txpool-viz/internal/storage"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	methodNotFoundCode  = -32601
+	filterNotFoundError = "filter not found"
+)
+
+// errFiltersUnsupported is returned when a node can't create pending tx filters
+var errFiltersUnsupported = errors.New("pending transaction filters not supported")
+
+// pollEndpoint ingests the pending txs of an endpoint over plain HTTP, through a pending tx filter or
+// by diffing txpool_content. New hashes take the same path as streamed ones, and are recorded as
+// subscription messages so captures replay the same way.
+func pollEndpoint(ctx context.Context, endpoint config.Endpoint, interval time.Duration, l logger.Logger, r *redis.Client, rec *capture.Recorder) {
+	storage := storage.NewClientStorage(endpoint.Name, r, l)
+	poller := &pendingPoller{
+		rpc:    endpoint.Client.Client(),
+		source: endpoint.Pending(),
+		name:   endpoint.Name,
+		logger: l,
+	}
+	defer poller.close()
+
+	l.Info("Polling for pending txs", logger.Fields{"endpoint": endpoint.Name, "source": poller.source, "interval": interval.String()})
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			l.Info("Shutting down pollEndpoint", logger.Fields{"endpoint": endpoint.Name})
+			return
+		case <-ticker.C:
+		}
+
+		hashes, err := poller.poll(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				l.Warn("Error polling pending txs", logger.Fields{"endpoint": endpoint.Name, "source": poller.source, "error": err.Error()})
+			}
+			continue
+		}
+
+		receivedAt := time.Now()
+		for _, hash := range hashes {
+			rec.RecordStream(endpoint.Name, subscriptionMessage(hash), receivedAt)
+			storeTxHash(ctx, endpoint.Name, hash.Hex(), receivedAt.Unix(), storage, l, r)
+		}
+	}
+}
+
+// pendingInterval parses polling.pending_interval, falling back to the default
+func pendingInterval(polling config.Polling, l logger.Logger) time.Duration {
+	if polling.PendingInterval == "" {
+		return config.DefaultPendingInterval
+	}
+
+	interval, err := time.ParseDuration(polling.PendingInterval)
+	if err != nil || interval <= 0 {
+		l.Error("Error parsing pending interval, using the default", logger.Fields{"interval": polling.PendingInterval})
+		return config.DefaultPendingInterval
+	}
+	return interval
+}
+
+// pendingPoller returns the pending txs a node gained since the previous poll
+type pendingPoller struct {
+	rpc    *rpc.Client
+	source string
+	name   string
+	logger logger.Logger
+
+	filterID string
+	known    map[common.Hash]struct{} // txpool content of the previous poll
+}
+
+func (p *pendingPoller) poll(ctx context.Context) ([]common.Hash, error) {
+	if p.source == config.PendingFilter {
+		hashes, err := p.pollFilter(ctx)
+		if !errors.Is(err, errFiltersUnsupported) {
+			return hashes, err
+		}
+
+		p.logger.Warn("Pending tx filters unsupported, diffing txpool_content instead", logger.Fields{"endpoint": p.name})
+		p.source = config.PendingTxpool
+	}
+
+	return p.pollTxpool(ctx)
+}
+
+// pollFilter returns the filter's changes, creating the filter first and again once it expired
+func (p *pendingPoller) pollFilter(ctx context.Context) ([]common.Hash, error) {
+	if p.filterID == "" {
+		if err := p.rpc.CallContext(ctx, &p.filterID, "eth_newPendingTransactionFilter"); err != nil {
+			var rpcErr rpc.Error
+			if errors.As(err, &rpcErr) && rpcErr.ErrorCode() == methodNotFoundCode {
+				return nil, errFiltersUnsupported
+			}
+			return nil, err
+		}
+	}
+
+	var hashes []common.Hash
+	err := p.rpc.CallContext(ctx, &hashes, "eth_getFilterChanges", p.filterID)
+	if err != nil && strings.Contains(err.Error(), filterNotFoundError) {
+		// Nodes drop filters that aren't polled for a while and on restart, txs pending meanwhile are missed
+		p.logger.Warn("Pending tx filter expired, creating a new one", logger.Fields{"endpoint": p.name})
+		p.filterID = ""
+		return nil, nil
+	}
+	return hashes, err
+}
+
+// pollTxpool returns the txs in txpool_content that weren't there on the previous poll
+func (p *pendingPoller) pollTxpool(ctx context.Context) ([]common.Hash, error) {
+	var content model.Result
+	if err := p.rpc.CallContext(ctx, &content, "txpool_content"); err != nil {
+		return nil, err
+	}
+
+	current := make(map[common.Hash]struct{})
+	var added []common.Hash
+	for _, pool := range []map[string]map[string]*types.Transaction{content.Pending, content.Queued} {
+		for _, txs := range pool {
+			for _, tx := range txs {
+				hash := tx.Hash()
+				current[hash] = struct{}{}
+				if _, ok := p.known[hash]; !ok {
+					added = append(added, hash)
+				}
+			}
+		}
+	}
+
+	p.known = current
+	return added, nil
+}
+
+// close uninstalls the filter so the node can free it right away
+func (p *pendingPoller) close() {
+	if p.filterID == "" {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var ok bool
+	_ = p.rpc.CallContext(ctx, &ok, "eth_uninstallFilter", p.filterID)
+}
+
+// subscriptionMessage is the newPendingTransactions notification a subscription would have sent for hash
+func subscriptionMessage(hash common.Hash) []byte {
+	msg, _ := json.Marshal(model.SubscriptionResponse{
+		Jsonrpc: "2.0",
+		Method:  "eth_subscription",
+		Params:  model.SubscriptionParams{Subscription: "poll", TxHash: hash.Hex()},
+	})
+	return msg
+}
@@ -2,36 +2,46 @@ package transactions
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
-	"strings"
+	"strconv"
+	"sync"
 	"time"
 
+	"txpool-viz/internal/clock"
 	"txpool-viz/internal/config"
 	"txpool-viz/internal/logger"
 	"txpool-viz/internal/model"
 	"txpool-viz/internal/service"
 	"txpool-viz/internal/storage"
-	"txpool-viz/utils"
 
-	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/redis/go-redis/v9"
 )
 
 const (
 	notIndexedError = "transaction indexing is in progress"
+
+	// blockCacheSize is how many recent block timestamps each endpoint keeps
+	blockCacheSize = 256
 )
 
-func ProcessTransactions(ctx context.Context, cfg *config.Config, srvc *service.Service) {
+// ProcessTransactions processes the queue of every endpoint, stamping and scheduling txs with clk
+func ProcessTransactions(ctx context.Context, cfg *config.Config, srvc *service.Service, clk clock.Clock) {
 	// Initialize a queue for each client
-	interval := cfg.Polling.Interval
 	for _, endpoint := range cfg.Endpoints {
-		go processEndpointQueue(ctx, &endpoint, srvc, interval)
+		go processEndpointQueue(ctx, &endpoint, srvc, cfg.Polling, clk)
 	}
 }
 
-func processEndpointQueue(ctx context.Context, endpoint *config.Endpoint, srvc *service.Service, intervalString string) {
-	interval, err := time.ParseDuration(intervalString)
+// processEndpointQueue claims every tx whose check is due each tick, in batches handed to a pool of
+// concurrency workers that check them with JSON-RPC batch requests. Ticks follow the wall clock, the
+// checks clk.
+func processEndpointQueue(ctx context.Context, endpoint *config.Endpoint, srvc *service.Service, polling config.Polling, clk clock.Clock) {
+	interval, err := time.ParseDuration(polling.Interval)
 
 	if err != nil {
 		srvc.Logger.Error("Error parsing endpoint interval", "error", err.Error())
@@ -40,158 +50,402 @@ func processEndpointQueue(ctx context.Context, endpoint *config.Endpoint, srvc *
 	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
-	storage := storage.NewClientStorage(endpoint.Name, srvc.Redis, srvc.Logger)
-	queue := utils.RedisStreamKey(endpoint.Name)
+	batchSize, concurrency := endpoint.Batching(polling)
+	minWait, maxWait := recheckBounds(polling, srvc.Logger)
+	processor := &batchProcessor{
+		endpoint:  endpoint,
+		rpc:       endpoint.Client.Client(),
+		srvc:      srvc,
+		storage:   storage.NewClientStorage(endpoint.Name, srvc.Redis, srvc.Logger),
+		clock:     clk,
+		scheduler: newScheduler(endpoint.Name, srvc.Redis, clk, minWait, maxWait),
+		blocks:    newBlockCache(blockCacheSize),
+	}
 
 	// Launch queue monitor
-	go monitorQueueSize(ctx, srvc.Redis, srvc.Logger, queue)
+	go monitorQueueSize(ctx, srvc.Redis, srvc.Logger, clk, processor.scheduler.key)
 
-	maxConcurrent := 1000
-	sem := make(chan struct{}, maxConcurrent)
+	batches := make(chan []string)
+	var wg sync.WaitGroup
+	for range concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for hashes := range batches {
+				processor.processBatch(ctx, hashes, processor.clock.Now().Unix())
+			}
+		}()
+	}
+	defer func() {
+		close(batches)
+		wg.Wait()
+	}()
 
 	for {
 		select {
 		case <-ctx.Done():
 			return
 		case <-ticker.C:
+		}
 
-			currentTime := time.Now().Unix()
-
-			txString, err := srvc.Redis.LPop(ctx, queue).Result()
-			if err == redis.Nil {
-				continue
-			}
+		// Drain everything that is due, the workers pace the claims
+		for {
+			hashes, err := processor.scheduler.due(ctx, batchSize)
 			if err != nil {
-				srvc.Logger.Error(fmt.Sprintf("Error reading queued txs: %s", err), logger.Fields{"queue": queue})
-				continue
+				if ctx.Err() == nil {
+					srvc.Logger.Error(fmt.Sprintf("Error reading due txs: %s", err), logger.Fields{"queue": processor.scheduler.key})
+				}
+				break
+			}
+			if len(hashes) == 0 {
+				break
 			}
 
-			tx := strings.Split(txString, ":")
-			if len(tx) < 2 {
-				srvc.Logger.Warn(fmt.Sprintf("Invalid transaction format: %s", txString))
-				continue
+			select {
+			case batches <- hashes:
+			case <-ctx.Done():
+				return
 			}
 
-			sem <- struct{}{}
-			go func(txHash string) {
-				defer func() { <-sem }()
-				processTransaction(ctx, txHash, endpoint, srvc, storage, currentTime)
-			}(tx[1])
+			if len(hashes) < batchSize {
+				break
+			}
 		}
 	}
 }
 
-func processTransaction(
-	ctx context.Context,
-	txHash string,
-	endpoint *config.Endpoint,
-	srvc *service.Service,
-	storage *storage.ClientStorage,
-	timestamp int64,
-) {
-	streamKey := utils.RedisStreamKey(endpoint.Name)
-	l := srvc.Logger
-
-	// Check for transaction receipt — if exists, it's mined
-	receipt, err := endpoint.Client.TransactionReceipt(ctx, common.HexToHash(txHash))
-	if err == nil {
-		l.Debug("Transaction is mined", logger.Fields{
-			"txHash":      txHash,
-			"blockNumber": receipt.BlockNumber,
-			"status":      model.MinedTxStatus(receipt.Status).String(),
-			"endpoint":    endpoint.Name,
-		})
-
-		// fetch tx details
-		tx, _, txErr := endpoint.Client.TransactionByHash(ctx, receipt.TxHash)
-		if txErr != nil {
-			l.Error("Error fetching mined transaction details", logger.Fields{"txHash": txHash, "error": txErr.Error()})
-			return
+// batchProcessor checks queued txs of one endpoint against its node
+type batchProcessor struct {
+	endpoint  *config.Endpoint
+	rpc       *rpc.Client
+	srvc      *service.Service
+	storage   *storage.ClientStorage
+	clock     clock.Clock
+	scheduler *scheduler
+	blocks    *blockCache
+}
+
+// processBatch fetches the receipts of hashes in one batch request, then the txs in a second one and
+// the blocks they were mined in, missing from the cache, in a third. Mined txs are stored as mined and
+// txs the node no longer knows as dropped, both are done. The rest are stored and rescheduled.
+func (p *batchProcessor) processBatch(ctx context.Context, hashes []string, timestamp int64) {
+	l := p.srvc.Logger
+
+	receipts := make([]*types.Receipt, len(hashes))
+	receiptCalls := make([]rpc.BatchElem, len(hashes))
+	for i, txHash := range hashes {
+		receiptCalls[i] = rpc.BatchElem{
+			Method: "eth_getTransactionReceipt",
+			Args:   []any{common.HexToHash(txHash)},
+			Result: &receipts[i],
+		}
+	}
+	if err := p.rpc.BatchCallContext(ctx, receiptCalls); err != nil {
+		// Nothing was checked, retry the whole batch rather than waiting for the lease
+		l.Error("Error fetching transaction receipts", logger.Fields{"endpoint": p.endpoint.Name, "count": len(hashes), "error": err.Error()})
+		p.retry(ctx, hashes)
+		return
+	}
+
+	var retry, reschedule, done []string
+	var lookup []int // indexes of the hashes whose tx is fetched next
+	for i, call := range receiptCalls {
+		if call.Error != nil && call.Error.Error() == notIndexedError {
+			l.Debug("Transaction receipt not indexed yet", logger.Fields{
+				"txHash":   hashes[i],
+				"endpoint": p.endpoint.Name,
+			})
+			retry = append(retry, hashes[i])
+			continue
 		}
+		if call.Error != nil {
+			receipts[i] = nil
+		}
+		lookup = append(lookup, i)
+	}
 
-		block, err := endpoint.Client.BlockByNumber(ctx, receipt.BlockNumber)
-		if err != nil {
-			l.Error("Error fetching block details", logger.Fields{"txHash": txHash, "error": err.Error()})
+	txs := make([]*rpcTransaction, len(hashes))
+	txCalls := make([]rpc.BatchElem, len(lookup))
+	for j, i := range lookup {
+		txCalls[j] = rpc.BatchElem{
+			Method: "eth_getTransactionByHash",
+			Args:   []any{common.HexToHash(hashes[i])},
+			Result: &txs[i],
+		}
+	}
+	if len(txCalls) > 0 {
+		if err := p.rpc.BatchCallContext(ctx, txCalls); err != nil {
+			l.Error("Error fetching transactions", logger.Fields{"endpoint": p.endpoint.Name, "count": len(txCalls), "error": err.Error()})
+			for _, i := range lookup {
+				retry = append(retry, hashes[i])
+			}
+			p.retry(ctx, retry)
 			return
 		}
+	}
 
-		blocktimestamp := block.Time()
+	var mined []*types.Receipt
+	for _, i := range lookup {
+		if receipts[i] != nil {
+			mined = append(mined, receipts[i])
+		}
+	}
+	blockTimes, err := p.blocks.times(ctx, p.rpc, mined)
+	if err != nil {
+		l.Error("Error fetching block details", logger.Fields{"endpoint": p.endpoint.Name, "count": len(mined), "error": err.Error()})
+	}
 
-		if err := storage.UpdateMinedTransaction(
-			ctx,
-			txHash,
-			tx,
-			int64(blocktimestamp),
-			receipt.Status,
-			receipt.BlockNumber,
-			&receipt.BlockHash,
-			&receipt.GasUsed,
-		); err != nil {
-			l.Error("Error updating mined transaction", logger.Fields{"txHash": txHash, "error": err.Error()})
+	pooled := make(map[string]*types.Transaction)
+	for j, i := range lookup {
+		if receipts[i] == nil && txCalls[j].Error == nil && txs[i] != nil && txs[i].tx != nil {
+			pooled[hashes[i]] = txs[i].tx
 		}
-		return
-	} else if err.Error() == notIndexedError {
-		l.Debug("Transaction receipt not indexed yet", logger.Fields{
-			"txHash":   txHash,
-			"endpoint": endpoint.Name,
-		})
-		// Requeue
-		if err := srvc.Redis.RPush(ctx, streamKey, fmt.Sprintf("%s:%s", endpoint.Name, txHash)).Err(); err != nil {
-			l.Error("Error requeuing transaction", logger.Fields{"txHash": txHash, "error": err.Error()})
+	}
+	sidecars := p.sidecars(ctx, pooled)
+
+	for j, i := range lookup {
+		txHash := hashes[i]
+		txErr := txCalls[j].Error
+		if txErr == nil && txs[i] != nil && txs[i].tx == nil {
+			txErr = fmt.Errorf("server returned transaction without signature")
 		}
 
-		return
+		if receipt := receipts[i]; receipt != nil {
+			l.Debug("Transaction is mined", logger.Fields{
+				"txHash":      txHash,
+				"blockNumber": receipt.BlockNumber,
+				"status":      model.MinedTxStatus(receipt.Status).String(),
+				"endpoint":    p.endpoint.Name,
+			})
+			if p.storeMined(ctx, txHash, txs[i], txErr, receipt, blockTimes) {
+				done = append(done, txHash)
+			} else {
+				reschedule = append(reschedule, txHash)
+			}
+			continue
+		}
+
+		if txErr != nil {
+			l.Error("Error fetching transaction from mempool", logger.Fields{"txHash": txHash, "error": txErr.Error()})
+			reschedule = append(reschedule, txHash)
+			continue
+		}
+
+		if txs[i] == nil {
+			// Not in mempool — it's dropped
+			l.Debug("Transaction dropped", logger.Fields{"txHash": txHash})
+			if err := p.storage.UpdateDroppedTransaction(ctx, txHash, timestamp); err != nil {
+				l.Error("Error updating dropped transaction", logger.Fields{"txHash": txHash, "error": err.Error()})
+			}
+			done = append(done, txHash)
+			continue
+		}
+
+		// If in mempool and pending
+		if txs[i].isPending() {
+			l.Debug("Transaction is pending", logger.Fields{"txHash": txHash, "endpoint": p.endpoint.Name})
+			if err := p.storage.UpdatePendingTransaction(ctx, txHash, txs[i].tx, sidecars[txHash], timestamp); err != nil {
+				l.Error("Error updating pending transaction", logger.Fields{"txHash": txHash, "error": err.Error()})
+			}
+		} else {
+			// It's queued — waiting for future block (nonce/gas)
+			l.Debug("Transaction is queued", logger.Fields{"txHash": txHash})
+			if err := p.storage.UpdateQueuedTransaction(ctx, txHash, txs[i].tx, sidecars[txHash], timestamp); err != nil {
+				l.Error("Error updating queued transaction", logger.Fields{"txHash": txHash, "error": err.Error()})
+			}
+		}
+
+		// Schedule a future check
+		reschedule = append(reschedule, txHash)
 	}
 
-	// No receipt — check if it's still in mempool
-	tx, isPending, err := endpoint.Client.TransactionByHash(ctx, common.HexToHash(txHash))
-	if err == ethereum.NotFound {
-		// Not in mempool — it's dropped
-		l.Debug("Transaction dropped", logger.Fields{"txHash": txHash})
-		if err := storage.UpdateDroppedTransaction(ctx, txHash, timestamp); err != nil {
-			l.Error("Error updating dropped transaction", logger.Fields{"txHash": txHash, "error": err.Error()})
+	p.retry(ctx, retry)
+	if err := p.scheduler.reschedule(ctx, reschedule); err != nil {
+		l.Error("Error rescheduling transactions", logger.Fields{"endpoint": p.endpoint.Name, "count": len(reschedule), "error": err.Error()})
+	}
+	if err := p.scheduler.done(ctx, done); err != nil {
+		l.Error("Error unscheduling transactions", logger.Fields{"endpoint": p.endpoint.Name, "count": len(done), "error": err.Error()})
+	}
+}
+
+// storeMined stores a mined tx, it reports false when the tx or its block couldn't be fetched
+func (p *batchProcessor) storeMined(ctx context.Context, txHash string, tx *rpcTransaction, txErr error, receipt *types.Receipt, blockTimes map[common.Hash]uint64) bool {
+	l := p.srvc.Logger
+
+	if txErr == nil && tx == nil {
+		txErr = fmt.Errorf("not found")
+	}
+	if txErr != nil {
+		l.Error("Error fetching mined transaction details", logger.Fields{"txHash": txHash, "error": txErr.Error()})
+		return false
+	}
+
+	// The receipt can predate a reorg the tracker already handled, the tx is checked again instead
+	if orphaned, err := p.storage.IsOrphanedBlock(ctx, receipt.BlockHash); err != nil || orphaned {
+		l.Debug("Transaction receipt is in an orphaned block", logger.Fields{"txHash": txHash, "blockHash": receipt.BlockHash.Hex()})
+		return false
+	}
+
+	blocktimestamp, ok := blockTimes[receipt.BlockHash]
+	if !ok {
+		l.Error("Error fetching block details", logger.Fields{"txHash": txHash, "blockNumber": receipt.BlockNumber})
+		return false
+	}
+
+	if err := p.storage.UpdateMinedTransaction(
+		ctx,
+		txHash,
+		tx.tx,
+		int64(blocktimestamp),
+		receipt.Status,
+		receipt.BlockNumber,
+		&receipt.BlockHash,
+		&receipt.GasUsed,
+	); err != nil {
+		l.Error("Error updating mined transaction", logger.Fields{"txHash": txHash, "error": err.Error()})
+	}
+	return true
+}
+
+// retry checks hashes again after the min wait
+func (p *batchProcessor) retry(ctx context.Context, hashes []string) {
+	if err := p.scheduler.retry(ctx, hashes); err != nil {
+		p.srvc.Logger.Error("Error rescheduling transactions", logger.Fields{"endpoint": p.endpoint.Name, "count": len(hashes), "error": err.Error()})
+	}
+}
+
+// rpcTransaction is a tx as returned by eth_getTransactionByHash, with the block it was included in
+type rpcTransaction struct {
+	tx          *types.Transaction
+	blockNumber *string
+}
+
+func (t *rpcTransaction) UnmarshalJSON(msg []byte) error {
+	var extra struct {
+		BlockNumber *string         `json:"blockNumber"`
+		R           json.RawMessage `json:"r"`
+	}
+	if err := json.Unmarshal(msg, &extra); err != nil {
+		return err
+	}
+	t.blockNumber = extra.BlockNumber
+
+	// Like ethclient, a tx without a signature is reported rather than decoded
+	if extra.R == nil {
+		return nil
+	}
+	return json.Unmarshal(msg, &t.tx)
+}
+
+// isPending reports whether the tx is not in a block yet
+func (t *rpcTransaction) isPending() bool {
+	return t.blockNumber == nil
+}
+
+// blockCache keeps the timestamps of recent blocks by number, so txs mined in the same block share one
+// lookup. The hash is kept too, a block replaced by a reorg is fetched again.
+type blockCache struct {
+	mu     sync.Mutex
+	size   int
+	blocks map[uint64]cachedBlock
+}
+
+type cachedBlock struct {
+	Hash common.Hash    `json:"hash"`
+	Time hexutil.Uint64 `json:"timestamp"`
+}
+
+func newBlockCache(size int) *blockCache {
+	return &blockCache{size: size, blocks: make(map[uint64]cachedBlock)}
+}
+
+// times returns the timestamps of the blocks the receipts were mined in by block hash. Blocks missing
+// from the cache are fetched in one batch request. Blocks that couldn't be fetched are left out.
+func (c *blockCache) times(ctx context.Context, client *rpc.Client, receipts []*types.Receipt) (map[common.Hash]uint64, error) {
+	times := make(map[common.Hash]uint64)
+	var missing []uint64
+	requested := make(map[uint64]bool)
+
+	c.mu.Lock()
+	for _, receipt := range receipts {
+		if receipt.BlockNumber == nil {
+			continue
+		}
+		number := receipt.BlockNumber.Uint64()
+		if block, ok := c.blocks[number]; ok && block.Hash == receipt.BlockHash {
+			times[block.Hash] = uint64(block.Time)
+			continue
+		}
+		if !requested[number] {
+			requested[number] = true
+			missing = append(missing, number)
 		}
-		return
 	}
+	c.mu.Unlock()
 
-	if err != nil {
-		l.Error("Error fetching transaction from mempool", logger.Fields{"txHash": txHash, "error": err.Error()})
-		return
+	if len(missing) == 0 {
+		return times, nil
 	}
 
-	// If in mempool and pending
-	if isPending {
-		l.Debug("Transaction is pending", logger.Fields{"txHash": txHash, "endpoint": endpoint.Name})
-		if err := storage.UpdatePendingTransaction(ctx, txHash, tx, timestamp); err != nil {
-			l.Error("Error updating pending transaction", logger.Fields{"txHash": txHash, "error": err.Error()})
+	blocks := make([]*cachedBlock, len(missing))
+	calls := make([]rpc.BatchElem, len(missing))
+	for i, number := range missing {
+		calls[i] = rpc.BatchElem{
+			Method: "eth_getBlockByNumber",
+			Args:   []any{hexutil.EncodeUint64(number), false},
+			Result: &blocks[i],
 		}
-	} else {
-		// It's queued — waiting for future block (nonce/gas)
-		l.Debug("Transaction is queued", logger.Fields{"txHash": txHash})
-		if err := storage.UpdateQueuedTransaction(ctx, txHash, tx, timestamp); err != nil {
-			l.Error("Error updating queued transaction", logger.Fields{"txHash": txHash, "error": err.Error()})
+	}
+	if err := client.BatchCallContext(ctx, calls); err != nil {
+		return times, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, number := range missing {
+		if calls[i].Error != nil || blocks[i] == nil {
+			continue
 		}
+		c.add(number, *blocks[i])
+		times[blocks[i].Hash] = uint64(blocks[i].Time)
 	}
+	return times, nil
+}
 
-	// Requeue for future check
-	if err := srvc.Redis.RPush(ctx, streamKey, fmt.Sprintf("%s:%s", endpoint.Name, txHash)).Err(); err != nil {
-		l.Error("Error requeuing transaction", logger.Fields{"txHash": txHash, "error": err.Error()})
+// add caches a block, evicting the lowest numbers once the cache is full. Callers hold mu.
+func (c *blockCache) add(number uint64, block cachedBlock) {
+	c.blocks[number] = block
+	for len(c.blocks) > c.size {
+		lowest := number
+		for n := range c.blocks {
+			if n < lowest {
+				lowest = n
+			}
+		}
+		delete(c.blocks, lowest)
 	}
 }
 
-func monitorQueueSize(ctx context.Context, redis *redis.Client, l logger.Logger, queue string) {
+// monitorQueueSize logs how many txs are scheduled and how many of them are due on clk, the clock
+// the scheduler scores them with
+func monitorQueueSize(ctx context.Context, r *redis.Client, l logger.Logger, clk clock.Clock, queue string) {
 	ticker := time.NewTicker(3 * time.Second) // Queue monitor set to 3s. Might be dynamic in the future
 	defer ticker.Stop()
 
 	for {
 		select {
 		case <-ticker.C:
-			count, err := redis.LLen(ctx, queue).Result()
+			count, err := r.ZCard(ctx, queue).Result()
 			if err != nil {
 				l.Warn(fmt.Sprintf("Error getting queue length: %s", err.Error()))
 				continue
 			}
-			l.Info("Queue size checked", logger.Fields{"queue": queue, "size": count})
+			due, err := r.ZCount(ctx, queue, "-inf", strconv.FormatInt(clk.Now().UnixMilli(), 10)).Result()
+			if err != nil {
+				l.Warn(fmt.Sprintf("Error getting due tx count: %s", err.Error()))
+				continue
+			}
+			l.Info("Queue size checked", logger.Fields{"queue": queue, "size": count, "due": due})
 		case <-ctx.Done():
 			return
 		}
@@ -0,0 +1,148 @@
+package transactions
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"txpool-viz/internal/clock"
+	"txpool-viz/internal/config"
+	"txpool-viz/internal/logger"
+	"txpool-viz/utils"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// recheckLease is how long claimed txs stay out of the due set. Txs of a batch that never finished,
+// e.g. because its endpoint was restarted, are checked again once it expires.
+const recheckLease = 30 * time.Second
+
+// claimDue returns up to ARGV[2] members due by ARGV[1] and moves them to ARGV[3] in one step
+var claimDue = redis.NewScript(`
+local due = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, ARGV[2])
+for _, member in ipairs(due) do
+	redis.call('ZADD', KEYS[1], 'XX', ARGV[3], member)
+end
+return due
+`)
+
+// scheduleCheck queues a newly seen tx for its first check as of receivedAt, so right away. A tx already
+// scheduled keeps its next check time.
+func scheduleCheck(ctx context.Context, r *redis.Client, endpointName, txHash string, receivedAt int64) error {
+	return r.ZAddNX(ctx, utils.RedisScheduleKey(endpointName), redis.Z{
+		Score:  float64(time.Unix(receivedAt, 0).UnixMilli()),
+		Member: txHash,
+	}).Err()
+}
+
+// scheduler keeps an endpoint's non-terminal txs in a ZSET scored by their next check time. Txs are
+// checked often while new and less often as they age, mined and dropped txs are removed.
+type scheduler struct {
+	r     *redis.Client
+	key   string
+	clock clock.Clock
+
+	minWait time.Duration
+	maxWait time.Duration
+
+	mu           sync.Mutex
+	firstChecked map[string]time.Time // by tx hash, the age the backoff is based on
+}
+
+func newScheduler(endpointName string, r *redis.Client, clk clock.Clock, minWait, maxWait time.Duration) *scheduler {
+	return &scheduler{
+		r:            r,
+		key:          utils.RedisScheduleKey(endpointName),
+		clock:        clk,
+		minWait:      minWait,
+		maxWait:      maxWait,
+		firstChecked: make(map[string]time.Time),
+	}
+}
+
+// recheckBounds parses polling.recheck_min and recheck_max, falling back to the defaults
+func recheckBounds(polling config.Polling, l logger.Logger) (time.Duration, time.Duration) {
+	parse := func(value string, fallback time.Duration) time.Duration {
+		if value == "" {
+			return fallback
+		}
+		d, err := time.ParseDuration(value)
+		if err != nil || d <= 0 {
+			l.Error("Error parsing recheck wait, using the default", logger.Fields{"wait": value})
+			return fallback
+		}
+		return d
+	}
+
+	minWait := parse(polling.RecheckMin, config.DefaultRecheckMin)
+	maxWait := parse(polling.RecheckMax, config.DefaultRecheckMax)
+	if maxWait < minWait {
+		maxWait = minWait
+	}
+	return minWait, maxWait
+}
+
+// due claims up to limit txs whose check time has passed. They stay claimed for recheckLease.
+func (s *scheduler) due(ctx context.Context, limit int) ([]string, error) {
+	now := s.clock.Now()
+	return claimDue.Run(ctx, s.r, []string{s.key},
+		now.UnixMilli(),
+		limit,
+		now.Add(recheckLease).UnixMilli(),
+	).StringSlice()
+}
+
+// reschedule sets the next check of hashes a quarter of their age from now, within the min and max wait
+func (s *scheduler) reschedule(ctx context.Context, hashes []string) error {
+	if len(hashes) == 0 {
+		return nil
+	}
+
+	now := s.clock.Now()
+	members := make([]redis.Z, len(hashes))
+
+	s.mu.Lock()
+	for i, txHash := range hashes {
+		first, ok := s.firstChecked[txHash]
+		if !ok {
+			first = now
+			s.firstChecked[txHash] = now
+		}
+		wait := min(max(now.Sub(first)/4, s.minWait), s.maxWait)
+		members[i] = redis.Z{Score: float64(now.Add(wait).UnixMilli()), Member: txHash}
+	}
+	s.mu.Unlock()
+
+	return s.r.ZAdd(ctx, s.key, members...).Err()
+}
+
+// retry checks hashes again after the min wait, for checks that failed or couldn't be done yet
+func (s *scheduler) retry(ctx context.Context, hashes []string) error {
+	if len(hashes) == 0 {
+		return nil
+	}
+
+	score := float64(s.clock.Now().Add(s.minWait).UnixMilli())
+	members := make([]redis.Z, len(hashes))
+	for i, txHash := range hashes {
+		members[i] = redis.Z{Score: score, Member: txHash}
+	}
+	return s.r.ZAdd(ctx, s.key, members...).Err()
+}
+
+// done removes txs that reached a terminal state
+func (s *scheduler) done(ctx context.Context, hashes []string) error {
+	if len(hashes) == 0 {
+		return nil
+	}
+
+	s.mu.Lock()
+	members := make([]any, len(hashes))
+	for i, txHash := range hashes {
+		members[i] = txHash
+		delete(s.firstChecked, txHash)
+	}
+	s.mu.Unlock()
+
+	return s.r.ZRem(ctx, s.key, members...).Err()
+}
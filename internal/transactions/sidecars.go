@@ -0,0 +1,83 @@
+package transactions
+
+import (
+	"context"
+
+	"txpool-viz/internal/logger"
+	"txpool-viz/internal/model"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// maxBlobsPerRequest is how many versioned hashes engine_getBlobsV1 accepts in one call
+const maxBlobsPerRequest = 128
+
+// sidecars asks the node's engine API which of the pooled txs it holds every blob of. Other txs get no
+// status. Blob txs are unknown without an engine API or when it fails, eth_getTransactionByHash doesn't
+// tell.
+func (p *batchProcessor) sidecars(ctx context.Context, txs map[string]*types.Transaction) map[string]model.SidecarStatus {
+	statuses := make(map[string]model.SidecarStatus)
+	var blobTxs []string
+	var hashes []common.Hash
+	for txHash, tx := range txs {
+		if tx.Type() != types.BlobTxType {
+			continue
+		}
+		statuses[txHash] = model.SidecarUnknown
+		if p.endpoint.Engine != nil {
+			blobTxs = append(blobTxs, txHash)
+			hashes = append(hashes, tx.BlobHashes()...)
+		}
+	}
+	if len(hashes) == 0 {
+		return statuses
+	}
+
+	// Only whether each blob came back matters, the blobs and proofs are skipped rather than decoded
+	served := make([][]*struct{}, (len(hashes)+maxBlobsPerRequest-1)/maxBlobsPerRequest)
+	calls := make([]rpc.BatchElem, len(served))
+	chunks := make([][]common.Hash, len(served))
+	for i := range calls {
+		chunks[i] = hashes[i*maxBlobsPerRequest : min((i+1)*maxBlobsPerRequest, len(hashes))]
+		calls[i] = rpc.BatchElem{
+			Method: "engine_getBlobsV1",
+			Args:   []any{chunks[i]},
+			Result: &served[i],
+		}
+	}
+	if err := p.endpoint.Engine.BatchCallContext(ctx, calls); err != nil {
+		p.srvc.Logger.Error("Error fetching blobs from the engine API", logger.Fields{"endpoint": p.endpoint.Name, "count": len(hashes), "error": err.Error()})
+		return statuses
+	}
+
+	blobs := make(map[common.Hash]bool, len(hashes))
+	for i, call := range calls {
+		if call.Error != nil {
+			p.srvc.Logger.Error("Error fetching blobs from the engine API", logger.Fields{"endpoint": p.endpoint.Name, "error": call.Error.Error()})
+			continue
+		}
+		for j, blob := range served[i] {
+			if j < len(chunks[i]) {
+				blobs[chunks[i][j]] = blob != nil
+			}
+		}
+	}
+
+	for _, txHash := range blobTxs {
+		status := model.SidecarServed
+		for _, hash := range txs[txHash].BlobHashes() {
+			held, ok := blobs[hash]
+			if !ok {
+				status = model.SidecarUnknown
+				break
+			}
+			if !held {
+				status = model.SidecarMissing
+			}
+		}
+		statuses[txHash] = status
+	}
+	return statuses
+}
@@ -5,19 +5,37 @@ import (
 )
 
 const (
-	redisStreamPrefix                    = "txpool:%s:stream"        // Per-client stream (list of incoming tx hashes)
-	redisClientMetaPrefix                = "txpool:%s:meta"          // Per-client high-level tx & metadata records
-	redisUniversalSortedSet              = "txpool:universal"        // Global ZSET of tx hashes ordered by received time
-	redisGasIndexPrefix                  = "txpool:%s:index:gas"     // Sorted by gas price
-	redisNonceIndexPrefix                = "txpool:%s:index:nonce"   // Sorted by nonce
-	redisTypeIndexPrefix                 = "txpool:%s:index:type"    // Sorted by tx type
-	redisInclusionListTransactionsPrefix = "txpool:inclusion:txns"   // Slot by slot inclusion list transactions
-	redisInclusionListScorePrefix        = "txpool:inclusion:score"  // Slot by slot inclusion list score
-	redisInclusionListReportPrefix       = "txpool:inclusion:report" // Slot by slot inclusion list report
+	redisSchedulePrefix                  = "txpool:%s:schedule"               // Per-client ZSET of tx hashes scored by next check time (ms)
+	redisClientMetaPrefix                = "txpool:%s:meta"                   // Per-client high-level tx & metadata records
+	redisUniversalSortedSet              = "txpool:universal"                 // Global ZSET of tx hashes ordered by received time
+	redisGasIndexPrefix                  = "txpool:%s:index:gas"              // Sorted by gas price
+	redisNonceIndexPrefix                = "txpool:%s:index:nonce"            // Sorted by nonce
+	redisTypeIndexPrefix                 = "txpool:%s:index:type"             // Sorted by tx type
+	redisDelegateIndexPrefix             = "txpool:%s:index:delegate:%s"      // Per-client set of set code tx hashes authorizing a delegate
+	redisTipIndexPrefix                  = "txpool:%s:index:tip"              // Per-client ZSET of held txs scored by max priority fee (wei)
+	redisPoolCountsPrefix                = "txpool:%s:counts"                 // Per-client counts of txs by status, and of held txs by type, gas and blobs
+	redisBlockTxsPrefix                  = "txpool:%s:block:%s"               // Per-client set of tx hashes stored as mined in a block hash
+	redisOrphanedBlocksPrefix            = "txpool:%s:orphaned"               // Per-client ZSET of block hashes removed by reorgs, scored by detection time
+	redisReorgLog                        = "txpool:reorgs"                    // List of reorg events, newest first
+	redisBlockFeesPrefix                 = "txpool:%s:blocks:fees"            // Per-client ZSET of recent blocks' fee state scored by block number
+	redisPrivateFlow                     = "txpool:blocks:private"            // ZSET of recent blocks' public and private tx counts scored by block number
+	redisPrivateFlowClaimPrefix          = "txpool:blocks:private:%s"         // Per-block-hash claim so one endpoint analyses each block
+	redisCensorshipAlerts                = "txpool:censorship:alerts"         // List of stuck tx alerts, newest first
+	redisCensorshipAlertedPrefix         = "txpool:censorship:alerted:%s"     // Marks a tx already alerted on
+	redisStatsPrefix                     = "txpool:stats:%s"                  // Per-resolution ZSET of pool samples scored by their bucket's unix time
+	redisInclusionListTransactionsPrefix = "txpool:inclusion:txns"            // Slot by slot inclusion list transactions
+	redisInclusionListScorePrefix        = "txpool:inclusion:score"           // Slot by slot inclusion list score
+	redisInclusionListReportPrefix       = "txpool:inclusion:report"          // Slot by slot inclusion list report
+	redisInclusionListSeenPrefix         = "txpool:inclusion:seen"            // Slot by slot first inclusion list arrival time
+	redisInclusionVerificationPrefix     = "txpool:inclusion:verification:%s" // Per-slot signature/committee checks keyed by validator index
+	redisInclusionListRootsPrefix        = "txpool:inclusion:roots:%s"        // Per-slot distinct inclusion list roots and their validator index
+	redisInclusionListArrivalsPrefix     = "txpool:inclusion:arrivals:%s"     // Per-root arrival time (ms) from each beacon node
+	redisInclusionListValidatorsPrefix   = "txpool:inclusion:validators:%s"   // Per-slot tx hashes listed by each validator
+	redisInclusionListListedPrefix       = "txpool:inclusion:listed:%s"       // Per-slot raw txs listed by any validator, keyed by tx hash
 )
 
-func RedisStreamKey(client string) string {
-	return fmt.Sprintf(redisStreamPrefix, client)
+func RedisScheduleKey(client string) string {
+	return fmt.Sprintf(redisSchedulePrefix, client)
 }
 
 func RedisClientMetaKey(client string) string {
@@ -40,6 +58,54 @@ func RedisTypeIndexKey(client string) string {
 	return fmt.Sprintf(redisTypeIndexPrefix, client)
 }
 
+func RedisDelegateIndexKey(client, delegate string) string {
+	return fmt.Sprintf(redisDelegateIndexPrefix, client, delegate)
+}
+
+func RedisTipIndexKey(client string) string {
+	return fmt.Sprintf(redisTipIndexPrefix, client)
+}
+
+func RedisPoolCountsKey(client string) string {
+	return fmt.Sprintf(redisPoolCountsPrefix, client)
+}
+
+func RedisBlockTxsKey(client, blockHash string) string {
+	return fmt.Sprintf(redisBlockTxsPrefix, client, blockHash)
+}
+
+func RedisOrphanedBlocksKey(client string) string {
+	return fmt.Sprintf(redisOrphanedBlocksPrefix, client)
+}
+
+func RedisReorgLogKey() string {
+	return redisReorgLog
+}
+
+func RedisBlockFeesKey(client string) string {
+	return fmt.Sprintf(redisBlockFeesPrefix, client)
+}
+
+func RedisPrivateFlowKey() string {
+	return redisPrivateFlow
+}
+
+func RedisPrivateFlowClaimKey(blockHash string) string {
+	return fmt.Sprintf(redisPrivateFlowClaimPrefix, blockHash)
+}
+
+func RedisCensorshipAlertsKey() string {
+	return redisCensorshipAlerts
+}
+
+func RedisCensorshipAlertedKey(txHash string) string {
+	return fmt.Sprintf(redisCensorshipAlertedPrefix, txHash)
+}
+
+func RedisStatsKey(resolution string) string {
+	return fmt.Sprintf(redisStatsPrefix, resolution)
+}
+
 func RedisInclusionListTxnsKey() string {
 	return redisInclusionListTransactionsPrefix
 }
@@ -50,4 +116,28 @@ func RedisInclusionScoreKey() string {
 
 func RedisInclusionListReportKey() string {
 	return redisInclusionListReportPrefix
-}
\ No newline at end of file
+}
+
+func RedisInclusionListSeenKey() string {
+	return redisInclusionListSeenPrefix
+}
+
+func RedisInclusionVerificationKey(slot string) string {
+	return fmt.Sprintf(redisInclusionVerificationPrefix, slot)
+}
+
+func RedisInclusionListRootsKey(slot string) string {
+	return fmt.Sprintf(redisInclusionListRootsPrefix, slot)
+}
+
+func RedisInclusionListArrivalsKey(root string) string {
+	return fmt.Sprintf(redisInclusionListArrivalsPrefix, root)
+}
+
+func RedisInclusionListValidatorsKey(slot string) string {
+	return fmt.Sprintf(redisInclusionListValidatorsPrefix, slot)
+}
+
+func RedisInclusionListListedKey(slot string) string {
+	return fmt.Sprintf(redisInclusionListListedPrefix, slot)
+}